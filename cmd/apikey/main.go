@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+	"github.com/spf13/viper"
+
+	"github.com/ktsivkov/su-exc/internal/apikey"
+)
+
+const usage = "usage: apikey issue -name NAME -scopes SCOPE[,SCOPE...] [-accounts ID[,ID...]]"
+
+// The apikey command issues the API keys the API cannot issue itself, first of all the admin key issuing all others.
+// The key is printed once, only its hash is stored.
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "issue" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("issue", flag.ExitOnError)
+	name := flags.String("name", "", "name telling what the key is used for")
+	scopes := flags.String("scopes", "", "comma separated scopes of the key")
+	accounts := flags.String("accounts", "", "comma separated ids of the accounts the key is restricted to")
+	_ = flags.Parse(os.Args[2:])
+
+	if *name == "" || *scopes == "" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var accountIds []int64
+	for _, raw := range split(*accounts) {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid account id %q\n", raw)
+			os.Exit(2)
+		}
+		accountIds = append(accountIds, id)
+	}
+
+	var keyScopes []apikey.Scope
+	for _, scope := range split(*scopes) {
+		keyScopes = append(keyScopes, apikey.Scope(scope))
+	}
+
+	conf := viper.New()
+	conf.AddConfigPath("configs")
+	conf.SetConfigType("yaml")
+	conf.SetConfigName("app")
+	conf.AutomaticEnv()
+	if err := conf.ReadInConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "cannot read config:", err)
+		os.Exit(2)
+	}
+
+	key, secret, err := issue(context.Background(), conf.GetString("POSTGRES_URI"), *name, keyScopes, accountIds)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot issue api key:", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "api key id=%d issued, it cannot be shown again\n", key.Id)
+	fmt.Println(secret)
+}
+
+func issue(ctx context.Context, dbUri string, name string, scopes []apikey.Scope, accountIds []int64) (*apikey.Key, string, error) {
+	db, err := sql.Open("postgres", dbUri)
+	if err != nil {
+		return nil, "", err
+	}
+	defer db.Close()
+
+	apiKeyRepo, err := apikey.NewRepository(db)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return apiKeyRepo.Issue(ctx, name, scopes, accountIds)
+}
+
+func split(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	_ "github.com/lib/pq"
+	"github.com/spf13/viper"
+
+	"github.com/ktsivkov/su-exc/internal/audit"
+)
+
+const usage = "usage: audit verify"
+
+// The audit command checks the audit log. It exits with 1 if the hash chain is broken and with 2 if it could not
+// be checked at all.
+func main() {
+	if len(os.Args) != 2 || os.Args[1] != "verify" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	conf := viper.New()
+	conf.AddConfigPath("configs")
+	conf.SetConfigType("yaml")
+	conf.SetConfigName("app")
+	conf.AutomaticEnv()
+	if err := conf.ReadInConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, "cannot read config:", err)
+		os.Exit(2)
+	}
+
+	report, err := verify(context.Background(), conf.GetString("POSTGRES_URI"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot verify audit log:", err)
+		os.Exit(2)
+	}
+
+	for _, problem := range report.Problems {
+		fmt.Printf("entry id=%d: %s\n", problem.EntryId, problem.Reason)
+	}
+	if !report.Intact() {
+		fmt.Printf("audit log tampered with: %d problem(s) in %d entries\n", len(report.Problems), report.Checked)
+		os.Exit(1)
+	}
+
+	fmt.Printf("audit log intact: %d entries verified\n", report.Checked)
+}
+
+func verify(ctx context.Context, dbUri string) (*audit.Report, error) {
+	db, err := sql.Open("postgres", dbUri)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	auditRepo, err := audit.NewRepository(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return auditRepo.Verify(ctx)
+}
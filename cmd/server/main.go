@@ -15,15 +15,50 @@ func main() {
 	conf.SetConfigType("yaml")
 	conf.SetConfigName("app")
 	conf.AutomaticEnv()
+	conf.SetDefault("IDEMPOTENCY_KEY_RETENTION", "24h")
+	conf.SetDefault("IDEMPOTENCY_KEY_LEASE", "1m")
+	conf.SetDefault("FX_QUOTE_TTL", "30s")
+	conf.SetDefault("HOLD_TTL", "168h")
+	conf.SetDefault("SCHEDULER_INTERVAL", "10s")
+	conf.SetDefault("SCHEDULER_LEASE_TTL", "1m")
+	conf.SetDefault("OUTBOX_RELAY_INTERVAL", "1s")
+	conf.SetDefault("OUTBOX_STDOUT", false)
+	conf.SetDefault("OUTBOX_WEBHOOK_TIMEOUT", "5s")
+	conf.SetDefault("WEBHOOK_DISPATCH_INTERVAL", "1s")
+	conf.SetDefault("WEBHOOK_TIMEOUT", "5s")
+	conf.SetDefault("WEBHOOK_MAX_ATTEMPTS", 10)
+	conf.SetDefault("WEBHOOK_RETRY_BACKOFF", "10s")
+	conf.SetDefault("BALANCE_SNAPSHOT_INTERVAL", "24h")
+	conf.SetDefault("JWT_PRIVILEGED_ROLES", []string{"admin"})
 	if err := conf.ReadInConfig(); err != nil {
 		panic(err)
 	}
 
-	dbUri := conf.GetString("POSTGRES_URI")
-	appPort := conf.GetInt("APP_PORT")
-	shutdownGracePeriod := conf.GetDuration("APP_SHUTDOWN_GRACE_PERIOD")
-
-	err := rest.Boot(context.Background(), dbUri, appPort, shutdownGracePeriod)
+	err := rest.Boot(context.Background(), &rest.Config{
+		DbUri:                   conf.GetString("POSTGRES_URI"),
+		Port:                    conf.GetInt("APP_PORT"),
+		ShutdownGracePeriod:     conf.GetDuration("APP_SHUTDOWN_GRACE_PERIOD"),
+		IdempotencyRetention:    conf.GetDuration("IDEMPOTENCY_KEY_RETENTION"),
+		IdempotencyLease:        conf.GetDuration("IDEMPOTENCY_KEY_LEASE"),
+		FxRates:                 conf.GetStringMapString("FX_RATES"),
+		FxQuoteTtl:              conf.GetDuration("FX_QUOTE_TTL"),
+		HoldTtl:                 conf.GetDuration("HOLD_TTL"),
+		SchedulerInterval:       conf.GetDuration("SCHEDULER_INTERVAL"),
+		SchedulerLeaseTtl:       conf.GetDuration("SCHEDULER_LEASE_TTL"),
+		OutboxRelayInterval:     conf.GetDuration("OUTBOX_RELAY_INTERVAL"),
+		OutboxStdout:            conf.GetBool("OUTBOX_STDOUT"),
+		OutboxFile:              conf.GetString("OUTBOX_FILE"),
+		OutboxWebhookUrl:        conf.GetString("OUTBOX_WEBHOOK_URL"),
+		OutboxWebhookTimeout:    conf.GetDuration("OUTBOX_WEBHOOK_TIMEOUT"),
+		WebhookDispatchInterval: conf.GetDuration("WEBHOOK_DISPATCH_INTERVAL"),
+		WebhookTimeout:          conf.GetDuration("WEBHOOK_TIMEOUT"),
+		WebhookMaxAttempts:      conf.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+		WebhookRetryBackoff:     conf.GetDuration("WEBHOOK_RETRY_BACKOFF"),
+		BalanceSnapshotInterval: conf.GetDuration("BALANCE_SNAPSHOT_INTERVAL"),
+		JwtSecret:               conf.GetString("JWT_HS256_SECRET"),
+		JwtJwksFile:             conf.GetString("JWT_JWKS_FILE"),
+		JwtPrivilegedRoles:      conf.GetStringSlice("JWT_PRIVILEGED_ROLES"),
+	})
 	if err != nil {
 		panic(err)
 	}
@@ -1,7 +1,349 @@
+DROP TABLE IF EXISTS su.public.api_keys;
+DROP TABLE IF EXISTS su.public.idempotency_keys;
+DROP TABLE IF EXISTS su.public.audit_log;
+DROP TABLE IF EXISTS su.public.webhook_deliveries;
+DROP TABLE IF EXISTS su.public.webhook_subscriptions;
+DROP TABLE IF EXISTS su.public.outbox;
+DROP TABLE IF EXISTS su.public.scheduled_transfer_runs;
+DROP TABLE IF EXISTS su.public.scheduled_transfers;
+DROP TABLE IF EXISTS su.public.transactions;
+DROP TABLE IF EXISTS su.public.balance_snapshots;
+DROP TABLE IF EXISTS su.public.holds;
+DROP TABLE IF EXISTS su.public.transfer_limits;
+DROP TABLE IF EXISTS su.public.account_tiers;
+DROP TABLE IF EXISTS su.public.fx_quotes;
+DROP TABLE IF EXISTS su.public.postings;
+DROP TABLE IF EXISTS su.public.journal_entries;
 DROP TABLE IF EXISTS su.public.accounts;
+DROP TABLE IF EXISTS su.public.customers;
+
+CREATE TABLE IF NOT EXISTS su.public.customers
+(
+    id           BIGSERIAL PRIMARY KEY,
+    name         TEXT        NOT NULL,
+    email        TEXT        NOT NULL,
+    -- Identifier of the customer in the systems of the client, unique if set
+    external_ref TEXT        NULL UNIQUE,
+    kyc_tier     TEXT        NOT NULL DEFAULT 'none' CHECK (kyc_tier IN ('none', 'basic', 'full')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
 
 CREATE TABLE IF NOT EXISTS su.public.accounts
 (
-    id      BIGSERIAL PRIMARY KEY,
-    balance INT NOT NULL DEFAULT 0
+    id              BIGSERIAL PRIMARY KEY,
+    -- ISO 4217 code, the supported codes and their minor units are maintained in the currency package
+    currency        CHAR(3) NOT NULL DEFAULT 'EUR' CHECK (currency ~ '^[A-Z]{3}$'),
+    balance         BIGINT  NOT NULL DEFAULT 0,
+    status          TEXT    NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'frozen', 'closed')),
+    -- How far below zero the balance may go, in minor units of the account currency
+    overdraft_limit BIGINT  NOT NULL DEFAULT 0 CHECK (overdraft_limit >= 0),
+    -- Subject of the token the account was created with, NULL for accounts created otherwise
+    owner           TEXT    NULL,
+    -- Customers with open accounts cannot be deleted, the closed accounts of a deleted customer are unlinked
+    customer_id     BIGINT  NULL REFERENCES su.public.customers (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS accounts_owner_idx ON su.public.accounts (owner);
+CREATE INDEX IF NOT EXISTS accounts_customer_idx ON su.public.accounts (customer_id);
+
+-- Exchange rates locked for a limited time, each quote backs at most one exchange
+CREATE TABLE IF NOT EXISTS su.public.fx_quotes
+(
+    id              TEXT PRIMARY KEY,
+    source_currency CHAR(3)     NOT NULL,
+    target_currency CHAR(3)     NOT NULL,
+    rate            NUMERIC     NOT NULL CHECK (rate > 0),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at      TIMESTAMPTZ NOT NULL,
+    used_at         TIMESTAMPTZ NULL
+);
+
+CREATE TABLE IF NOT EXISTS su.public.journal_entries
+(
+    id          BIGSERIAL PRIMARY KEY,
+    kind        TEXT        NOT NULL,
+    -- Rate applied by exchange entries, NULL for single currency entries
+    fx_quote_id TEXT        NULL REFERENCES su.public.fx_quotes (id),
+    fx_rate     NUMERIC     NULL,
+    -- Entry compensated by a reversal, NULL for any other entry
+    reversal_of BIGINT      NULL REFERENCES su.public.journal_entries (id),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS journal_entries_reversal_of_idx ON su.public.journal_entries (reversal_of) WHERE reversal_of IS NOT NULL;
+
+-- account_id is not a foreign key, as system accounts (negative ids) only exist in the ledger
+CREATE TABLE IF NOT EXISTS su.public.postings
+(
+    id               BIGSERIAL PRIMARY KEY,
+    journal_entry_id BIGINT      NOT NULL REFERENCES su.public.journal_entries (id),
+    account_id       BIGINT      NOT NULL,
+    amount           BIGINT      NOT NULL CHECK (amount <> 0),
+    currency         CHAR(3)     NOT NULL,
+    -- Same as the created_at of the journal entry, kept here so that balances at a point in time only need postings
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS postings_account_id_idx ON su.public.postings (account_id, created_at);
+CREATE INDEX IF NOT EXISTS postings_created_at_idx ON su.public.postings (created_at);
+CREATE INDEX IF NOT EXISTS postings_journal_entry_id_idx ON su.public.postings (journal_entry_id);
+
+-- Balance of an account at a point in time computed from its postings, a snapshot is only taken for accounts which had
+-- postings since their previous one
+CREATE TABLE IF NOT EXISTS su.public.balance_snapshots
+(
+    account_id BIGINT      NOT NULL REFERENCES su.public.accounts (id),
+    as_of      TIMESTAMPTZ NOT NULL,
+    balance    BIGINT      NOT NULL,
+    PRIMARY KEY (account_id, as_of)
+);
+
+CREATE INDEX IF NOT EXISTS balance_snapshots_as_of_idx ON su.public.balance_snapshots (as_of);
+
+-- Per account view of the money movements, written together with the journal entry they belong to
+CREATE TABLE IF NOT EXISTS su.public.transactions
+(
+    id               BIGSERIAL PRIMARY KEY,
+    account_id       BIGINT      NOT NULL REFERENCES su.public.accounts (id),
+    journal_entry_id BIGINT      NOT NULL REFERENCES su.public.journal_entries (id),
+    type             TEXT        NOT NULL,
+    direction        TEXT        NOT NULL CHECK (direction IN ('in', 'out')),
+    counterparty_id  BIGINT      NULL REFERENCES su.public.accounts (id),
+    amount           BIGINT      NOT NULL CHECK (amount > 0),
+    currency         CHAR(3)     NOT NULL,
+    balance          BIGINT      NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS transactions_account_id_id_idx ON su.public.transactions (account_id, id DESC);
+
+-- Funds reserved for a later transfer, active holds reduce the available balance of their source account until they
+-- are captured, voided or expire
+CREATE TABLE IF NOT EXISTS su.public.holds
+(
+    id               BIGSERIAL PRIMARY KEY,
+    source_id        BIGINT      NOT NULL REFERENCES su.public.accounts (id),
+    target_id        BIGINT      NOT NULL REFERENCES su.public.accounts (id),
+    amount           BIGINT      NOT NULL CHECK (amount > 0),
+    currency         CHAR(3)     NOT NULL,
+    status           TEXT        NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'voided', 'expired')),
+    captured_amount  BIGINT      NULL CHECK (captured_amount > 0 AND captured_amount <= amount),
+    -- Journal entry of the capturing transfer
+    journal_entry_id BIGINT      NULL REFERENCES su.public.journal_entries (id),
+    expires_at       TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS holds_source_id_active_idx ON su.public.holds (source_id) WHERE status = 'active';
+
+-- Accounts without a row here belong to the standard tier
+CREATE TABLE IF NOT EXISTS su.public.account_tiers
+(
+    account_id BIGINT PRIMARY KEY REFERENCES su.public.accounts (id),
+    tier       TEXT NOT NULL
+);
+
+-- Caps on the outgoing transfers of an account per calendar hour or day in UTC, either of a single account or of every
+-- account in a tier, the rules of an account take precedence over the rules of its tier for the same metric and period
+CREATE TABLE IF NOT EXISTS su.public.transfer_limits
+(
+    id         BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NULL REFERENCES su.public.accounts (id),
+    tier       TEXT   NULL,
+    metric     TEXT   NOT NULL CHECK (metric IN ('count', 'amount')),
+    period     TEXT   NOT NULL CHECK (period IN ('hour', 'day')),
+    -- Number of transfers, or minor units of the account currency for amount rules
+    max        BIGINT NOT NULL CHECK (max >= 0),
+    CHECK ((account_id IS NULL) <> (tier IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS transfer_limits_account_idx ON su.public.transfer_limits (account_id, metric, period) WHERE account_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS transfer_limits_tier_idx ON su.public.transfer_limits (tier, metric, period) WHERE tier IS NOT NULL;
+
+-- Transfers executed later on, once or on a recurrence anchored at start_at. A scheduler claims due transfers by leasing
+-- them, so that several instances of the application can poll the table without running the same transfer twice
+CREATE TABLE IF NOT EXISTS su.public.scheduled_transfers
+(
+    id               BIGSERIAL PRIMARY KEY,
+    source_id        BIGINT      NOT NULL REFERENCES su.public.accounts (id),
+    target_id        BIGINT      NOT NULL REFERENCES su.public.accounts (id),
+    amount           BIGINT      NOT NULL CHECK (amount > 0),
+    currency         CHAR(3)     NOT NULL,
+    -- NULL for one-off transfers
+    recurrence       TEXT        NULL CHECK (recurrence IN ('daily', 'weekly', 'monthly')),
+    start_at         TIMESTAMPTZ NOT NULL,
+    -- Index of the run due at next_run_at, start_at being the 0-th run
+    occurrence       INT         NOT NULL DEFAULT 0,
+    -- NULL once completed
+    next_run_at      TIMESTAMPTZ NULL,
+    status           TEXT        NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed')),
+    lease_owner      TEXT        NULL,
+    lease_expires_at TIMESTAMPTZ NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_transfers_next_run_at_idx ON su.public.scheduled_transfers (next_run_at) WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS su.public.scheduled_transfer_runs
+(
+    id                    BIGSERIAL PRIMARY KEY,
+    scheduled_transfer_id BIGINT      NOT NULL REFERENCES su.public.scheduled_transfers (id),
+    scheduled_for         TIMESTAMPTZ NOT NULL,
+    outcome               TEXT        NOT NULL CHECK (outcome IN ('succeeded', 'failed')),
+    error                 TEXT        NULL,
+    -- Journal entry of the transfer, NULL for failed runs
+    journal_entry_id      BIGINT      NULL REFERENCES su.public.journal_entries (id),
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (scheduled_transfer_id, scheduled_for)
+);
+
+-- Domain events written in the same transaction as the change they describe, until the relay publishes them.
+-- Events of an account are published in id order, which matches the order of their commits as the account is locked.
+CREATE TABLE IF NOT EXISTS su.public.outbox
+(
+    id            BIGSERIAL PRIMARY KEY,
+    type          TEXT        NOT NULL,
+    account_ids   BIGINT[]    NOT NULL,
+    payload       JSONB       NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- Until when the event is being published by a relay, another relay publishes it again afterwards
+    claimed_until TIMESTAMPTZ NULL,
+    published_at  TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON su.public.outbox (id) WHERE published_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS su.public.webhook_subscriptions
+(
+    id          BIGSERIAL PRIMARY KEY,
+    url         TEXT        NOT NULL,
+    event_types TEXT[]      NOT NULL CHECK (cardinality(event_types) > 0),
+    -- Key of the HMAC-SHA256 signatures of the deliveries
+    secret      TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS su.public.webhook_deliveries
+(
+    id               BIGSERIAL PRIMARY KEY,
+    subscription_id  BIGINT      NOT NULL REFERENCES su.public.webhook_subscriptions (id),
+    event_id         BIGINT      NOT NULL REFERENCES su.public.outbox (id),
+    event_type       TEXT        NOT NULL,
+    body             JSONB       NOT NULL,
+    status           TEXT        NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'dead')),
+    attempts         INT         NOT NULL DEFAULT 0,
+    -- Response status of the last attempt, NULL if it received no response
+    last_status_code INT         NULL,
+    last_error       TEXT        NULL,
+    -- Set on pending deliveries only, claimed deliveries are pushed back until their lease is over
+    next_attempt_at  TIMESTAMPTZ NULL,
+    delivered_at     TIMESTAMPTZ NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_next_attempt_at_idx ON su.public.webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- Ledger rows are append-only
+CREATE OR REPLACE FUNCTION su.public.ledger_forbid_change() RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION '% is append-only, % is not allowed', TG_TABLE_NAME, TG_OP;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER journal_entries_append_only
+    BEFORE UPDATE OR DELETE
+    ON su.public.journal_entries
+    FOR EACH ROW
+EXECUTE FUNCTION su.public.ledger_forbid_change();
+
+CREATE TRIGGER postings_append_only
+    BEFORE UPDATE OR DELETE
+    ON su.public.postings
+    FOR EACH ROW
+EXECUTE FUNCTION su.public.ledger_forbid_change();
+
+-- Every state-changing request, each entry holds the hash of the entry before it
+CREATE TABLE IF NOT EXISTS su.public.audit_log
+(
+    id          BIGSERIAL PRIMARY KEY,
+    actor       TEXT        NOT NULL,
+    request_id  TEXT        NOT NULL,
+    method      TEXT        NOT NULL,
+    endpoint    TEXT        NOT NULL,
+    path        TEXT        NOT NULL,
+    status_code INT         NOT NULL,
+    -- Balances of the accounts the request locked, before and after it, in minor units
+    balances    JSONB       NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    -- Empty for the first entry of the chain
+    prev_hash   TEXT        NOT NULL,
+    hash        TEXT        NOT NULL UNIQUE
+);
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE
+    ON su.public.audit_log
+    FOR EACH ROW
+EXECUTE FUNCTION su.public.ledger_forbid_change();
+
+-- Every journal entry must be balanced in each of its currencies by the time its transaction commits
+CREATE OR REPLACE FUNCTION su.public.ledger_check_balanced() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF EXISTS (SELECT 1
+               FROM su.public.postings
+               WHERE journal_entry_id = NEW.journal_entry_id
+               GROUP BY currency
+               HAVING SUM(amount) <> 0) THEN
+        RAISE EXCEPTION 'journal entry id=% is not balanced', NEW.journal_entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER postings_balanced
+    AFTER INSERT
+    ON su.public.postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+EXECUTE FUNCTION su.public.ledger_check_balanced();
+
+-- Responses of requests sent with an Idempotency-Key header, a NULL status_code marks a request in progress
+CREATE TABLE IF NOT EXISTS su.public.idempotency_keys
+(
+    -- Who sent the key, keys are only unique to their actor
+    actor         TEXT        NOT NULL,
+    key           TEXT        NOT NULL,
+    -- Request holding the key, only it stores the response or releases the key
+    owner         TEXT        NOT NULL,
+    fingerprint   TEXT        NOT NULL,
+    status_code   INT         NULL,
+    content_type  TEXT        NULL,
+    response_body BYTEA       NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- Until when the key is held by the request processing it, its outcome is unknown afterwards
+    locked_until  TIMESTAMPTZ NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (actor, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON su.public.idempotency_keys (expires_at);
+
+-- Keys authenticating the API clients, only a hash of each key is stored
+CREATE TABLE IF NOT EXISTS su.public.api_keys
+(
+    id          BIGSERIAL PRIMARY KEY,
+    name        TEXT        NOT NULL,
+    -- Leading characters of the key, enough to tell keys apart without revealing them
+    prefix      TEXT        NOT NULL,
+    -- Hex encoded SHA-256 of the key
+    key_hash    CHAR(64)    NOT NULL UNIQUE,
+    scopes      TEXT[]      NOT NULL CHECK (cardinality(scopes) > 0),
+    -- Accounts the key is restricted to, empty if it may act on any account
+    account_ids BIGINT[]    NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at  TIMESTAMPTZ NULL
 );
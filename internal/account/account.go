@@ -1,19 +1,109 @@
 package account
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
 
 type Creator interface {
-	Create(ctx context.Context) (int64, error)
+	// Create opens an account in the given currency, owned by owner unless it is empty and belonging to the customer
+	// with customerId unless it is nil.
+	Create(ctx context.Context, code currency.Code, owner string, customerId *int64) (*Record, error)
+}
+
+type CustomerBalancer interface {
+	// CustomerBalances sums up the balances of every account of the customer per currency, closed ones included.
+	CustomerBalances(ctx context.Context, customerId int64) ([]*CustomerBalance, error)
 }
 
 type Finder interface {
 	FindById(ctx context.Context, id int64) (*Record, error)
 }
 
+type BalanceHistorian interface {
+	BalanceAsOf(ctx context.Context, target *Record, asOf time.Time) (money.Amount, error)
+}
+
+type Lister interface {
+	List(ctx context.Context, query *ListQuery) ([]*Record, error)
+}
+
 type TopUpper interface {
-	TopUp(ctx context.Context, target *Record, amount int) error
+	TopUp(ctx context.Context, target *Record, amount money.Amount) (*Record, error)
+}
+
+type Freezer interface {
+	Freeze(ctx context.Context, target *Record) (*Record, error)
+	Unfreeze(ctx context.Context, target *Record) (*Record, error)
+}
+
+type Closer interface {
+	// Close closes the target account, its remaining balance is swept to the sweepTo account unless it is nil.
+	Close(ctx context.Context, target *Record, sweepTo *Record) (*Record, error)
+}
+
+type OverdraftLimiter interface {
+	SetOverdraftLimit(ctx context.Context, target *Record, limit money.Amount) (*Record, error)
+}
+
+type Withdrawer interface {
+	Withdraw(ctx context.Context, source *Record, amount money.Amount) error
 }
 
 type Transferrer interface {
-	Transfer(ctx context.Context, source *Record, target *Record, amount int) error
+	Transfer(ctx context.Context, source *Record, target *Record, amount money.Amount) (*Transfer, error)
+}
+
+type BatchTransferrer interface {
+	TransferBatch(ctx context.Context, source *Record, items []BatchItem, mode BatchMode) ([]*BatchResult, error)
+}
+
+type Exchanger interface {
+	Exchange(ctx context.Context, source *Record, target *Record, amount money.Amount, quoteId string) (*Transfer, error)
+}
+
+type Authorizer interface {
+	Authorize(ctx context.Context, source *Record, target *Record, amount money.Amount) (*Hold, error)
+}
+
+type HoldFinder interface {
+	FindHold(ctx context.Context, id int64) (*Hold, error)
+}
+
+type Capturer interface {
+	Capture(ctx context.Context, hold *Hold, amount money.Amount) (*Hold, error)
+}
+
+type Voider interface {
+	Void(ctx context.Context, hold *Hold) (*Hold, error)
+}
+
+type TransferFinder interface {
+	FindTransfer(ctx context.Context, id int64) (*Transfer, error)
+}
+
+type Reverser interface {
+	Reverse(ctx context.Context, transfer *Transfer, amount money.Amount, allowNegative bool) (*Reversal, error)
+}
+
+type Scheduler interface {
+	// Schedule sets up a transfer to run at startAt, and then again at every recurrence unless it is nil.
+	Schedule(ctx context.Context, source *Record, target *Record, amount money.Amount, startAt time.Time, recurrence *Recurrence) (*ScheduledTransfer, error)
+}
+
+type ScheduledTransferFinder interface {
+	FindScheduledTransfer(ctx context.Context, id int64) (*ScheduledTransfer, error)
+	ScheduledRuns(ctx context.Context, scheduled *ScheduledTransfer) ([]*ScheduledRun, error)
+}
+
+type ScheduledTransferRunner interface {
+	ClaimScheduledTransfers(ctx context.Context, owner string, limit int, leaseTtl time.Duration) ([]*ScheduledTransfer, error)
+	RunScheduledTransfer(ctx context.Context, scheduled *ScheduledTransfer, owner string) (*ScheduledRun, error)
+}
+
+type HistoryFinder interface {
+	History(ctx context.Context, query *HistoryQuery) ([]*Transaction, error)
 }
@@ -0,0 +1,31 @@
+package account
+
+import (
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+const (
+	// BatchModeAllOrNothing runs every transfer of a batch or none of them.
+	BatchModeAllOrNothing BatchMode = "all_or_nothing"
+	// BatchModeBestEffort runs every transfer of a batch on its own, the failure of one does not affect the others.
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+type BatchMode string
+
+func (m BatchMode) IsValid() bool {
+	return m == BatchModeAllOrNothing || m == BatchModeBestEffort
+}
+
+type BatchItem struct {
+	TargetId int64
+	Amount   money.Amount
+}
+
+type BatchResult struct {
+	BatchItem
+	// TransferId is the journal entry of the transfer, it is only set if the transfer succeeded.
+	TransferId *int64
+	// Err is why the transfer failed, it is only set if the transfer failed.
+	Err error
+}
@@ -0,0 +1,121 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/database"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+var ErrEmptyBatch = errors.New("batch has no transfers")
+
+// TransferBatch transfers every item from the source account within a single database transaction. The total of the
+// batch has to be covered by the source account up front, whatever the mode. In BatchModeAllOrNothing the first
+// failing transfer aborts the whole batch, while in BatchModeBestEffort it is reported in its result and the others
+// go ahead.
+func (r *Repository) TransferBatch(ctx context.Context, source *Record, items []BatchItem, mode BatchMode) ([]*BatchResult, error) {
+	if len(items) == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	total := money.Zero(source.Currency)
+	for i, item := range items {
+		var err error
+		if total, err = total.Add(item.Amount); err != nil {
+			return nil, errors.Wrapf(err, "transfer #%d", i)
+		}
+	}
+
+	var results []*BatchResult
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		accounts, err := lockBatchAccounts(ctx, tx, source, items)
+		if err != nil {
+			return err
+		}
+		if err := ensureActive(accounts[source.Id]); err != nil {
+			return err
+		}
+		if err := ensureFunds(accounts[source.Id], total); err != nil {
+			return err
+		}
+
+		results = make([]*BatchResult, 0, len(items))
+		for i, item := range items {
+			result := &BatchResult{BatchItem: item}
+			results = append(results, result)
+
+			if mode == BatchModeBestEffort {
+				if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_item"); err != nil {
+					return errors.Wrap(err, "could not create savepoint")
+				}
+			}
+
+			entryId, err := batchTransfer(ctx, tx, accounts, source.Id, item)
+			if err == nil {
+				result.TransferId = &entryId
+				continue
+			}
+			if mode == BatchModeAllOrNothing || !isRejection(err) {
+				return errors.Wrapf(err, "transfer #%d to account=%d", i, item.TargetId)
+			}
+
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_item"); err != nil {
+				return errors.Wrap(err, "could not roll back to savepoint")
+			}
+			result.Err = err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not transfer batch of total=%s from account=%d", total, source.Id)
+	}
+
+	return results, nil
+}
+
+func batchTransfer(ctx context.Context, tx *sql.Tx, accounts map[int64]*Record, sourceId int64, item BatchItem) (int64, error) {
+	target, ok := accounts[item.TargetId]
+	if !ok {
+		return 0, errors.Wrapf(ErrDoesNotExist, "account id=%d", item.TargetId)
+	}
+	if target.Currency != item.Amount.Currency() {
+		return 0, errors.Wrapf(ErrCurrencyMismatch, "target account id=%d holds %s, transfer is in %s", target.Id, target.Currency, item.Amount.Currency())
+	}
+
+	return transfer(ctx, tx, &Record{Id: sourceId}, target, item.Amount, item.Amount, transferOptions{limited: true})
+}
+
+// lockBatchAccounts locks the source account and every existing target account of the batch up front, in ascending
+// id order like lockAccounts, targets which do not exist are left out and fail their own transfer.
+func lockBatchAccounts(ctx context.Context, tx *sql.Tx, source *Record, items []BatchItem) (map[int64]*Record, error) {
+	ids := make([]int64, 0, len(items)+1)
+	ids = append(ids, source.Id)
+	for _, item := range items {
+		ids = append(ids, item.TargetId)
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM accounts WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not look up batch accounts")
+	}
+	defer rows.Close()
+
+	existing := make([]int64, 0, len(ids))
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.Wrap(err, "could not scan database query result into struct")
+		}
+		existing = append(existing, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over database query result")
+	}
+
+	return lockAccounts(ctx, tx, append(existing, source.Id)...)
+}
@@ -0,0 +1,144 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/outbox"
+)
+
+// Events added to the outbox by the Repository, each in the transaction of the change it describes.
+const (
+	EventAccountCreated          = "AccountCreated"
+	EventAccountToppedUp         = "AccountToppedUp"
+	EventAccountWithdrawn        = "AccountWithdrawn"
+	EventTransferCompleted       = "TransferCompleted"
+	EventExchangeCompleted       = "ExchangeCompleted"
+	EventTransferReversed        = "TransferReversed"
+	EventHoldAuthorized          = "HoldAuthorized"
+	EventHoldCaptured            = "HoldCaptured"
+	EventHoldVoided              = "HoldVoided"
+	EventHoldExpired             = "HoldExpired"
+	EventAccountFrozen           = "AccountFrozen"
+	EventAccountUnfrozen         = "AccountUnfrozen"
+	EventAccountClosed           = "AccountClosed"
+	EventOverdraftLimitChanged   = "OverdraftLimitChanged"
+	EventTransferScheduled       = "TransferScheduled"
+	EventScheduledTransferFailed = "ScheduledTransferFailed"
+)
+
+// EventTypes lists every type of event the Repository adds to the outbox.
+var EventTypes = []string{
+	EventAccountCreated, EventAccountToppedUp, EventAccountWithdrawn,
+	EventTransferCompleted, EventExchangeCompleted, EventTransferReversed,
+	EventHoldAuthorized, EventHoldCaptured, EventHoldVoided, EventHoldExpired,
+	EventAccountFrozen, EventAccountUnfrozen, EventAccountClosed, EventOverdraftLimitChanged,
+	EventTransferScheduled, EventScheduledTransferFailed,
+}
+
+// AccountEvent is the payload of the events changing an account itself, it carries the account as changed.
+type AccountEvent struct {
+	AccountId      int64         `json:"account_id"`
+	Currency       currency.Code `json:"currency"`
+	Status         Status        `json:"status"`
+	OverdraftLimit money.Amount  `json:"overdraft_limit"`
+	Balance        money.Amount  `json:"balance"`
+}
+
+// BalanceEvent is the payload of top-ups and withdrawals.
+type BalanceEvent struct {
+	AccountId int64         `json:"account_id"`
+	EntryId   int64         `json:"entry_id"`
+	Amount    money.Amount  `json:"amount"`
+	Currency  currency.Code `json:"currency"`
+	Balance   money.Amount  `json:"balance"`
+}
+
+// TransferEvent is the payload of transfers, exchanges and reversals. The source and target amounts only differ
+// when an exchange converts between the currencies of the two accounts.
+type TransferEvent struct {
+	TransferId     int64         `json:"transfer_id"`
+	SourceId       int64         `json:"source_id"`
+	TargetId       int64         `json:"target_id"`
+	SourceAmount   money.Amount  `json:"source_amount"`
+	SourceCurrency currency.Code `json:"source_currency"`
+	SourceBalance  money.Amount  `json:"source_balance"`
+	TargetAmount   money.Amount  `json:"target_amount"`
+	TargetCurrency currency.Code `json:"target_currency"`
+	TargetBalance  money.Amount  `json:"target_balance"`
+	ReversalOf     *int64        `json:"reversal_of,omitempty"`
+}
+
+type HoldEvent struct {
+	HoldId     int64         `json:"hold_id"`
+	SourceId   int64         `json:"source_id"`
+	TargetId   int64         `json:"target_id"`
+	Amount     money.Amount  `json:"amount"`
+	Currency   currency.Code `json:"currency"`
+	Status     HoldStatus    `json:"status"`
+	Captured   *money.Amount `json:"captured_amount,omitempty"`
+	TransferId *int64        `json:"transfer_id,omitempty"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+}
+
+type ScheduledTransferEvent struct {
+	ScheduledTransferId int64         `json:"scheduled_transfer_id"`
+	SourceId            int64         `json:"source_id"`
+	TargetId            int64         `json:"target_id"`
+	Amount              money.Amount  `json:"amount"`
+	Currency            currency.Code `json:"currency"`
+	Recurrence          *Recurrence   `json:"recurrence,omitempty"`
+	// ScheduledFor is the run the event is about, the first one for EventTransferScheduled.
+	ScheduledFor time.Time `json:"scheduled_for"`
+	Error        *string   `json:"error,omitempty"`
+}
+
+func addAccountEvent(ctx context.Context, tx *sql.Tx, eventType string, record *Record) error {
+	return outbox.Add(ctx, tx, eventType, []int64{record.Id}, &AccountEvent{
+		AccountId:      record.Id,
+		Currency:       record.Currency,
+		Status:         record.Status,
+		OverdraftLimit: record.OverdraftLimit,
+		Balance:        record.Balance,
+	})
+}
+
+func addBalanceEvent(ctx context.Context, tx *sql.Tx, eventType string, entryId int64, accountId int64, amount money.Amount, balance money.Amount) error {
+	return outbox.Add(ctx, tx, eventType, []int64{accountId}, &BalanceEvent{
+		AccountId: accountId,
+		EntryId:   entryId,
+		Amount:    amount,
+		Currency:  amount.Currency(),
+		Balance:   balance,
+	})
+}
+
+func addHoldEvent(ctx context.Context, tx *sql.Tx, eventType string, hold *Hold) error {
+	return outbox.Add(ctx, tx, eventType, []int64{hold.SourceId, hold.TargetId}, &HoldEvent{
+		HoldId:     hold.Id,
+		SourceId:   hold.SourceId,
+		TargetId:   hold.TargetId,
+		Amount:     hold.Amount,
+		Currency:   hold.Amount.Currency(),
+		Status:     hold.Status,
+		Captured:   hold.Captured,
+		TransferId: hold.TransferId,
+		ExpiresAt:  hold.ExpiresAt,
+	})
+}
+
+func addScheduledEvent(ctx context.Context, tx *sql.Tx, eventType string, scheduled *ScheduledTransfer, scheduledFor time.Time, reason *string) error {
+	return outbox.Add(ctx, tx, eventType, []int64{scheduled.SourceId, scheduled.TargetId}, &ScheduledTransferEvent{
+		ScheduledTransferId: scheduled.Id,
+		SourceId:            scheduled.SourceId,
+		TargetId:            scheduled.TargetId,
+		Amount:              scheduled.Amount,
+		Currency:            scheduled.Amount.Currency(),
+		Recurrence:          scheduled.Recurrence,
+		ScheduledFor:        scheduledFor,
+		Error:               reason,
+	})
+}
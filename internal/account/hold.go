@@ -0,0 +1,32 @@
+package account
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+const (
+	HoldStatusActive   HoldStatus = "active"
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusVoided   HoldStatus = "voided"
+	HoldStatusExpired  HoldStatus = "expired"
+)
+
+type HoldStatus string
+
+// Hold reserves Amount of the source account for a later transfer to the target account,
+// reserved funds still count towards the ledger balance but no longer towards the available balance.
+type Hold struct {
+	Id       int64
+	SourceId int64
+	TargetId int64
+	Amount   money.Amount
+	Status   HoldStatus
+	// Captured is the amount actually transferred, it is only set once the hold is captured.
+	Captured *money.Amount
+	// TransferId is the journal entry of the capturing transfer, it is only set once the hold is captured.
+	TransferId *int64
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+}
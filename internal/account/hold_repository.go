@@ -0,0 +1,232 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/database"
+	"github.com/ktsivkov/su-exc/internal/limits"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+var ErrHoldDoesNotExist = errors.New("hold not found")
+var ErrHoldNotActive = errors.New("hold is not active")
+var ErrHoldExceeded = errors.New("capture amount exceeds the held amount")
+
+// holdColumns are read by scanHold, active holds past their expiry are reported as expired
+// even before ExpireHolds gets to update their status.
+const holdColumns = "id, source_id, target_id, amount, currency, CASE WHEN status = 'active' AND expires_at <= now() THEN 'expired' ELSE status END, captured_amount, journal_entry_id, expires_at, created_at"
+
+func (r *Repository) Authorize(ctx context.Context, source *Record, target *Record, amount money.Amount) (*Hold, error) {
+	if source.Currency != target.Currency {
+		return nil, errors.Wrapf(ErrCurrencyMismatch, "source account id=%d holds %s, target account id=%d holds %s", source.Id, source.Currency, target.Id, target.Currency)
+	}
+	if source.Currency != amount.Currency() {
+		return nil, errors.Wrapf(ErrCurrencyMismatch, "accounts hold %s, hold is in %s", source.Currency, amount.Currency())
+	}
+
+	hold := &Hold{
+		SourceId: source.Id,
+		TargetId: target.Id,
+		Amount:   amount,
+		Status:   HoldStatusActive,
+	}
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		// Locking the source serializes the hold with transfers and other holds competing for the same funds
+		accounts, err := lockAccounts(ctx, tx, source.Id, target.Id)
+		if err != nil {
+			return err
+		}
+		for _, id := range []int64{source.Id, target.Id} {
+			if err := ensureActive(accounts[id]); err != nil {
+				return err
+			}
+		}
+
+		// Holds are turned into transfers of the account owner, so they are refused once the limits could not allow them
+		if err := limits.Check(ctx, tx, source.Id, amount); err != nil {
+			return err
+		}
+		if err := ensureFunds(accounts[source.Id], amount); err != nil {
+			return err
+		}
+
+		res := tx.QueryRowContext(ctx, "INSERT INTO holds (source_id, target_id, amount, currency, expires_at) VALUES ($1, $2, $3, $4, now() + $5 * INTERVAL '1 millisecond') RETURNING id, expires_at, created_at",
+			hold.SourceId, hold.TargetId, hold.Amount, hold.Amount.Currency(), r.holdTtl.Milliseconds())
+		if err := res.Scan(&hold.Id, &hold.ExpiresAt, &hold.CreatedAt); err != nil {
+			return errors.Wrap(err, "could not insert hold")
+		}
+
+		return addHoldEvent(ctx, tx, EventHoldAuthorized, hold)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not hold amount=%s, from account=%d, for account=%d", amount, source.Id, target.Id)
+	}
+
+	return hold, nil
+}
+
+func (r *Repository) FindHold(ctx context.Context, id int64) (*Hold, error) {
+	res := r.db.QueryRowContext(ctx, "SELECT "+holdColumns+" FROM holds WHERE id = $1", id)
+	if res.Err() != nil {
+		return nil, errors.Wrap(res.Err(), "database query failed")
+	}
+
+	hold, err := scanHold(res)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Wrapf(ErrHoldDoesNotExist, "hold id=%d", id)
+		}
+		return nil, errors.Wrap(err, "could not scan database query result into struct")
+	}
+
+	return hold, nil
+}
+
+// Capture transfers amount, which may be less than the held amount, to the target of the hold and releases the rest.
+func (r *Repository) Capture(ctx context.Context, hold *Hold, amount money.Amount) (*Hold, error) {
+	var captured *Hold
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		if captured, err = lockActiveHold(ctx, tx, hold.Id); err != nil {
+			return err
+		}
+
+		cmp, err := amount.Cmp(captured.Amount)
+		if err != nil {
+			return errors.Wrapf(ErrCurrencyMismatch, "hold id=%d is in %s, capture is in %s", captured.Id, captured.Amount.Currency(), amount.Currency())
+		}
+		if cmp > 0 {
+			return errors.Wrapf(ErrHoldExceeded, "held amount=%s, capture amount=%s", captured.Amount, amount)
+		}
+
+		// The hold has to be released first, otherwise it would still reserve the funds the transfer is about to take
+		if _, err := tx.ExecContext(ctx, "UPDATE holds SET status = $1, captured_amount = $2 WHERE id = $3", HoldStatusCaptured, amount, captured.Id); err != nil {
+			return errors.Wrapf(err, "could not capture hold id=%d", captured.Id)
+		}
+
+		entryId, err := transfer(ctx, tx, &Record{Id: captured.SourceId}, &Record{Id: captured.TargetId}, amount, amount, transferOptions{limited: true})
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE holds SET journal_entry_id = $1 WHERE id = $2", entryId, captured.Id); err != nil {
+			return errors.Wrapf(err, "could not link hold id=%d to its transfer", captured.Id)
+		}
+
+		captured.Status, captured.Captured, captured.TransferId = HoldStatusCaptured, &amount, &entryId
+		return addHoldEvent(ctx, tx, EventHoldCaptured, captured)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not capture amount=%s of hold id=%d", amount, hold.Id)
+	}
+
+	return captured, nil
+}
+
+func (r *Repository) Void(ctx context.Context, hold *Hold) (*Hold, error) {
+	var voided *Hold
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		var err error
+		if voided, err = lockActiveHold(ctx, tx, hold.Id); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE holds SET status = $1 WHERE id = $2", HoldStatusVoided, voided.Id); err != nil {
+			return errors.Wrapf(err, "could not void hold id=%d", voided.Id)
+		}
+
+		voided.Status = HoldStatusVoided
+		// The accounts are locked for the event only, so that it is ordered after the events already committed for them
+		if _, err := lockAccounts(ctx, tx, voided.SourceId, voided.TargetId); err != nil {
+			return err
+		}
+		return addHoldEvent(ctx, tx, EventHoldVoided, voided)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not void hold id=%d", hold.Id)
+	}
+
+	return voided, nil
+}
+
+// ExpireHolds marks the active holds past their expiry as expired and returns how many were updated.
+func (r *Repository) ExpireHolds(ctx context.Context) (int64, error) {
+	var expired []*Hold
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, "UPDATE holds SET status = $1 WHERE status = $2 AND expires_at <= now() RETURNING "+holdColumns, HoldStatusExpired, HoldStatusActive)
+		if err != nil {
+			return errors.Wrap(err, "database request failed")
+		}
+		defer rows.Close()
+
+		expired = nil
+		var ids []int64
+		for rows.Next() {
+			hold, err := scanHold(rows)
+			if err != nil {
+				return errors.Wrap(err, "could not scan database query result into struct")
+			}
+			expired = append(expired, hold)
+			ids = append(ids, hold.SourceId, hold.TargetId)
+		}
+		if err := rows.Err(); err != nil {
+			return errors.Wrap(err, "could not iterate over database query result")
+		}
+		rows.Close()
+
+		// The accounts are locked for the events only, so that they are ordered after the events already committed for them
+		if _, err := lockAccounts(ctx, tx, ids...); err != nil {
+			return err
+		}
+		for _, hold := range expired {
+			if err := addHoldEvent(ctx, tx, EventHoldExpired, hold); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "could not expire holds")
+	}
+
+	return int64(len(expired)), nil
+}
+
+func lockActiveHold(ctx context.Context, tx *sql.Tx, id int64) (*Hold, error) {
+	hold, err := scanHold(tx.QueryRowContext(ctx, "SELECT "+holdColumns+" FROM holds WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Wrapf(ErrHoldDoesNotExist, "hold id=%d", id)
+		}
+		return nil, errors.Wrapf(err, "could not lock hold id=%d", id)
+	}
+
+	if hold.Status != HoldStatusActive {
+		return nil, errors.Wrapf(ErrHoldNotActive, "hold id=%d is %s", id, hold.Status)
+	}
+
+	return hold, nil
+}
+
+func scanHold(row interface{ Scan(dest ...any) error }) (*Hold, error) {
+	hold := &Hold{}
+	var amount int64
+	var captured *int64
+	var code currency.Code
+	if err := row.Scan(&hold.Id, &hold.SourceId, &hold.TargetId, &amount, &code, &hold.Status, &captured, &hold.TransferId, &hold.ExpiresAt, &hold.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	hold.Amount = money.New(amount, code)
+	if captured != nil {
+		capturedAmount := money.New(*captured, code)
+		hold.Captured = &capturedAmount
+	}
+
+	return hold, nil
+}
@@ -0,0 +1,26 @@
+package account
+
+const (
+	SortById      SortField = "id"
+	SortByBalance SortField = "balance"
+)
+
+type SortField string
+
+type ListQuery struct {
+	SortBy     SortField
+	Descending bool
+	// After is the keyset position, only records sorted after it are returned when set.
+	After *Position
+	// MinBalance and MaxBalance are in minor units of the account currencies.
+	MinBalance *int64
+	MaxBalance *int64
+	// CustomerId restricts the records to the accounts of the customer when set.
+	CustomerId *int64
+	Limit      int
+}
+
+type Position struct {
+	Id      int64
+	Balance int64
+}
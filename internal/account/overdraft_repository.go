@@ -0,0 +1,49 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/database"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+var ErrInvalidOverdraftLimit = errors.New("overdraft limit must not be negative")
+
+// SetOverdraftLimit changes how far below zero the balance of the target account may go. Lowering the limit
+// below what the account already owes is allowed, it only prevents any further debits until the account recovers.
+func (r *Repository) SetOverdraftLimit(ctx context.Context, target *Record, limit money.Amount) (*Record, error) {
+	if target.Currency != limit.Currency() {
+		return nil, errors.Wrapf(ErrCurrencyMismatch, "account id=%d holds %s, overdraft limit is in %s", target.Id, target.Currency, limit.Currency())
+	}
+	if limit.IsNegative() {
+		return nil, errors.Wrapf(ErrInvalidOverdraftLimit, "overdraft limit=%s", limit)
+	}
+
+	var changed *Record
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		accounts, err := lockAccounts(ctx, tx, target.Id)
+		if err != nil {
+			return err
+		}
+
+		changed = accounts[target.Id]
+		if changed.Status == StatusClosed {
+			return errors.Wrapf(ErrAccountClosed, "account id=%d", changed.Id)
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE accounts SET overdraft_limit = $1 WHERE id = $2", limit, changed.Id); err != nil {
+			return errors.Wrapf(err, "could not update overdraft limit of account with id=%d", changed.Id)
+		}
+
+		changed.OverdraftLimit = limit
+		return addAccountEvent(ctx, tx, EventOverdraftLimitChanged, changed)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not set overdraft limit=%s of account with id=%d", limit, target.Id)
+	}
+
+	return changed, nil
+}
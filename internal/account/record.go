@@ -1,6 +1,63 @@
 package account
 
+import (
+	"fmt"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+const (
+	StatusActive Status = "active"
+	// StatusFrozen accounts keep their balance but cannot take part in any movement of money until unfrozen.
+	StatusFrozen Status = "frozen"
+	// StatusClosed accounts have a zero balance and never take part in a movement of money again.
+	StatusClosed Status = "closed"
+)
+
+type Status string
+
 type Record struct {
-	Id      int64
-	Balance string
+	Id       int64
+	Currency currency.Code
+	Status   Status
+	// OverdraftLimit is how far below zero the available balance may go, it is never negative.
+	OverdraftLimit money.Amount
+	// Balance is the ledger balance, it includes funds reserved by active holds.
+	Balance money.Amount
+	// Available is the balance minus the funds reserved by active holds.
+	Available money.Amount
+	// Owner is the subject of the token the account was created with, accounts created otherwise have none.
+	Owner string
+	// CustomerId is the customer the account belongs to, if any.
+	CustomerId *int64
+}
+
+// CustomerBalance sums up the balances of the accounts of a customer held in Currency.
+type CustomerBalance struct {
+	Currency  currency.Code
+	Accounts  int
+	Balance   money.Amount
+	Available money.Amount
+}
+
+// InsufficientBalance is returned, wrapped, when a debit would take the available balance of an account below its
+// overdraft limit, it unwraps to ErrInsufficientBalance.
+type InsufficientBalance struct {
+	AccountId      int64
+	Available      money.Amount
+	OverdraftLimit money.Amount
+	Required       money.Amount
+}
+
+func (e *InsufficientBalance) Error() string {
+	return fmt.Sprintf("%s: available balance=%s, overdraft limit=%s, required amount=%s", ErrInsufficientBalance, e.Available, e.OverdraftLimit, e.Required)
+}
+
+func (e *InsufficientBalance) Unwrap() error {
+	return ErrInsufficientBalance
+}
+
+func (r *Record) IsOwnedBy(owner string) bool {
+	return r.Owner != "" && r.Owner == owner
 }
@@ -3,48 +3,122 @@ package account
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/audit"
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/customer"
+	"github.com/ktsivkov/su-exc/internal/database"
+	"github.com/ktsivkov/su-exc/internal/fx"
+	"github.com/ktsivkov/su-exc/internal/ledger"
+	"github.com/ktsivkov/su-exc/internal/limits"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/outbox"
 )
 
 var ErrDoesNotExist = errors.New("account not found")
 var ErrInsufficientBalance = errors.New("insufficient balance")
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+var ErrAccountFrozen = errors.New("account is frozen")
+var ErrAccountClosed = errors.New("account is closed")
+
+// recordColumns are read by scanRecord.
+const recordColumns = "id, currency, status, overdraft_limit, COALESCE(owner, ''), customer_id, balance, " + availableColumn
+
+// availableColumn is the balance of the accounts row minus the funds reserved by its active holds, holds past their
+// expiry no longer reserve any funds.
+const availableColumn = "balance - (SELECT COALESCE(SUM(h.amount), 0) FROM holds h WHERE h.source_id = accounts.id AND h.status = 'active' AND h.expires_at > now())::BIGINT"
 
-func NewRepository(db *sql.DB) (*Repository, error) {
+func NewRepository(db *sql.DB, holdTtl time.Duration) (*Repository, error) {
 	if db == nil {
 		return nil, errors.New("db cannot be nil")
 	}
+	if holdTtl <= 0 {
+		return nil, errors.New("hold ttl must be positive")
+	}
 	return &Repository{
-		db: db,
+		db:      db,
+		holdTtl: holdTtl,
 	}, nil
 }
 
 type Repository struct {
-	db *sql.DB
+	db      *sql.DB
+	holdTtl time.Duration
 }
 
-func (r *Repository) Create(ctx context.Context) (int64, error) {
-	res := r.db.QueryRowContext(ctx, "INSERT INTO accounts DEFAULT VALUES RETURNING id")
-	if res.Err() != nil {
-		return 0, errors.Wrap(res.Err(), "database request failed")
+func (r *Repository) Create(ctx context.Context, code currency.Code, owner string, customerId *int64) (*Record, error) {
+	if !code.IsValid() {
+		return nil, errors.Wrapf(currency.ErrUnsupported, "code=%q", code)
 	}
 
-	var id int64
-	if err := res.Scan(&id); err != nil {
-		return 0, errors.Wrap(err, "could not read last inserted id")
+	var created *Record
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		// The customer is locked until the account is linked, so that it cannot be deleted in between
+		if customerId != nil {
+			var found bool
+			if err := tx.QueryRowContext(ctx, "SELECT TRUE FROM customers WHERE id = $1 FOR KEY SHARE", *customerId).Scan(&found); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return errors.Wrapf(customer.ErrDoesNotExist, "customer id=%d", *customerId)
+				}
+				return errors.Wrapf(err, "could not lock customer with id=%d", *customerId)
+			}
+		}
+
+		res := tx.QueryRowContext(ctx, "INSERT INTO accounts (currency, owner, customer_id) VALUES ($1, NULLIF($2, ''), $3) RETURNING "+recordColumns, code, owner, customerId)
+		var err error
+		if created, err = scanRecord(res); err != nil {
+			return errors.Wrap(err, "could not read inserted account")
+		}
+
+		audit.Observe(ctx, created.Id, created.Balance)
+		return addAccountEvent(ctx, tx, EventAccountCreated, created)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create %s account", code)
 	}
 
-	return id, nil
+	return created, nil
+}
+
+func (r *Repository) CustomerBalances(ctx context.Context, customerId int64) ([]*CustomerBalance, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT currency, COUNT(*), SUM(balance)::BIGINT, SUM(available)::BIGINT FROM (SELECT currency, balance, "+availableColumn+" AS available FROM accounts WHERE customer_id = $1) customer_accounts GROUP BY currency ORDER BY currency", customerId)
+	if err != nil {
+		return nil, errors.Wrap(err, "database query failed")
+	}
+	defer rows.Close()
+
+	balances := make([]*CustomerBalance, 0)
+	for rows.Next() {
+		var code currency.Code
+		var balance, available int64
+		total := &CustomerBalance{}
+		if err := rows.Scan(&code, &total.Accounts, &balance, &available); err != nil {
+			return nil, errors.Wrap(err, "could not scan database query result into struct")
+		}
+		total.Currency, total.Balance, total.Available = code, money.New(balance, code), money.New(available, code)
+		balances = append(balances, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over database query result")
+	}
+
+	return balances, nil
 }
 
 func (r *Repository) FindById(ctx context.Context, id int64) (*Record, error) {
-	res := r.db.QueryRowContext(ctx, "SELECT id, balance FROM accounts WHERE id = $1", id)
+	res := r.db.QueryRowContext(ctx, "SELECT "+recordColumns+" FROM accounts WHERE id = $1", id)
 	if res.Err() != nil {
 		return nil, errors.Wrap(res.Err(), "database query failed")
 	}
 
-	record := &Record{}
-	if err := res.Scan(&record.Id, &record.Balance); err != nil {
+	record, err := scanRecord(res)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.Wrapf(ErrDoesNotExist, "account id=%d", id)
 		}
@@ -54,46 +128,541 @@ func (r *Repository) FindById(ctx context.Context, id int64) (*Record, error) {
 	return record, nil
 }
 
-func (r *Repository) TopUp(ctx context.Context, target *Record, amount int) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE accounts SET balance = balance+$1 WHERE id = $2", amount, target.Id)
+func (r *Repository) List(ctx context.Context, query *ListQuery) ([]*Record, error) {
+	// The sort field is whitelisted as it cannot be passed as a query argument
+	var sortColumns string
+	switch query.SortBy {
+	case SortById:
+		sortColumns = "id"
+	case SortByBalance:
+		sortColumns = "balance, id"
+	default:
+		return nil, errors.Errorf("unsupported sort field %q", query.SortBy)
+	}
+
+	stmt := "SELECT " + recordColumns + " FROM accounts WHERE TRUE"
+	var args []any
+	addArg := func(arg any) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if query.After != nil {
+		comparator, position := ">", addArg(query.After.Id)
+		if query.Descending {
+			comparator = "<"
+		}
+		if query.SortBy == SortByBalance {
+			position = fmt.Sprintf("%s::BIGINT, %s", addArg(query.After.Balance), position)
+		}
+		stmt += fmt.Sprintf(" AND (%s) %s (%s)", sortColumns, comparator, position)
+	}
+	if query.MinBalance != nil {
+		stmt += " AND balance >= " + addArg(*query.MinBalance)
+	}
+	if query.MaxBalance != nil {
+		stmt += " AND balance <= " + addArg(*query.MaxBalance)
+	}
+	if query.CustomerId != nil {
+		stmt += " AND customer_id = " + addArg(*query.CustomerId)
+	}
+
+	direction := "ASC"
+	if query.Descending {
+		direction = "DESC"
+	}
+	stmt += " ORDER BY " + strings.ReplaceAll(sortColumns, ",", " "+direction+",") + " " + direction
+	stmt += " LIMIT " + addArg(query.Limit)
+
+	rows, err := r.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "database query failed")
+	}
+	defer rows.Close()
+
+	records := make([]*Record, 0, query.Limit)
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan database query result into struct")
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over database query result")
+	}
+
+	return records, nil
+}
+
+func (r *Repository) TopUp(ctx context.Context, target *Record, amount money.Amount) (*Record, error) {
+	if target.Currency != amount.Currency() {
+		return nil, errors.Wrapf(ErrCurrencyMismatch, "account id=%d holds %s, top-up is in %s", target.Id, target.Currency, amount.Currency())
+	}
+
+	var topped *Record
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		accounts, err := lockAccounts(ctx, tx, target.Id)
+		if err != nil {
+			return err
+		}
+		topped = accounts[target.Id]
+		if err := ensureActive(topped); err != nil {
+			return err
+		}
+
+		balance, err := topped.Balance.Add(amount)
+		if err != nil {
+			return err
+		}
+		if err := setBalance(ctx, tx, topped, balance); err != nil {
+			return err
+		}
+
+		entryId, err := ledger.Post(ctx, tx, &ledger.Entry{
+			Kind: ledger.KindTopUp,
+			Postings: []ledger.Posting{
+				ledger.Debit(ledger.FundingAccountId, amount),
+				ledger.Credit(target.Id, amount),
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := addBalanceEvent(ctx, tx, EventAccountToppedUp, entryId, target.Id, amount, balance); err != nil {
+			return err
+		}
+
+		return recordTransaction(ctx, tx, entryId, &Transaction{
+			AccountId: target.Id,
+			Type:      TransactionTypeTopUp,
+			Direction: DirectionIn,
+			Amount:    amount,
+			Balance:   balance,
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not top-up account with id=%d", target.Id)
+	}
+
+	return topped, nil
+}
+
+func (r *Repository) Withdraw(ctx context.Context, source *Record, amount money.Amount) error {
+	if source.Currency != amount.Currency() {
+		return errors.Wrapf(ErrCurrencyMismatch, "account id=%d holds %s, withdrawal is in %s", source.Id, source.Currency, amount.Currency())
+	}
+
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		accounts, err := lockAccounts(ctx, tx, source.Id)
+		if err != nil {
+			return err
+		}
+		if err := ensureActive(accounts[source.Id]); err != nil {
+			return err
+		}
+
+		if err := ensureFunds(accounts[source.Id], amount); err != nil {
+			return err
+		}
+
+		balance, err := accounts[source.Id].Balance.Sub(amount)
+		if err != nil {
+			return err
+		}
+		if err := setBalance(ctx, tx, accounts[source.Id], balance); err != nil {
+			return err
+		}
+
+		entryId, err := ledger.Post(ctx, tx, &ledger.Entry{
+			Kind: ledger.KindWithdrawal,
+			Postings: []ledger.Posting{
+				ledger.Debit(source.Id, amount),
+				ledger.Credit(ledger.PayoutAccountId, amount),
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := addBalanceEvent(ctx, tx, EventAccountWithdrawn, entryId, source.Id, amount, balance); err != nil {
+			return err
+		}
+
+		return recordTransaction(ctx, tx, entryId, &Transaction{
+			AccountId: source.Id,
+			Type:      TransactionTypeWithdrawal,
+			Direction: DirectionOut,
+			Amount:    amount,
+			Balance:   balance,
+		})
+	})
 	if err != nil {
-		return errors.Wrapf(err, "could not top-up account with id=%d", target.Id)
+		return errors.Wrapf(err, "could not withdraw amount=%s from account with id=%d", amount, source.Id)
 	}
 
 	return nil
 }
 
-func (r *Repository) Transfer(ctx context.Context, source *Record, target *Record, amount int) error {
-	tx, err := r.db.BeginTx(ctx, nil)
+func (r *Repository) Transfer(ctx context.Context, source *Record, target *Record, amount money.Amount) (*Transfer, error) {
+	if source.Currency != target.Currency {
+		return nil, errors.Wrapf(ErrCurrencyMismatch, "source account id=%d holds %s, target account id=%d holds %s", source.Id, source.Currency, target.Id, target.Currency)
+	}
+	if source.Currency != amount.Currency() {
+		return nil, errors.Wrapf(ErrCurrencyMismatch, "accounts hold %s, transfer is in %s", source.Currency, amount.Currency())
+	}
+
+	var completed *Transfer
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		entryId, err := transfer(ctx, tx, source, target, amount, amount, transferOptions{limited: true})
+		if err != nil {
+			return err
+		}
+
+		completed, err = readTransfer(ctx, tx, entryId)
+		return err
+	})
 	if err != nil {
-		return errors.Wrap(err, "could not start transaction")
+		return nil, errors.Wrapf(err, "could not transfer amount=%s, from account=%d, to account=%d", amount, source.Id, target.Id)
 	}
-	defer tx.Rollback()
 
-	res := tx.QueryRowContext(ctx, "SELECT balance FROM accounts WHERE id = $1", source.Id)
-	if err := res.Err(); res.Err() != nil {
-		return errors.Wrapf(err, "could not query source account with id=%d", source.Id)
+	return completed, nil
+}
+
+func (r *Repository) Exchange(ctx context.Context, source *Record, target *Record, amount money.Amount, quoteId string) (*Transfer, error) {
+	if source.Currency != amount.Currency() {
+		return nil, errors.Wrapf(ErrCurrencyMismatch, "source account id=%d holds %s, exchange is in %s", source.Id, source.Currency, amount.Currency())
 	}
-	var sourceBalance int
-	if err := res.Scan(&sourceBalance); err != nil {
-		return errors.Wrapf(err, "could not balance of source account with id=%d", source.Id)
+
+	var completed *Transfer
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		quote, err := fx.Consume(ctx, tx, quoteId, source.Currency, target.Currency)
+		if err != nil {
+			return err
+		}
+
+		converted, err := fx.Convert(amount, target.Currency, quote.Rate)
+		if err != nil {
+			return err
+		}
+
+		entryId, err := transfer(ctx, tx, source, target, amount, converted, transferOptions{
+			exchange: &ledger.Exchange{
+				QuoteId: quote.Id,
+				Rate:    quote.Rate,
+			},
+			limited: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		completed, err = readTransfer(ctx, tx, entryId)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not exchange amount=%s, from account=%d, to account=%d", amount, source.Id, target.Id)
 	}
 
-	if sourceBalance-amount < 0 {
-		return errors.Wrapf(ErrInsufficientBalance, "available balance=%d, required amount=%d", sourceBalance, amount)
+	return completed, nil
+}
+
+func (r *Repository) History(ctx context.Context, query *HistoryQuery) ([]*Transaction, error) {
+	stmt := "SELECT id, account_id, journal_entry_id, type, direction, counterparty_id, amount, currency, balance, created_at FROM transactions WHERE account_id = $1"
+	args := []any{query.AccountId}
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		stmt += fmt.Sprintf(" AND %s $%d", condition, len(args))
 	}
 
-	if _, err := tx.ExecContext(ctx, "UPDATE accounts SET balance=balance+$1 WHERE id=$2", amount, target.Id); err != nil {
-		return errors.Errorf("could not update balance of target account with id=%d", target.Id)
+	if query.Before > 0 {
+		addCondition("id <", query.Before)
+	}
+	if !query.From.IsZero() {
+		addCondition("created_at >=", query.From)
+	}
+	if !query.To.IsZero() {
+		addCondition("created_at <", query.To)
+	}
+	if query.Direction != "" {
+		addCondition("direction =", query.Direction)
+	}
+	if query.Type != "" {
+		addCondition("type =", query.Type)
+	}
+	args = append(args, query.Limit)
+	stmt += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "database query failed")
+	}
+	defer rows.Close()
+
+	transactions := make([]*Transaction, 0, query.Limit)
+	for rows.Next() {
+		transaction := &Transaction{}
+		var amount, balance int64
+		var code currency.Code
+		if err := rows.Scan(&transaction.Id, &transaction.AccountId, &transaction.EntryId, &transaction.Type, &transaction.Direction, &transaction.CounterpartyId, &amount, &code, &balance, &transaction.CreatedAt); err != nil {
+			return nil, errors.Wrap(err, "could not scan database query result into struct")
+		}
+		transaction.Amount, transaction.Balance = money.New(amount, code), money.New(balance, code)
+		transactions = append(transactions, transaction)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over database query result")
+	}
+
+	return transactions, nil
+}
+
+// scanRecord reads an account row selected with recordColumns.
+func scanRecord(row interface{ Scan(dest ...any) error }) (*Record, error) {
+	record := &Record{}
+	var overdraftLimit, balance, available int64
+	if err := row.Scan(&record.Id, &record.Currency, &record.Status, &overdraftLimit, &record.Owner, &record.CustomerId, &balance, &available); err != nil {
+		return nil, err
+	}
+	record.OverdraftLimit = money.New(overdraftLimit, record.Currency)
+	record.Balance, record.Available = money.New(balance, record.Currency), money.New(available, record.Currency)
+
+	return record, nil
+}
+
+// setBalance overwrites the balance of an account locked by lockAccounts and updates the record to match, the new
+// balance is computed with checked arithmetic beforehand, so that an overflow is reported instead of wrapping around.
+func setBalance(ctx context.Context, tx *sql.Tx, record *Record, balance money.Amount) error {
+	res, err := tx.ExecContext(ctx, "UPDATE accounts SET balance = $1 WHERE id = $2", balance, record.Id)
+	if err != nil {
+		return errors.Wrapf(err, "could not update balance of account with id=%d", record.Id)
+	}
+
+	if affected, err := res.RowsAffected(); err == nil && affected == 0 {
+		return errors.Wrapf(ErrDoesNotExist, "account id=%d", record.Id)
 	}
 
-	if _, err := tx.ExecContext(ctx, "UPDATE accounts SET balance=balance-$1 WHERE id=$2", amount, source.Id); err != nil {
-		return errors.Errorf("could not update balance of source account with id=%d", source.Id)
+	// The funds reserved by holds are left as they were
+	reserved, err := record.Balance.Sub(record.Available)
+	if err != nil {
+		return err
+	}
+	if record.Available, err = balance.Sub(reserved); err != nil {
+		return err
 	}
+	record.Balance = balance
+	audit.Observe(ctx, record.Id, balance)
+
+	return nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return errors.Wrapf(err, "could not commit transaction of amount=%d, from account=%d, to account=%d", amount, source.Id, target.Id)
+func recordTransaction(ctx context.Context, tx *sql.Tx, entryId int64, transaction *Transaction) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO transactions (account_id, journal_entry_id, type, direction, counterparty_id, amount, currency, balance) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		transaction.AccountId, entryId, transaction.Type, transaction.Direction, transaction.CounterpartyId, transaction.Amount, transaction.Amount.Currency(), transaction.Balance)
+	if err != nil {
+		return errors.Wrapf(err, "could not record %s transaction of account with id=%d", transaction.Type, transaction.AccountId)
 	}
 
 	return nil
 }
+
+type transferOptions struct {
+	// exchange is only set when the transfer converts between the currencies of the two accounts.
+	exchange *ledger.Exchange
+	// reversalOf is the id of the transfer being compensated, it is only set on reversals.
+	reversalOf *int64
+	// allowNegative lets the source balance go below its overdraft limit instead of failing with ErrInsufficientBalance.
+	allowNegative bool
+	// sweep empties a source account which is being closed, it may do so even if the account is frozen.
+	sweep bool
+	// limited subjects the source account to its transfer limits, it is only set on transfers the account owner asked for.
+	limited bool
+}
+
+// transfer debits the source and credits the target account within tx and returns the id of the journal entry,
+// the debited and credited amounts only differ when an exchange converts between the currencies of the two accounts.
+func transfer(ctx context.Context, tx *sql.Tx, source *Record, target *Record, debit money.Amount, credit money.Amount, opts transferOptions) (int64, error) {
+	accounts, err := lockAccounts(ctx, tx, source.Id, target.Id)
+	if err != nil {
+		return 0, err
+	}
+	if err := ensureActive(accounts[target.Id]); err != nil {
+		return 0, err
+	}
+	if err := ensureActive(accounts[source.Id]); err != nil && !opts.sweep {
+		return 0, err
+	}
+
+	if opts.limited {
+		if err := limits.Check(ctx, tx, source.Id, debit); err != nil {
+			return 0, err
+		}
+	}
+	if !opts.allowNegative {
+		if err := ensureFunds(accounts[source.Id], debit); err != nil {
+			return 0, err
+		}
+	}
+
+	sourceBalance, err := accounts[source.Id].Balance.Sub(debit)
+	if err != nil {
+		return 0, err
+	}
+	// A transfer to the same account credits the balance it has just been debited from, as both are the same record
+	if err := setBalance(ctx, tx, accounts[source.Id], sourceBalance); err != nil {
+		return 0, err
+	}
+
+	targetBalance, err := accounts[target.Id].Balance.Add(credit)
+	if err != nil {
+		return 0, err
+	}
+	if err := setBalance(ctx, tx, accounts[target.Id], targetBalance); err != nil {
+		return 0, err
+	}
+
+	transactionType, eventType := TransactionTypeTransfer, EventTransferCompleted
+	entry := &ledger.Entry{
+		Kind: ledger.KindTransfer,
+		Postings: []ledger.Posting{
+			ledger.Debit(source.Id, debit),
+			ledger.Credit(target.Id, credit),
+		},
+	}
+	if opts.reversalOf != nil {
+		transactionType, eventType = TransactionTypeReversal, EventTransferReversed
+		entry.Kind = ledger.KindReversal
+		entry.ReversalOf = opts.reversalOf
+	}
+	if opts.exchange != nil {
+		// The exchange account buys the source currency and sells the target currency, balancing both sides
+		eventType = EventExchangeCompleted
+		entry.Kind = ledger.KindExchange
+		entry.Exchange = opts.exchange
+		entry.Postings = []ledger.Posting{
+			ledger.Debit(source.Id, debit),
+			ledger.Credit(ledger.ExchangeAccountId, debit),
+			ledger.Debit(ledger.ExchangeAccountId, credit),
+			ledger.Credit(target.Id, credit),
+		}
+	}
+
+	entryId, err := ledger.Post(ctx, tx, entry)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := recordTransaction(ctx, tx, entryId, &Transaction{
+		AccountId:      source.Id,
+		Type:           transactionType,
+		Direction:      DirectionOut,
+		CounterpartyId: &target.Id,
+		Amount:         debit,
+		Balance:        sourceBalance,
+	}); err != nil {
+		return 0, err
+	}
+
+	if err := recordTransaction(ctx, tx, entryId, &Transaction{
+		AccountId:      target.Id,
+		Type:           transactionType,
+		Direction:      DirectionIn,
+		CounterpartyId: &source.Id,
+		Amount:         credit,
+		Balance:        targetBalance,
+	}); err != nil {
+		return 0, err
+	}
+
+	if err := outbox.Add(ctx, tx, eventType, []int64{source.Id, target.Id}, &TransferEvent{
+		TransferId:     entryId,
+		SourceId:       source.Id,
+		TargetId:       target.Id,
+		SourceAmount:   debit,
+		SourceCurrency: debit.Currency(),
+		SourceBalance:  sourceBalance,
+		TargetAmount:   credit,
+		TargetCurrency: credit.Currency(),
+		TargetBalance:  targetBalance,
+		ReversalOf:     opts.reversalOf,
+	}); err != nil {
+		return 0, err
+	}
+
+	return entryId, nil
+}
+
+// isRejection tells the errors a transfer is refused with for business reasons apart from technical failures.
+func isRejection(err error) bool {
+	for _, rejection := range []error{ErrInsufficientBalance, ErrAccountFrozen, ErrAccountClosed, ErrDoesNotExist, ErrCurrencyMismatch, limits.ErrLimitExceeded, money.ErrOverflow} {
+		if errors.Is(err, rejection) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureActive rejects movements of money involving frozen or closed accounts.
+func ensureActive(record *Record) error {
+	switch record.Status {
+	case StatusFrozen:
+		return errors.Wrapf(ErrAccountFrozen, "account id=%d", record.Id)
+	case StatusClosed:
+		return errors.Wrapf(ErrAccountClosed, "account id=%d", record.Id)
+	default:
+		return nil
+	}
+}
+
+// ensureFunds rejects debiting amount from an account locked by lockAccounts if it would take the available
+// balance below the overdraft limit of the account.
+func ensureFunds(record *Record, amount money.Amount) error {
+	available, err := record.Available.Sub(amount)
+	if err != nil {
+		return err
+	}
+	floor, err := record.OverdraftLimit.Neg()
+	if err != nil {
+		return err
+	}
+
+	cmp, err := available.Cmp(floor)
+	if err != nil {
+		return err
+	}
+	if cmp < 0 {
+		return &InsufficientBalance{
+			AccountId:      record.Id,
+			Available:      record.Available,
+			OverdraftLimit: record.OverdraftLimit,
+			Required:       amount,
+		}
+	}
+
+	return nil
+}
+
+// lockAccounts acquires row-level locks on the given accounts in ascending id order, so that concurrent
+// transactions touching the same pair of accounts always queue up instead of deadlocking each other.
+func lockAccounts(ctx context.Context, tx *sql.Tx, ids ...int64) (map[int64]*Record, error) {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	accounts := make(map[int64]*Record, len(ids))
+	for _, id := range ids {
+		res := tx.QueryRowContext(ctx, "SELECT "+recordColumns+" FROM accounts WHERE id = $1 FOR UPDATE", id)
+		record, err := scanRecord(res)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, errors.Wrapf(ErrDoesNotExist, "account id=%d", id)
+			}
+			return nil, errors.Wrapf(err, "could not lock account with id=%d", id)
+		}
+		audit.Observe(ctx, id, record.Balance)
+		accounts[id] = record
+	}
+
+	return accounts, nil
+}
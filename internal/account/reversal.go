@@ -0,0 +1,50 @@
+package account
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+const (
+	TransferStatusCompleted         TransferStatus = "completed"
+	TransferStatusPartiallyReversed TransferStatus = "partially_reversed"
+	TransferStatusReversed          TransferStatus = "reversed"
+)
+
+type TransferStatus string
+
+// Transfer is a completed movement of money between two accounts, identified by its journal entry.
+type Transfer struct {
+	Id       int64
+	SourceId int64
+	TargetId int64
+	// Amount is debited from the source, TargetAmount is credited to the target and differs from it only for exchanges.
+	Amount       money.Amount
+	TargetAmount money.Amount
+	// SourceBalance is the balance of the source right after the transfer.
+	SourceBalance money.Amount
+	// Reversed is the part of Amount already sent back to the source by reversals.
+	Reversed  money.Amount
+	CreatedAt time.Time
+}
+
+func (t *Transfer) Status() TransferStatus {
+	switch {
+	case t.Reversed.IsZero():
+		return TransferStatusCompleted
+	case t.Reversed.Minor() >= t.Amount.Minor():
+		return TransferStatusReversed
+	default:
+		return TransferStatusPartiallyReversed
+	}
+}
+
+// Reversal sends (part of) a transfer back from its target to its source, identified by its own journal entry.
+type Reversal struct {
+	Id         int64
+	TransferId int64
+	SourceId   int64
+	TargetId   int64
+	Amount     money.Amount
+}
@@ -0,0 +1,112 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/database"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+var ErrTransferDoesNotExist = errors.New("transfer not found")
+var ErrTransferAlreadyReversed = errors.New("transfer is already fully reversed")
+var ErrReversalExceedsTransfer = errors.New("reversal amount exceeds the amount left to reverse")
+
+// transferSelect selects a movement of money between two accounts as recorded in the transactions of both sides,
+// along with the source balance it resulted in and the amount already reversed.
+const transferSelect = `SELECT e.id, o.account_id, i.account_id, o.amount, o.currency, i.amount, i.currency, o.balance, e.created_at,
+	(SELECT COALESCE(SUM(p.amount), 0) FROM journal_entries r JOIN postings p ON p.journal_entry_id = r.id WHERE r.reversal_of = e.id AND p.amount > 0)::BIGINT
+FROM journal_entries e
+JOIN transactions o ON o.journal_entry_id = e.id AND o.direction = 'out'
+JOIN transactions i ON i.journal_entry_id = e.id AND i.direction = 'in'
+WHERE e.id = $1`
+
+// transferQuery selects a plain transfer, exchanges and entries of any other kind cannot be reversed and are not
+// found by it.
+const transferQuery = transferSelect + " AND e.kind = 'transfer'"
+
+func (r *Repository) FindTransfer(ctx context.Context, id int64) (*Transfer, error) {
+	found, err := scanTransfer(r.db.QueryRowContext(ctx, transferQuery, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Wrapf(ErrTransferDoesNotExist, "transfer id=%d", id)
+		}
+		return nil, errors.Wrap(err, "could not scan database query result into struct")
+	}
+
+	return found, nil
+}
+
+// Reverse sends amount of the transfer back from its target to its source. Unless allowNegative is set, the reversal
+// fails with ErrInsufficientBalance when the target no longer has the funds available.
+func (r *Repository) Reverse(ctx context.Context, original *Transfer, amount money.Amount, allowNegative bool) (*Reversal, error) {
+	reversal := &Reversal{
+		TransferId: original.Id,
+		SourceId:   original.TargetId,
+		TargetId:   original.SourceId,
+		Amount:     amount,
+	}
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		// Locking the original entry serializes concurrent reversals of the same transfer
+		locked, err := scanTransfer(tx.QueryRowContext(ctx, transferQuery+" FOR UPDATE OF e", original.Id))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.Wrapf(ErrTransferDoesNotExist, "transfer id=%d", original.Id)
+			}
+			return errors.Wrapf(err, "could not lock transfer id=%d", original.Id)
+		}
+
+		remaining, err := locked.Amount.Sub(locked.Reversed)
+		if err != nil {
+			return err
+		}
+		if remaining.IsZero() {
+			return errors.Wrapf(ErrTransferAlreadyReversed, "transfer id=%d", locked.Id)
+		}
+
+		cmp, err := amount.Cmp(remaining)
+		if err != nil {
+			return errors.Wrapf(ErrCurrencyMismatch, "transfer id=%d is in %s, reversal is in %s", locked.Id, locked.Amount.Currency(), amount.Currency())
+		}
+		if cmp > 0 {
+			return errors.Wrapf(ErrReversalExceedsTransfer, "left to reverse=%s, reversal amount=%s", remaining, amount)
+		}
+
+		reversal.Id, err = transfer(ctx, tx, &Record{Id: locked.TargetId}, &Record{Id: locked.SourceId}, amount, amount, transferOptions{
+			reversalOf:    &locked.Id,
+			allowNegative: allowNegative,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not reverse amount=%s of transfer id=%d", amount, original.Id)
+	}
+
+	return reversal, nil
+}
+
+// readTransfer reads the transfer just completed within tx by its journal entry id.
+func readTransfer(ctx context.Context, tx *sql.Tx, entryId int64) (*Transfer, error) {
+	completed, err := scanTransfer(tx.QueryRowContext(ctx, transferSelect, entryId))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read transfer id=%d", entryId)
+	}
+
+	return completed, nil
+}
+
+func scanTransfer(row interface{ Scan(dest ...any) error }) (*Transfer, error) {
+	found := &Transfer{}
+	var amount, targetAmount, sourceBalance, reversed int64
+	var code, targetCode currency.Code
+	if err := row.Scan(&found.Id, &found.SourceId, &found.TargetId, &amount, &code, &targetAmount, &targetCode, &sourceBalance, &found.CreatedAt, &reversed); err != nil {
+		return nil, err
+	}
+	found.Amount, found.TargetAmount = money.New(amount, code), money.New(targetAmount, targetCode)
+	found.SourceBalance, found.Reversed = money.New(sourceBalance, code), money.New(reversed, code)
+
+	return found, nil
+}
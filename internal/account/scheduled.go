@@ -0,0 +1,79 @@
+package account
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+const (
+	RecurrenceDaily   Recurrence = "daily"
+	RecurrenceWeekly  Recurrence = "weekly"
+	RecurrenceMonthly Recurrence = "monthly"
+)
+
+const (
+	ScheduleStatusActive ScheduleStatus = "active"
+	// ScheduleStatusCompleted one-off transfers have run, whatever the outcome of their run was.
+	ScheduleStatusCompleted ScheduleStatus = "completed"
+)
+
+const (
+	RunOutcomeSucceeded RunOutcome = "succeeded"
+	RunOutcomeFailed    RunOutcome = "failed"
+)
+
+type Recurrence string
+
+func (r Recurrence) IsValid() bool {
+	return r == RecurrenceDaily || r == RecurrenceWeekly || r == RecurrenceMonthly
+}
+
+// Occurrence returns the n-th run of a recurrence starting at start, the 0-th run being start itself.
+// Monthly runs fall on the last day of shorter months, e.g. the 31st of January is followed by the 28th of February.
+func (r Recurrence) Occurrence(start time.Time, n int) time.Time {
+	switch r {
+	case RecurrenceDaily:
+		return start.AddDate(0, 0, n)
+	case RecurrenceWeekly:
+		return start.AddDate(0, 0, 7*n)
+	default:
+		year, month, day := start.Date()
+		first := time.Date(year, month+time.Month(n), 1, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		return first.AddDate(0, 0, min(day, first.AddDate(0, 1, -1).Day())-1)
+	}
+}
+
+type ScheduleStatus string
+
+type RunOutcome string
+
+// ScheduledTransfer is a transfer executed later on, once at StartAt or repeatedly starting at StartAt.
+type ScheduledTransfer struct {
+	Id       int64
+	SourceId int64
+	TargetId int64
+	Amount   money.Amount
+	// Recurrence is nil for one-off transfers.
+	Recurrence *Recurrence
+	StartAt    time.Time
+	// occurrence is the index of the run due at NextRunAt, counting StartAt as the 0-th run.
+	occurrence int
+	// NextRunAt is nil once the transfer has completed.
+	NextRunAt *time.Time
+	Status    ScheduleStatus
+	CreatedAt time.Time
+}
+
+// ScheduledRun is the outcome of a single execution of a scheduled transfer.
+type ScheduledRun struct {
+	Id                  int64
+	ScheduledTransferId int64
+	ScheduledFor        time.Time
+	Outcome             RunOutcome
+	// Error explains why the run failed, it is only set on failed runs.
+	Error *string
+	// TransferId is the journal entry of the transfer, it is only set on successful runs.
+	TransferId *int64
+	CreatedAt  time.Time
+}
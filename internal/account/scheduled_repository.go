@@ -0,0 +1,219 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/database"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+var ErrScheduledTransferDoesNotExist = errors.New("scheduled transfer not found")
+var ErrLeaseLost = errors.New("scheduled transfer is no longer leased by this scheduler")
+
+const scheduledColumns = "id, source_id, target_id, amount, currency, recurrence, start_at, occurrence, next_run_at, status, created_at"
+
+func (r *Repository) Schedule(ctx context.Context, source *Record, target *Record, amount money.Amount, startAt time.Time, recurrence *Recurrence) (*ScheduledTransfer, error) {
+	if source.Currency != target.Currency {
+		return nil, errors.Wrapf(ErrCurrencyMismatch, "source account id=%d holds %s, target account id=%d holds %s", source.Id, source.Currency, target.Id, target.Currency)
+	}
+	if source.Currency != amount.Currency() {
+		return nil, errors.Wrapf(ErrCurrencyMismatch, "accounts hold %s, transfer is in %s", source.Currency, amount.Currency())
+	}
+
+	var scheduled *ScheduledTransfer
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		accounts, err := lockAccounts(ctx, tx, source.Id, target.Id)
+		if err != nil {
+			return err
+		}
+		for _, id := range []int64{source.Id, target.Id} {
+			if err := ensureActive(accounts[id]); err != nil {
+				return err
+			}
+		}
+
+		scheduled, err = scanScheduled(tx.QueryRowContext(ctx, "INSERT INTO scheduled_transfers (source_id, target_id, amount, currency, recurrence, start_at, next_run_at) VALUES ($1, $2, $3, $4, $5, $6, $6) RETURNING "+scheduledColumns,
+			source.Id, target.Id, amount, amount.Currency(), recurrence, startAt))
+		if err != nil {
+			return errors.Wrap(err, "could not insert scheduled transfer")
+		}
+
+		return addScheduledEvent(ctx, tx, EventTransferScheduled, scheduled, startAt, nil)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not schedule transfer of amount=%s, from account=%d, to account=%d", amount, source.Id, target.Id)
+	}
+
+	return scheduled, nil
+}
+
+func (r *Repository) FindScheduledTransfer(ctx context.Context, id int64) (*ScheduledTransfer, error) {
+	scheduled, err := scanScheduled(r.db.QueryRowContext(ctx, "SELECT "+scheduledColumns+" FROM scheduled_transfers WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Wrapf(ErrScheduledTransferDoesNotExist, "scheduled transfer id=%d", id)
+		}
+		return nil, errors.Wrap(err, "could not scan database query result into struct")
+	}
+
+	return scheduled, nil
+}
+
+// ScheduledRuns returns the runs of a scheduled transfer, most recent first.
+func (r *Repository) ScheduledRuns(ctx context.Context, scheduled *ScheduledTransfer) ([]*ScheduledRun, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, scheduled_transfer_id, scheduled_for, outcome, error, journal_entry_id, created_at FROM scheduled_transfer_runs WHERE scheduled_transfer_id = $1 ORDER BY id DESC", scheduled.Id)
+	if err != nil {
+		return nil, errors.Wrap(err, "database query failed")
+	}
+	defer rows.Close()
+
+	runs := make([]*ScheduledRun, 0)
+	for rows.Next() {
+		run := &ScheduledRun{}
+		if err := rows.Scan(&run.Id, &run.ScheduledTransferId, &run.ScheduledFor, &run.Outcome, &run.Error, &run.TransferId, &run.CreatedAt); err != nil {
+			return nil, errors.Wrap(err, "could not scan database query result into struct")
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over database query result")
+	}
+
+	return runs, nil
+}
+
+// ClaimScheduledTransfers leases up to limit due transfers to owner for leaseTtl. Transfers leased by another
+// scheduler are skipped until their lease expires, so that several instances can poll side by side.
+func (r *Repository) ClaimScheduledTransfers(ctx context.Context, owner string, limit int, leaseTtl time.Duration) ([]*ScheduledTransfer, error) {
+	rows, err := r.db.QueryContext(ctx, `UPDATE scheduled_transfers SET lease_owner = $1, lease_expires_at = now() + $2 * INTERVAL '1 millisecond'
+WHERE id IN (
+    SELECT id FROM scheduled_transfers
+    WHERE status = 'active' AND next_run_at <= now() AND (lease_expires_at IS NULL OR lease_expires_at <= now())
+    ORDER BY next_run_at
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING `+scheduledColumns, owner, leaseTtl.Milliseconds(), limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not claim due scheduled transfers")
+	}
+	defer rows.Close()
+
+	claimed := make([]*ScheduledTransfer, 0, limit)
+	for rows.Next() {
+		scheduled, err := scanScheduled(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan database query result into struct")
+		}
+		claimed = append(claimed, scheduled)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over database query result")
+	}
+
+	return claimed, nil
+}
+
+// RunScheduledTransfer executes the due run of a transfer claimed by owner. The transfer, the record of its outcome
+// and the move to the next run are committed together, so a run is never executed twice even if the lease expires
+// in the meantime. A run that fails for business reasons, e.g. for lack of funds, is recorded and not retried.
+func (r *Repository) RunScheduledTransfer(ctx context.Context, scheduled *ScheduledTransfer, owner string) (*ScheduledRun, error) {
+	var run *ScheduledRun
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		locked, err := scanScheduled(tx.QueryRowContext(ctx, "SELECT "+scheduledColumns+" FROM scheduled_transfers WHERE id = $1 AND status = 'active' AND lease_owner = $2 FOR UPDATE", scheduled.Id, owner))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.Wrapf(ErrLeaseLost, "scheduled transfer id=%d", scheduled.Id)
+			}
+			return errors.Wrapf(err, "could not lock scheduled transfer id=%d", scheduled.Id)
+		}
+		if scheduled.NextRunAt == nil || !locked.NextRunAt.Equal(*scheduled.NextRunAt) {
+			return errors.Wrapf(ErrLeaseLost, "scheduled transfer id=%d already ran", scheduled.Id)
+		}
+
+		run = &ScheduledRun{
+			ScheduledTransferId: locked.Id,
+			ScheduledFor:        *locked.NextRunAt,
+			Outcome:             RunOutcomeSucceeded,
+		}
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT scheduled_run"); err != nil {
+			return errors.Wrap(err, "could not create savepoint")
+		}
+		entryId, err := transfer(ctx, tx, &Record{Id: locked.SourceId}, &Record{Id: locked.TargetId}, locked.Amount, locked.Amount, transferOptions{limited: true})
+		switch {
+		case err == nil:
+			run.TransferId = &entryId
+		case isRejection(err):
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT scheduled_run"); err != nil {
+				return errors.Wrap(err, "could not roll back to savepoint")
+			}
+			reason := err.Error()
+			run.Outcome, run.Error = RunOutcomeFailed, &reason
+
+			// Rolling back to the savepoint released the account locks taken by the transfer, the event needs them back
+			if _, err := lockAccounts(ctx, tx, locked.SourceId, locked.TargetId); err != nil {
+				return err
+			}
+			if err := addScheduledEvent(ctx, tx, EventScheduledTransferFailed, locked, run.ScheduledFor, run.Error); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		res := tx.QueryRowContext(ctx, "INSERT INTO scheduled_transfer_runs (scheduled_transfer_id, scheduled_for, outcome, error, journal_entry_id) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at",
+			run.ScheduledTransferId, run.ScheduledFor, run.Outcome, run.Error, run.TransferId)
+		if err := res.Scan(&run.Id, &run.CreatedAt); err != nil {
+			return errors.Wrapf(err, "could not record run of scheduled transfer id=%d", locked.Id)
+		}
+
+		return advanceScheduled(ctx, tx, locked)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not run scheduled transfer id=%d", scheduled.Id)
+	}
+
+	return run, nil
+}
+
+// advanceScheduled moves a recurring transfer to its next run in the future, runs missed while no scheduler was
+// running are skipped rather than executed in a burst. One-off transfers are completed instead.
+func advanceScheduled(ctx context.Context, tx *sql.Tx, scheduled *ScheduledTransfer) error {
+	if scheduled.Recurrence == nil {
+		if _, err := tx.ExecContext(ctx, "UPDATE scheduled_transfers SET status = $1, next_run_at = NULL, lease_owner = NULL, lease_expires_at = NULL WHERE id = $2", ScheduleStatusCompleted, scheduled.Id); err != nil {
+			return errors.Wrapf(err, "could not complete scheduled transfer id=%d", scheduled.Id)
+		}
+		return nil
+	}
+
+	now := time.Now()
+	occurrence := scheduled.occurrence + 1
+	next := scheduled.Recurrence.Occurrence(scheduled.StartAt, occurrence)
+	for !next.After(now) {
+		occurrence++
+		next = scheduled.Recurrence.Occurrence(scheduled.StartAt, occurrence)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE scheduled_transfers SET occurrence = $1, next_run_at = $2, lease_owner = NULL, lease_expires_at = NULL WHERE id = $3", occurrence, next, scheduled.Id); err != nil {
+		return errors.Wrapf(err, "could not advance scheduled transfer id=%d", scheduled.Id)
+	}
+	return nil
+}
+
+func scanScheduled(row interface{ Scan(dest ...any) error }) (*ScheduledTransfer, error) {
+	scheduled := &ScheduledTransfer{}
+	var amount int64
+	var code currency.Code
+	if err := row.Scan(&scheduled.Id, &scheduled.SourceId, &scheduled.TargetId, &amount, &code, &scheduled.Recurrence, &scheduled.StartAt, &scheduled.occurrence, &scheduled.NextRunAt, &scheduled.Status, &scheduled.CreatedAt); err != nil {
+		return nil, err
+	}
+	scheduled.Amount = money.New(amount, code)
+
+	return scheduled, nil
+}
@@ -0,0 +1,35 @@
+package account_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+)
+
+func TestRecurrenceOccurrence(t *testing.T) {
+	type testCase struct {
+		recurrence account.Recurrence
+		start      time.Time
+		n          int
+		expected   time.Time
+	}
+
+	start := time.Date(2024, time.January, 31, 9, 30, 0, 0, time.UTC)
+	testCases := map[string]testCase{
+		"start":                        {recurrence: account.RecurrenceDaily, start: start, n: 0, expected: start},
+		"daily":                        {recurrence: account.RecurrenceDaily, start: start, n: 2, expected: time.Date(2024, time.February, 2, 9, 30, 0, 0, time.UTC)},
+		"weekly":                       {recurrence: account.RecurrenceWeekly, start: start, n: 1, expected: time.Date(2024, time.February, 7, 9, 30, 0, 0, time.UTC)},
+		"monthly into a shorter month": {recurrence: account.RecurrenceMonthly, start: start, n: 1, expected: time.Date(2024, time.February, 29, 9, 30, 0, 0, time.UTC)},
+		"monthly back to a long month": {recurrence: account.RecurrenceMonthly, start: start, n: 2, expected: time.Date(2024, time.March, 31, 9, 30, 0, 0, time.UTC)},
+		"monthly across a year":        {recurrence: account.RecurrenceMonthly, start: start, n: 13, expected: time.Date(2025, time.February, 28, 9, 30, 0, 0, time.UTC)},
+	}
+
+	for testName, test := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.recurrence.Occurrence(test.start, test.n))
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package account
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+// BalanceAsOf computes the balance of the target account at asOf from its postings, starting from the latest balance
+// snapshot taken at or before asOf so that only the postings made since have to be summed up.
+func (r *Repository) BalanceAsOf(ctx context.Context, target *Record, asOf time.Time) (money.Amount, error) {
+	res := r.db.QueryRowContext(ctx, `WITH snapshot AS (SELECT as_of, balance FROM balance_snapshots WHERE account_id = $1 AND as_of <= $2 ORDER BY as_of DESC LIMIT 1)
+SELECT COALESCE((SELECT balance FROM snapshot), 0) + COALESCE(SUM(amount), 0)::BIGINT
+FROM postings
+WHERE account_id = $1 AND created_at <= $2 AND created_at > COALESCE((SELECT as_of FROM snapshot), '-infinity')`, target.Id, asOf)
+
+	var balance int64
+	if err := res.Scan(&balance); err != nil {
+		return money.Amount{}, errors.Wrapf(err, "could not compute balance of account id=%d as of %s", target.Id, asOf.Format(time.RFC3339))
+	}
+
+	return money.New(balance, target.Currency), nil
+}
+
+// SnapshotBalances records the balance at asOf of every account which had postings since the previous snapshot, and
+// returns the number of snapshots taken. Snapshots must be taken in chronological order and only once asOf is far
+// enough in the past for every transaction which started before it to have committed, as the postings of a transaction
+// are dated by its start.
+func (r *Repository) SnapshotBalances(ctx context.Context, asOf time.Time) (int, error) {
+	// Accounts without postings since the previous snapshot keep their latest one, which is still accurate
+	res, err := r.db.ExecContext(ctx, `WITH latest AS (SELECT DISTINCT ON (account_id) account_id, balance FROM balance_snapshots WHERE as_of < $1 ORDER BY account_id, as_of DESC),
+moved AS (SELECT account_id, SUM(amount)::BIGINT AS amount FROM postings
+          WHERE account_id > 0 AND created_at <= $1 AND created_at > COALESCE((SELECT MAX(as_of) FROM balance_snapshots WHERE as_of < $1), '-infinity')
+          GROUP BY account_id)
+INSERT INTO balance_snapshots (account_id, as_of, balance)
+SELECT moved.account_id, $1, COALESCE(latest.balance, 0) + moved.amount FROM moved LEFT JOIN latest ON latest.account_id = moved.account_id
+ON CONFLICT DO NOTHING`, asOf)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not snapshot balances as of %s", asOf.Format(time.RFC3339))
+	}
+
+	snapshots, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "could not count balance snapshots")
+	}
+
+	return int(snapshots), nil
+}
@@ -0,0 +1,106 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/database"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+var ErrStatusTransition = errors.New("account status does not allow this change")
+var ErrBalanceNotZero = errors.New("account balance must be zero or swept to another account")
+var ErrActiveHolds = errors.New("account has active holds")
+
+func (r *Repository) Freeze(ctx context.Context, target *Record) (*Record, error) {
+	return r.changeStatus(ctx, target, StatusActive, StatusFrozen, EventAccountFrozen)
+}
+
+func (r *Repository) Unfreeze(ctx context.Context, target *Record) (*Record, error) {
+	return r.changeStatus(ctx, target, StatusFrozen, StatusActive, EventAccountUnfrozen)
+}
+
+func (r *Repository) Close(ctx context.Context, target *Record, sweepTo *Record) (*Record, error) {
+	var closed *Record
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		ids := []int64{target.Id}
+		if sweepTo != nil {
+			ids = append(ids, sweepTo.Id)
+		}
+		accounts, err := lockAccounts(ctx, tx, ids...)
+		if err != nil {
+			return err
+		}
+
+		closed = accounts[target.Id]
+		if closed.Status == StatusClosed {
+			return errors.Wrapf(ErrAccountClosed, "account id=%d", closed.Id)
+		}
+		if closed.Available.Minor() != closed.Balance.Minor() {
+			return errors.Wrapf(ErrActiveHolds, "account id=%d", closed.Id)
+		}
+
+		if !closed.Balance.IsZero() {
+			if sweepTo == nil || closed.Balance.IsNegative() || sweepTo.Id == closed.Id {
+				return errors.Wrapf(ErrBalanceNotZero, "account id=%d, balance=%s", closed.Id, closed.Balance)
+			}
+			if closed.Currency != sweepTo.Currency {
+				return errors.Wrapf(ErrCurrencyMismatch, "account id=%d holds %s, sweep account id=%d holds %s", closed.Id, closed.Currency, sweepTo.Id, sweepTo.Currency)
+			}
+
+			if _, err := transfer(ctx, tx, closed, sweepTo, closed.Balance, closed.Balance, transferOptions{sweep: true}); err != nil {
+				return err
+			}
+			closed.Balance, closed.Available = money.Zero(closed.Currency), money.Zero(closed.Currency)
+		}
+
+		if err := setStatus(ctx, tx, closed, StatusClosed); err != nil {
+			return err
+		}
+		return addAccountEvent(ctx, tx, EventAccountClosed, closed)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not close account with id=%d", target.Id)
+	}
+
+	return closed, nil
+}
+
+func (r *Repository) changeStatus(ctx context.Context, target *Record, from Status, to Status, eventType string) (*Record, error) {
+	var changed *Record
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		accounts, err := lockAccounts(ctx, tx, target.Id)
+		if err != nil {
+			return err
+		}
+
+		changed = accounts[target.Id]
+		if changed.Status == StatusClosed {
+			return errors.Wrapf(ErrAccountClosed, "account id=%d", changed.Id)
+		}
+		if changed.Status != from {
+			return errors.Wrapf(ErrStatusTransition, "account id=%d is %s, it cannot become %s", changed.Id, changed.Status, to)
+		}
+
+		if err := setStatus(ctx, tx, changed, to); err != nil {
+			return err
+		}
+		return addAccountEvent(ctx, tx, eventType, changed)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not change status of account with id=%d to %s", target.Id, to)
+	}
+
+	return changed, nil
+}
+
+func setStatus(ctx context.Context, tx *sql.Tx, record *Record, status Status) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE accounts SET status = $1 WHERE id = $2", status, record.Id); err != nil {
+		return errors.Wrapf(err, "could not update status of account with id=%d", record.Id)
+	}
+
+	record.Status = status
+	return nil
+}
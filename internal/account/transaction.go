@@ -0,0 +1,47 @@
+package account
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+const (
+	TransactionTypeTopUp      TransactionType = "topup"
+	TransactionTypeTransfer   TransactionType = "transfer"
+	TransactionTypeWithdrawal TransactionType = "withdrawal"
+	TransactionTypeReversal   TransactionType = "reversal"
+)
+
+const (
+	DirectionIn  Direction = "in"
+	DirectionOut Direction = "out"
+)
+
+type TransactionType string
+
+type Direction string
+
+type Transaction struct {
+	Id        int64
+	AccountId int64
+	// EntryId is the journal entry the transaction belongs to, for transfers it is the id of the transfer.
+	EntryId        int64
+	Type           TransactionType
+	Direction      Direction
+	CounterpartyId *int64
+	Amount         money.Amount
+	Balance        money.Amount
+	CreatedAt      time.Time
+}
+
+type HistoryQuery struct {
+	AccountId int64
+	// Before is a cursor, only transactions with a lower id are returned when set.
+	Before    int64
+	Limit     int
+	From      time.Time
+	To        time.Time
+	Direction Direction
+	Type      TransactionType
+}
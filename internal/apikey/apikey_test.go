@@ -0,0 +1,53 @@
+package apikey_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/apikey"
+)
+
+func TestKeyAllows(t *testing.T) {
+	type testCase struct {
+		scopes   []apikey.Scope
+		scope    apikey.Scope
+		expected bool
+	}
+
+	testCases := map[string]testCase{
+		"granted scope":     {scopes: []apikey.Scope{apikey.ScopeAccountsRead}, scope: apikey.ScopeAccountsRead, expected: true},
+		"other scope":       {scopes: []apikey.Scope{apikey.ScopeAccountsRead}, scope: apikey.ScopeTransfersWrite, expected: false},
+		"admin scope":       {scopes: []apikey.Scope{apikey.ScopeAdmin}, scope: apikey.ScopeTransfersWrite, expected: true},
+		"admin not implied": {scopes: []apikey.Scope{apikey.ScopeAccountsRead, apikey.ScopeAccountsCreate, apikey.ScopeTransfersWrite}, scope: apikey.ScopeAdmin, expected: false},
+	}
+
+	for testName, test := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			key := &apikey.Key{Scopes: test.scopes}
+			assert.Equal(t, test.expected, key.Allows(test.scope))
+		})
+	}
+}
+
+func TestKeyCoversAccount(t *testing.T) {
+	type testCase struct {
+		accountIds []int64
+		id         int64
+		expected   bool
+	}
+
+	testCases := map[string]testCase{
+		"unrestricted key":   {accountIds: nil, id: 7, expected: true},
+		"restricted key":     {accountIds: []int64{3, 7}, id: 7, expected: true},
+		"account not listed": {accountIds: []int64{3}, id: 7, expected: false},
+	}
+
+	for testName, test := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			key := &apikey.Key{AccountIds: test.accountIds}
+			assert.Equal(t, test.expected, key.CoversAccount(test.id))
+			assert.Equal(t, len(test.accountIds) > 0, key.IsBound())
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// secretPrefix marks the API keys of this service, so that they are recognized when leaked e.g. by secret scanners.
+const secretPrefix = "suk_"
+
+// displayedLength is the length of the prefix of a key stored in clear.
+const displayedLength = len(secretPrefix) + 8
+
+var ErrDoesNotExist = errors.New("api key not found")
+var ErrInvalid = errors.New("api key is not valid")
+var ErrRevoked = errors.New("api key has been revoked")
+var ErrAlreadyRevoked = errors.New("api key is already revoked")
+var ErrNoScopes = errors.New("api key must be granted at least one scope")
+var ErrUnknownScope = errors.New("unknown api key scope")
+
+const keyColumns = "id, name, prefix, scopes, account_ids, created_at, revoked_at"
+
+func NewRepository(db *sql.DB) (*Repository, error) {
+	if db == nil {
+		return nil, errors.New("db cannot be nil")
+	}
+	return &Repository{
+		db: db,
+	}, nil
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func (r *Repository) Issue(ctx context.Context, name string, scopes []Scope, accountIds []int64) (*Key, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", ErrNoScopes
+	}
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return nil, "", errors.Wrapf(ErrUnknownScope, "scope=%q", scope)
+		}
+	}
+
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, string(scope))
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+	accountIds = slices.Clone(accountIds)
+	slices.Sort(accountIds)
+	accountIds = slices.Compact(accountIds)
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", errors.Wrap(err, "could not generate api key")
+	}
+	secret := secretPrefix + hex.EncodeToString(random)
+
+	key, err := scanKey(r.db.QueryRowContext(ctx, "INSERT INTO api_keys (name, prefix, key_hash, scopes, account_ids) VALUES ($1, $2, $3, $4, $5) RETURNING "+keyColumns,
+		name, secret[:displayedLength], hash(secret), pq.Array(names), pq.Array(nonNil(accountIds))))
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "could not issue api key name=%q", name)
+	}
+
+	return key, secret, nil
+}
+
+func (r *Repository) Authenticate(ctx context.Context, secret string) (*Key, error) {
+	if !strings.HasPrefix(secret, secretPrefix) {
+		return nil, ErrInvalid
+	}
+
+	key, err := scanKey(r.db.QueryRowContext(ctx, "SELECT "+keyColumns+" FROM api_keys WHERE key_hash = $1", hash(secret)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalid
+		}
+		return nil, errors.Wrap(err, "database query failed")
+	}
+
+	if key.RevokedAt != nil {
+		return nil, errors.Wrapf(ErrRevoked, "api key id=%d", key.Id)
+	}
+
+	return key, nil
+}
+
+func (r *Repository) Revoke(ctx context.Context, id int64) (*Key, error) {
+	key, err := scanKey(r.db.QueryRowContext(ctx, "UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL RETURNING "+keyColumns, id))
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrapf(err, "could not revoke api key id=%d", id)
+	}
+
+	// Nothing was updated, either there is no such key or it was revoked before
+	var revoked bool
+	if err := r.db.QueryRowContext(ctx, "SELECT revoked_at IS NOT NULL FROM api_keys WHERE id = $1", id).Scan(&revoked); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Wrapf(ErrDoesNotExist, "api key id=%d", id)
+		}
+		return nil, errors.Wrap(err, "database query failed")
+	}
+
+	return nil, errors.Wrapf(ErrAlreadyRevoked, "api key id=%d", id)
+}
+
+// hash is the SHA-256 of a key, keys are random enough for a plain hash to be safe.
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// nonNil stores a key restricted to no account with an empty array rather than NULL.
+func nonNil(accountIds []int64) []int64 {
+	if accountIds == nil {
+		return []int64{}
+	}
+	return accountIds
+}
+
+func scanKey(row interface{ Scan(dest ...any) error }) (*Key, error) {
+	key := &Key{}
+	var scopes []string
+	if err := row.Scan(&key.Id, &key.Name, &key.Prefix, pq.Array(&scopes), pq.Array(&key.AccountIds), &key.CreatedAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+
+	for _, scope := range scopes {
+		key.Scopes = append(key.Scopes, Scope(scope))
+	}
+
+	return key, nil
+}
@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/database"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+// AnonymousActor is recorded for requests made by no one in particular.
+const AnonymousActor = "anonymous"
+
+// Entry records a state-changing request, entries are chained by their hashes so that any change to a past entry,
+// or its removal, breaks the chain.
+type Entry struct {
+	Id        int64
+	Actor     string
+	RequestId string
+	Method    string
+	// Endpoint is the route the request matched, e.g. /account/{id}/transfer, while Path is the actual path requested.
+	Endpoint   string
+	Path       string
+	StatusCode int
+	// Balances are the balances of the accounts the request touched, before and after it.
+	Balances  []Balance
+	CreatedAt time.Time
+	// PrevHash is the hash of the previous entry, it is empty for the first entry.
+	PrevHash string
+	Hash     string
+}
+
+type Balance struct {
+	AccountId int64
+	Before    money.Amount
+	After     money.Amount
+}
+
+type Recorder interface {
+	Append(ctx context.Context, entry *Entry) error
+	// AppendTx appends the entry within tx, it is only appended if tx is committed.
+	AppendTx(ctx context.Context, tx *sql.Tx, entry *Entry) error
+}
+
+type actorKey struct{}
+
+type trailKey struct{}
+
+// WithActor tells who makes the request ctx belongs to.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set by WithActor, or AnonymousActor if none was set.
+func ActorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return AnonymousActor
+}
+
+// Trail collects the accounts a request touches along with their balances before and after it. The entry of the
+// request is appended within the transaction changing the balances, so that the changes are never committed without
+// it, or it without them.
+type Trail struct {
+	mu       sync.Mutex
+	recorder Recorder
+	entry    Entry
+	accounts []int64
+	before   map[int64]money.Amount
+	after    map[int64]money.Amount
+	recorded bool
+	pending  bool
+}
+
+// WithTrail starts the trail of the request ctx belongs to, entry is what is recorded about the request besides the
+// balances. Its status code is the one the request answers with once its changes are committed, http.StatusOK
+// unless changed by SetStatus.
+func WithTrail(ctx context.Context, recorder Recorder, entry Entry) (context.Context, *Trail) {
+	if entry.StatusCode == 0 {
+		entry.StatusCode = http.StatusOK
+	}
+	trail := &Trail{recorder: recorder, entry: entry}
+	trail.reset()
+	ctx = context.WithValue(ctx, trailKey{}, trail)
+	return database.WithHook(ctx, trail), trail
+}
+
+// SetStatus sets the status code the request ctx belongs to answers with once its changes are committed. It does
+// nothing unless ctx carries a Trail.
+func SetStatus(ctx context.Context, statusCode int) {
+	trail, ok := ctx.Value(trailKey{}).(*Trail)
+	if !ok {
+		return
+	}
+
+	trail.mu.Lock()
+	defer trail.mu.Unlock()
+
+	trail.entry.StatusCode = statusCode
+}
+
+// Observe notes the balance of an account as the request touches or changes it, the first balance observed for an
+// account within a transaction is its balance before and the last one its balance after. It does nothing unless ctx
+// carries a Trail.
+func Observe(ctx context.Context, accountId int64, balance money.Amount) {
+	trail, ok := ctx.Value(trailKey{}).(*Trail)
+	if !ok {
+		return
+	}
+
+	trail.mu.Lock()
+	defer trail.mu.Unlock()
+
+	if _, seen := trail.before[accountId]; !seen {
+		trail.accounts = append(trail.accounts, accountId)
+		trail.before[accountId] = balance
+	}
+	trail.after[accountId] = balance
+}
+
+// Begin forgets what the previous attempt of a transaction observed, its changes were rolled back.
+func (t *Trail) Begin() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reset()
+}
+
+// BeforeCommit appends the entry of the request within tx if the transaction touched any account.
+func (t *Trail) BeforeCommit(ctx context.Context, tx *sql.Tx) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.accounts) == 0 {
+		return nil
+	}
+
+	entry := t.entry
+	for _, id := range t.accounts {
+		entry.Balances = append(entry.Balances, Balance{AccountId: id, Before: t.before[id], After: t.after[id]})
+	}
+	if err := t.recorder.AppendTx(ctx, tx, &entry); err != nil {
+		return errors.Wrapf(err, "could not audit %s %s of request id=%s", entry.Method, entry.Path, entry.RequestId)
+	}
+	t.pending = true
+
+	return nil
+}
+
+// AfterCommit notes the entry appended before the commit as recorded.
+func (t *Trail) AfterCommit() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.recorded = t.recorded || t.pending
+	t.reset()
+}
+
+// Recorded tells whether the entry of the request was committed along with its changes.
+func (t *Trail) Recorded() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.recorded
+}
+
+// StatusCode returns the status code recorded for the request once its changes are committed.
+func (t *Trail) StatusCode() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.entry.StatusCode
+}
+
+// Uncommitted returns the entry of a request which committed no changes, the accounts it touched are recorded with
+// their balances unchanged as whatever it changed was rolled back.
+func (t *Trail) Uncommitted(statusCode int) *Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry := t.entry
+	entry.StatusCode = statusCode
+	for _, id := range t.accounts {
+		entry.Balances = append(entry.Balances, Balance{AccountId: id, Before: t.before[id], After: t.before[id]})
+	}
+	return &entry
+}
+
+func (t *Trail) reset() {
+	t.accounts = nil
+	t.before = make(map[int64]money.Amount)
+	t.after = make(map[int64]money.Amount)
+	t.pending = false
+}
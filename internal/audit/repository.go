@@ -0,0 +1,220 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/database"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+const entryColumns = "id, actor, request_id, method, endpoint, path, status_code, balances, created_at, prev_hash, hash"
+
+func NewRepository(db *sql.DB) (*Repository, error) {
+	if db == nil {
+		return nil, errors.New("db cannot be nil")
+	}
+	return &Repository{
+		db: db,
+	}, nil
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+// Append adds the entry at the end of the chain, it sets the id, time and hashes of the entry.
+func (r *Repository) Append(ctx context.Context, entry *Entry) error {
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		return r.AppendTx(ctx, tx, entry)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "could not audit %s %s of request id=%s", entry.Method, entry.Path, entry.RequestId)
+	}
+
+	return nil
+}
+
+// AppendTx adds the entry at the end of the chain within tx, it sets the id, time and hashes of the entry. The chain
+// is locked until tx ends, so tx should end promptly.
+func (r *Repository) AppendTx(ctx context.Context, tx *sql.Tx, entry *Entry) error {
+	// Entries are appended one at a time, otherwise two of them could be chained to the same previous entry
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('audit_log'))"); err != nil {
+		return errors.Wrap(err, "could not acquire audit log lock")
+	}
+
+	entry.PrevHash = ""
+	if err := tx.QueryRowContext(ctx, "SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1").Scan(&entry.PrevHash); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return errors.Wrap(err, "could not read the last audit log entry")
+	}
+
+	// The time is truncated to what the database stores, so that the hash can be computed again from the stored entry
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	content, err := encode(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = chainHash(entry.PrevHash, content)
+
+	balances, err := json.Marshal(storedBalances(entry.Balances))
+	if err != nil {
+		return errors.Wrap(err, "could not encode balances")
+	}
+
+	res := tx.QueryRowContext(ctx, "INSERT INTO audit_log (actor, request_id, method, endpoint, path, status_code, balances, created_at, prev_hash, hash) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id",
+		entry.Actor, entry.RequestId, entry.Method, entry.Endpoint, entry.Path, entry.StatusCode, balances, entry.CreatedAt, entry.PrevHash, entry.Hash)
+	if err := res.Scan(&entry.Id); err != nil {
+		return errors.Wrap(err, "could not insert audit log entry")
+	}
+
+	return nil
+}
+
+// Problem is a break in the chain found by Verify.
+type Problem struct {
+	EntryId int64
+	Reason  string
+}
+
+type Report struct {
+	Checked  int
+	Problems []Problem
+}
+
+func (r *Report) Intact() bool {
+	return len(r.Problems) == 0
+}
+
+// Verify walks the whole chain and reports every entry which has been changed or which does not follow the entry
+// before it, as happens when entries are removed or inserted. Removing the most recent entries cannot be told
+// apart from them never having been written, the last hash has to be kept elsewhere to detect that.
+func (r *Repository) Verify(ctx context.Context) (*Report, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+entryColumns+" FROM audit_log ORDER BY id")
+	if err != nil {
+		return nil, errors.Wrap(err, "database query failed")
+	}
+	defer rows.Close()
+
+	report := &Report{}
+	prevHash := ""
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan database query result into struct")
+		}
+		report.Checked++
+
+		if entry.PrevHash != prevHash {
+			report.Problems = append(report.Problems, Problem{
+				EntryId: entry.Id,
+				Reason:  fmt.Sprintf("previous hash %q does not match the hash %q of the entry before it", entry.PrevHash, prevHash),
+			})
+		}
+
+		content, err := encode(entry)
+		if err != nil {
+			return nil, err
+		}
+		if hash := chainHash(entry.PrevHash, content); hash != entry.Hash {
+			report.Problems = append(report.Problems, Problem{
+				EntryId: entry.Id,
+				Reason:  fmt.Sprintf("content hashes to %q instead of the recorded %q", hash, entry.Hash),
+			})
+		}
+
+		prevHash = entry.Hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over database query result")
+	}
+
+	return report, nil
+}
+
+// storedBalance is how a balance is stored and hashed, amounts are in minor units of the currency.
+type storedBalance struct {
+	AccountId int64         `json:"account_id"`
+	Currency  currency.Code `json:"currency"`
+	Before    int64         `json:"before"`
+	After     int64         `json:"after"`
+}
+
+func storedBalances(balances []Balance) []storedBalance {
+	stored := make([]storedBalance, 0, len(balances))
+	for _, balance := range balances {
+		stored = append(stored, storedBalance{
+			AccountId: balance.AccountId,
+			Currency:  balance.Before.Currency(),
+			Before:    balance.Before.Minor(),
+			After:     balance.After.Minor(),
+		})
+	}
+	return stored
+}
+
+// encode serializes what the hash of an entry covers, the encoding must never change as it would break the chain.
+func encode(entry *Entry) ([]byte, error) {
+	content, err := json.Marshal(struct {
+		Actor      string          `json:"actor"`
+		RequestId  string          `json:"request_id"`
+		Method     string          `json:"method"`
+		Endpoint   string          `json:"endpoint"`
+		Path       string          `json:"path"`
+		StatusCode int             `json:"status_code"`
+		Balances   []storedBalance `json:"balances"`
+		CreatedAt  string          `json:"created_at"`
+	}{
+		Actor:      entry.Actor,
+		RequestId:  entry.RequestId,
+		Method:     entry.Method,
+		Endpoint:   entry.Endpoint,
+		Path:       entry.Path,
+		StatusCode: entry.StatusCode,
+		Balances:   storedBalances(entry.Balances),
+		CreatedAt:  entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode audit log entry")
+	}
+
+	return content, nil
+}
+
+func chainHash(prevHash string, content []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(prevHash))
+	hash.Write([]byte("\n"))
+	hash.Write(content)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func scanEntry(row interface{ Scan(dest ...any) error }) (*Entry, error) {
+	entry := &Entry{}
+	var balances []byte
+	if err := row.Scan(&entry.Id, &entry.Actor, &entry.RequestId, &entry.Method, &entry.Endpoint, &entry.Path, &entry.StatusCode, &balances, &entry.CreatedAt, &entry.PrevHash, &entry.Hash); err != nil {
+		return nil, err
+	}
+
+	var stored []storedBalance
+	if err := json.Unmarshal(balances, &stored); err != nil {
+		return nil, errors.Wrapf(err, "could not decode balances of audit log entry id=%d", entry.Id)
+	}
+	for _, balance := range stored {
+		entry.Balances = append(entry.Balances, Balance{
+			AccountId: balance.AccountId,
+			Before:    money.New(balance.Before, balance.Currency),
+			After:     money.New(balance.After, balance.Currency),
+		})
+	}
+
+	return entry, nil
+}
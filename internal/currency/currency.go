@@ -0,0 +1,36 @@
+package currency
+
+import (
+	"github.com/pkg/errors"
+)
+
+const Default Code = "EUR"
+
+var ErrUnsupported = errors.New("unsupported currency")
+
+// Code is an ISO 4217 alphabetic currency code.
+type Code string
+
+func Parse(code string) (Code, error) {
+	if _, ok := exponents[Code(code)]; !ok {
+		return "", errors.Wrapf(ErrUnsupported, "code=%q", code)
+	}
+
+	return Code(code), nil
+}
+
+// Exponent is the number of digits after the decimal separator, i.e. an amount of 1234 minor units of a currency
+// with exponent 2 is 12.34 major units.
+func (c Code) Exponent() (int, error) {
+	exponent, ok := exponents[c]
+	if !ok {
+		return 0, errors.Wrapf(ErrUnsupported, "code=%q", c)
+	}
+
+	return exponent, nil
+}
+
+func (c Code) IsValid() bool {
+	_, ok := exponents[c]
+	return ok
+}
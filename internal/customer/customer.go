@@ -0,0 +1,61 @@
+package customer
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+const (
+	// KycTierNone customers have not been identified yet.
+	KycTierNone  KycTier = "none"
+	KycTierBasic KycTier = "basic"
+	KycTierFull  KycTier = "full"
+)
+
+// KycTiers lists every KYC tier, from the least to the most verified.
+var KycTiers = []KycTier{KycTierNone, KycTierBasic, KycTierFull}
+
+// KycTier tells how thoroughly the identity of a customer has been verified.
+type KycTier string
+
+func (t KycTier) IsValid() bool {
+	return slices.Contains(KycTiers, t)
+}
+
+type Customer struct {
+	Id    int64
+	Name  string
+	Email string
+	// ExternalRef identifies the customer in the systems of the client, it is unique when set.
+	ExternalRef string
+	KycTier     KycTier
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Details are the fields of a customer set by clients, KycTier defaults to KycTierNone when empty.
+type Details struct {
+	Name        string
+	Email       string
+	ExternalRef string
+	KycTier     KycTier
+}
+
+type Creator interface {
+	Create(ctx context.Context, details *Details) (*Customer, error)
+}
+
+type Finder interface {
+	FindById(ctx context.Context, id int64) (*Customer, error)
+}
+
+type Updater interface {
+	// Update replaces the details of the customer.
+	Update(ctx context.Context, id int64, details *Details) (*Customer, error)
+}
+
+type Deleter interface {
+	// Delete deletes a customer whose accounts are all closed, the closed accounts are unlinked from it.
+	Delete(ctx context.Context, id int64) error
+}
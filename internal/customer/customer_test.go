@@ -0,0 +1,17 @@
+package customer_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/customer"
+)
+
+func TestKycTierIsValid(t *testing.T) {
+	for _, tier := range customer.KycTiers {
+		assert.True(t, tier.IsValid(), tier)
+	}
+	assert.False(t, customer.KycTier("").IsValid())
+	assert.False(t, customer.KycTier("FULL").IsValid())
+}
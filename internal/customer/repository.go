@@ -0,0 +1,160 @@
+package customer
+
+import (
+	"context"
+	"database/sql"
+	"net/mail"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/database"
+)
+
+// maxNameLength is the length in characters names and external references are limited to.
+const maxNameLength = 200
+
+var ErrDoesNotExist = errors.New("customer not found")
+var ErrInvalidName = errors.New("customer name must be 1 to 200 characters")
+var ErrInvalidEmail = errors.New("customer email must be a valid email address")
+var ErrInvalidExternalRef = errors.New("customer external reference must be at most 200 characters")
+var ErrUnknownKycTier = errors.New("unknown kyc tier")
+var ErrExternalRefTaken = errors.New("customer external reference is already taken")
+var ErrHasOpenAccounts = errors.New("customer has accounts which are not closed")
+
+const customerColumns = "id, name, email, COALESCE(external_ref, ''), kyc_tier, created_at, updated_at"
+
+// uniqueViolation is the SQLSTATE of a violated unique constraint.
+const uniqueViolation pq.ErrorCode = "23505"
+
+func NewRepository(db *sql.DB) (*Repository, error) {
+	if db == nil {
+		return nil, errors.New("db cannot be nil")
+	}
+	return &Repository{
+		db: db,
+	}, nil
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func (r *Repository) Create(ctx context.Context, details *Details) (*Customer, error) {
+	details, err := normalize(details)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := scanCustomer(r.db.QueryRowContext(ctx, "INSERT INTO customers (name, email, external_ref, kyc_tier) VALUES ($1, $2, NULLIF($3, ''), $4) RETURNING "+customerColumns,
+		details.Name, details.Email, details.ExternalRef, details.KycTier))
+	if err != nil {
+		return nil, errors.Wrapf(uniqueError(err, details), "could not create customer name=%q", details.Name)
+	}
+
+	return created, nil
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (*Customer, error) {
+	found, err := scanCustomer(r.db.QueryRowContext(ctx, "SELECT "+customerColumns+" FROM customers WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Wrapf(ErrDoesNotExist, "customer id=%d", id)
+		}
+		return nil, errors.Wrap(err, "database query failed")
+	}
+
+	return found, nil
+}
+
+func (r *Repository) Update(ctx context.Context, id int64, details *Details) (*Customer, error) {
+	details, err := normalize(details)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := scanCustomer(r.db.QueryRowContext(ctx, "UPDATE customers SET name = $1, email = $2, external_ref = NULLIF($3, ''), kyc_tier = $4, updated_at = now() WHERE id = $5 RETURNING "+customerColumns,
+		details.Name, details.Email, details.ExternalRef, details.KycTier, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Wrapf(ErrDoesNotExist, "customer id=%d", id)
+		}
+		return nil, errors.Wrapf(uniqueError(err, details), "could not update customer id=%d", id)
+	}
+
+	return updated, nil
+}
+
+func (r *Repository) Delete(ctx context.Context, id int64) error {
+	return database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		// Accounts are linked under a key share lock of the customer, which the update lock waits for
+		var found bool
+		if err := tx.QueryRowContext(ctx, "SELECT TRUE FROM customers WHERE id = $1 FOR UPDATE", id).Scan(&found); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errors.Wrapf(ErrDoesNotExist, "customer id=%d", id)
+			}
+			return errors.Wrapf(err, "could not lock customer with id=%d", id)
+		}
+
+		var open int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM accounts WHERE customer_id = $1 AND status <> 'closed'", id).Scan(&open); err != nil {
+			return errors.Wrap(err, "could not count open accounts")
+		}
+		if open > 0 {
+			return errors.Wrapf(ErrHasOpenAccounts, "customer id=%d has %d open accounts", id, open)
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM customers WHERE id = $1", id); err != nil {
+			return errors.Wrapf(err, "could not delete customer id=%d", id)
+		}
+
+		return nil
+	})
+}
+
+// normalize validates the details and returns them trimmed, with the default KYC tier if none is set.
+func normalize(details *Details) (*Details, error) {
+	normalized := &Details{
+		Name:        strings.TrimSpace(details.Name),
+		Email:       strings.TrimSpace(details.Email),
+		ExternalRef: strings.TrimSpace(details.ExternalRef),
+		KycTier:     details.KycTier,
+	}
+
+	if normalized.Name == "" || len([]rune(normalized.Name)) > maxNameLength {
+		return nil, errors.Wrapf(ErrInvalidName, "name=%q", normalized.Name)
+	}
+	// Display names such as "Alice <alice@example.com>" are not addresses
+	if address, err := mail.ParseAddress(normalized.Email); err != nil || address.Address != normalized.Email {
+		return nil, errors.Wrapf(ErrInvalidEmail, "email=%q", normalized.Email)
+	}
+	if len([]rune(normalized.ExternalRef)) > maxNameLength {
+		return nil, ErrInvalidExternalRef
+	}
+	if normalized.KycTier == "" {
+		normalized.KycTier = KycTierNone
+	}
+	if !normalized.KycTier.IsValid() {
+		return nil, errors.Wrapf(ErrUnknownKycTier, "kyc tier=%q", normalized.KycTier)
+	}
+
+	return normalized, nil
+}
+
+// uniqueError tells a taken external reference apart from any other failure.
+func uniqueError(err error, details *Details) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return errors.Wrapf(ErrExternalRefTaken, "external reference=%q", details.ExternalRef)
+	}
+	return err
+}
+
+func scanCustomer(row interface{ Scan(dest ...any) error }) (*Customer, error) {
+	customer := &Customer{}
+	if err := row.Scan(&customer.Id, &customer.Name, &customer.Email, &customer.ExternalRef, &customer.KycTier, &customer.CreatedAt, &customer.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
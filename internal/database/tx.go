@@ -0,0 +1,95 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const maxTxAttempts = 5
+const txRetryBackoff = 10 * time.Millisecond
+
+var retryableErrorCodes = map[pq.ErrorCode]struct{}{
+	"40001": {}, // serialization_failure
+	"40P01": {}, // deadlock_detected
+}
+
+// Hook takes part in the transactions run by InTx with a context carrying it, see WithHook.
+type Hook interface {
+	// Begin is called as every attempt of a transaction starts, before fn runs.
+	Begin()
+	// BeforeCommit is called within the transaction once fn succeeded, an error rolls the transaction back.
+	BeforeCommit(ctx context.Context, tx *sql.Tx) error
+	// AfterCommit is called once the transaction has been committed.
+	AfterCommit()
+}
+
+type hookKey struct{}
+
+// WithHook makes hook take part in every transaction InTx runs with ctx.
+func WithHook(ctx context.Context, hook Hook) context.Context {
+	return context.WithValue(ctx, hookKey{}, hook)
+}
+
+func InTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTxAttempts; attempt++ {
+		if err = runTx(ctx, db, fn); err == nil || !IsRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "transaction retry aborted")
+		case <-time.After(time.Duration(attempt) * txRetryBackoff):
+		}
+	}
+
+	return errors.Wrapf(err, "transaction failed after %d attempts", maxTxAttempts)
+}
+
+func IsRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	_, ok := retryableErrorCodes[pqErr.Code]
+	return ok
+}
+
+func runTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "could not start transaction")
+	}
+	defer tx.Rollback()
+
+	hook, _ := ctx.Value(hookKey{}).(Hook)
+	if hook != nil {
+		hook.Begin()
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if hook != nil {
+		if err := hook.BeforeCommit(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "could not commit transaction")
+	}
+
+	if hook != nil {
+		hook.AfterCommit()
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package fx
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+)
+
+// Rates are served rounded to this many decimal places.
+const ratePrecision = 10
+
+// NewConfigRateProvider serves fixed rates keyed by currency pair, e.g. "EUR/USD": "1.0842".
+// Pairs are usable in both directions, the reverse direction uses the inverted rate.
+func NewConfigRateProvider(rates map[string]string) (*ConfigRateProvider, error) {
+	provider := &ConfigRateProvider{
+		rates: make(map[pair]*big.Rat, len(rates)),
+	}
+
+	for key, value := range rates {
+		codes := strings.Split(strings.ToUpper(key), "/")
+		if len(codes) != 2 {
+			return nil, errors.Errorf("invalid currency pair %q, expected format SOURCE/TARGET", key)
+		}
+
+		source, err := currency.Parse(codes[0])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid currency pair %q", key)
+		}
+		target, err := currency.Parse(codes[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid currency pair %q", key)
+		}
+		if source == target {
+			return nil, errors.Wrapf(ErrSameCurrency, "invalid currency pair %q", key)
+		}
+
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, errors.Errorf("invalid rate %q of currency pair %q", value, key)
+		}
+
+		provider.rates[pair{source: source, target: target}] = rate
+	}
+
+	return provider, nil
+}
+
+type ConfigRateProvider struct {
+	rates map[pair]*big.Rat
+}
+
+type pair struct {
+	source currency.Code
+	target currency.Code
+}
+
+func (p *ConfigRateProvider) Rate(_ context.Context, source currency.Code, target currency.Code) (string, error) {
+	if rate, ok := p.rates[pair{source: source, target: target}]; ok {
+		return formatRate(rate), nil
+	}
+
+	if rate, ok := p.rates[pair{source: target, target: source}]; ok {
+		return formatRate(new(big.Rat).Inv(rate)), nil
+	}
+
+	return "", errors.Wrapf(ErrRateNotAvailable, "%s/%s", source, target)
+}
+
+func formatRate(rate *big.Rat) string {
+	formatted := rate.FloatString(ratePrecision)
+	return strings.TrimRight(strings.TrimRight(formatted, "0"), ".")
+}
@@ -0,0 +1,75 @@
+package fx
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+var ErrRateNotAvailable = errors.New("exchange rate not available")
+var ErrSameCurrency = errors.New("source and target currency must differ")
+var ErrQuoteNotFound = errors.New("quote not found")
+var ErrQuoteExpired = errors.New("quote expired")
+var ErrQuoteUsed = errors.New("quote already used")
+var ErrQuoteCurrencyMismatch = errors.New("quote was issued for a different currency pair")
+var ErrConvertedAmountTooSmall = errors.New("converted amount is less than one minor unit")
+var ErrConvertedAmountTooLarge = errors.New("converted amount is too large")
+
+type RateProvider interface {
+	// Rate returns how many major units of the target currency one major unit of the source currency buys, as a decimal string.
+	Rate(ctx context.Context, source currency.Code, target currency.Code) (string, error)
+}
+
+type Quoter interface {
+	Quote(ctx context.Context, source currency.Code, target currency.Code) (*Quote, error)
+}
+
+type Quote struct {
+	Id        string
+	Source    currency.Code
+	Target    currency.Code
+	Rate      string
+	ExpiresAt time.Time
+}
+
+// Convert exchanges amount into the target currency at the given rate,
+// rounding down, so the exchange never pays out more than the rate allows.
+func Convert(amount money.Amount, target currency.Code, rate string) (money.Amount, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return money.Amount{}, errors.Errorf("invalid rate %q", rate)
+	}
+
+	sourceExponent, err := amount.Currency().Exponent()
+	if err != nil {
+		return money.Amount{}, err
+	}
+	targetExponent, err := target.Exponent()
+	if err != nil {
+		return money.Amount{}, err
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.Minor()), r)
+	shift := targetExponent - sourceExponent
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(max(shift, -shift))), nil))
+	if shift > 0 {
+		converted.Mul(converted, scale)
+	} else {
+		converted.Quo(converted, scale)
+	}
+
+	minorUnits := new(big.Int).Quo(converted.Num(), converted.Denom())
+	if minorUnits.Sign() < 1 {
+		return money.Amount{}, errors.Wrapf(ErrConvertedAmountTooSmall, "amount=%s, rate=%s", amount, rate)
+	}
+	if !minorUnits.IsInt64() {
+		return money.Amount{}, errors.Wrapf(ErrConvertedAmountTooLarge, "amount=%s, rate=%s", amount, rate)
+	}
+
+	return money.New(minorUnits.Int64(), target), nil
+}
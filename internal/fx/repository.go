@@ -0,0 +1,103 @@
+package fx
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+)
+
+func NewRepository(db *sql.DB, rateProvider RateProvider, quoteTtl time.Duration) (*Repository, error) {
+	if db == nil {
+		return nil, errors.New("db cannot be nil")
+	}
+	if rateProvider == nil {
+		return nil, errors.New("rate provider cannot be nil")
+	}
+	if quoteTtl <= 0 {
+		return nil, errors.New("quote ttl must be positive")
+	}
+	return &Repository{
+		db:           db,
+		rateProvider: rateProvider,
+		quoteTtl:     quoteTtl,
+	}, nil
+}
+
+type Repository struct {
+	db           *sql.DB
+	rateProvider RateProvider
+	quoteTtl     time.Duration
+}
+
+func (r *Repository) Quote(ctx context.Context, source currency.Code, target currency.Code) (*Quote, error) {
+	if source == target {
+		return nil, errors.Wrapf(ErrSameCurrency, "%s/%s", source, target)
+	}
+
+	rate, err := r.rateProvider.Rate(ctx, source, target)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not look up exchange rate")
+	}
+
+	id, err := newQuoteId()
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &Quote{
+		Id:     id,
+		Source: source,
+		Target: target,
+		Rate:   rate,
+	}
+	res := r.db.QueryRowContext(ctx, "INSERT INTO fx_quotes (id, source_currency, target_currency, rate, expires_at) VALUES ($1, $2, $3, $4, now() + $5 * INTERVAL '1 millisecond') RETURNING expires_at",
+		quote.Id, quote.Source, quote.Target, quote.Rate, r.quoteTtl.Milliseconds())
+	if err := res.Scan(&quote.ExpiresAt); err != nil {
+		return nil, errors.Wrapf(err, "could not store %s/%s quote", source, target)
+	}
+
+	return quote, nil
+}
+
+// Consume marks the quote as used within tx and returns it, a quote can only ever back a single exchange.
+func Consume(ctx context.Context, tx *sql.Tx, id string, source currency.Code, target currency.Code) (*Quote, error) {
+	quote := &Quote{}
+	var expired, used bool
+	res := tx.QueryRowContext(ctx, "SELECT id, source_currency, target_currency, rate, expires_at, expires_at <= now(), used_at IS NOT NULL FROM fx_quotes WHERE id = $1 FOR UPDATE", id)
+	if err := res.Scan(&quote.Id, &quote.Source, &quote.Target, &quote.Rate, &quote.ExpiresAt, &expired, &used); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Wrapf(ErrQuoteNotFound, "quote id=%s", id)
+		}
+		return nil, errors.Wrapf(err, "could not read quote id=%s", id)
+	}
+
+	switch {
+	case used:
+		return nil, errors.Wrapf(ErrQuoteUsed, "quote id=%s", id)
+	case expired:
+		return nil, errors.Wrapf(ErrQuoteExpired, "quote id=%s expired at %s", id, quote.ExpiresAt.Format(time.RFC3339))
+	case quote.Source != source || quote.Target != target:
+		return nil, errors.Wrapf(ErrQuoteCurrencyMismatch, "quote id=%s is for %s/%s, exchange is %s/%s", id, quote.Source, quote.Target, source, target)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE fx_quotes SET used_at = now() WHERE id = $1", id); err != nil {
+		return nil, errors.Wrapf(err, "could not mark quote id=%s as used", id)
+	}
+
+	return quote, nil
+}
+
+func newQuoteId() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", errors.Wrap(err, "could not generate quote id")
+	}
+
+	return hex.EncodeToString(raw), nil
+}
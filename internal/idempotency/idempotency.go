@@ -0,0 +1,29 @@
+package idempotency
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+var ErrFingerprintMismatch = errors.New("idempotency key was already used for a different request")
+var ErrInProgress = errors.New("request with the same idempotency key is still in progress")
+var ErrOutcomeUnknown = errors.New("request with the same idempotency key did not finish in time, its outcome is unknown")
+var ErrLeaseLost = errors.New("idempotency key is no longer held by this request")
+
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type Store interface {
+	// Begin claims the key of actor for owner, the request identified by fingerprint. A nil response means the key
+	// was claimed and the request has to be processed, otherwise the stored response of the original request is
+	// returned. Keys are scoped to the actor sending them, the same key sent by different actors names different requests.
+	Begin(ctx context.Context, actor string, key string, owner string, fingerprint string) (*Response, error)
+	// Complete stores the response of the request, it fails with ErrLeaseLost unless owner still holds the key.
+	Complete(ctx context.Context, actor string, key string, owner string, response *Response) error
+	// Release gives up the key held by owner, so that the request can be sent again.
+	Release(ctx context.Context, actor string, key string, owner string) error
+}
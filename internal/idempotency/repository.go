@@ -0,0 +1,120 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NewRepository keeps the keys for retention. A key is held by the request processing it for lease, a retry sent
+// after the lease lapsed without a response being stored is told the outcome of the request is unknown, as it may
+// have changed the balances before the instance crashed or simply still be running.
+func NewRepository(db *sql.DB, retention time.Duration, lease time.Duration) (*Repository, error) {
+	if db == nil {
+		return nil, errors.New("db cannot be nil")
+	}
+	if retention <= 0 {
+		return nil, errors.New("retention must be positive")
+	}
+	if lease <= 0 {
+		return nil, errors.New("lease must be positive")
+	}
+	return &Repository{
+		db:        db,
+		retention: retention,
+		lease:     lease,
+	}, nil
+}
+
+type Repository struct {
+	db        *sql.DB
+	retention time.Duration
+	lease     time.Duration
+}
+
+func (r *Repository) Begin(ctx context.Context, actor string, key string, owner string, fingerprint string) (*Response, error) {
+	// Expired keys are claimed again as if they never existed
+	res := r.db.QueryRowContext(ctx, `INSERT INTO idempotency_keys (actor, key, owner, fingerprint, locked_until, expires_at) VALUES ($1, $2, $3, $4, now() + $6 * INTERVAL '1 millisecond', now() + $5 * INTERVAL '1 millisecond')
+		ON CONFLICT (actor, key) DO UPDATE SET owner = EXCLUDED.owner, fingerprint = EXCLUDED.fingerprint, status_code = NULL, content_type = NULL, response_body = NULL, created_at = now(), locked_until = EXCLUDED.locked_until, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= now()
+		RETURNING key`, actor, key, owner, fingerprint, r.retention.Milliseconds(), r.lease.Milliseconds())
+	var claimed string
+	err := res.Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.Wrapf(err, "could not claim idempotency key=%q", key)
+	}
+
+	res = r.db.QueryRowContext(ctx, "SELECT fingerprint, status_code, content_type, response_body, locked_until <= now() FROM idempotency_keys WHERE actor = $1 AND key = $2", actor, key)
+	var storedFingerprint string
+	var statusCode sql.NullInt64
+	var contentType sql.NullString
+	var body []byte
+	var lapsed bool
+	if err := res.Scan(&storedFingerprint, &statusCode, &contentType, &body, &lapsed); err != nil {
+		return nil, errors.Wrapf(err, "could not read idempotency key=%q", key)
+	}
+
+	if storedFingerprint != fingerprint {
+		return nil, errors.Wrapf(ErrFingerprintMismatch, "key=%q", key)
+	}
+
+	if !statusCode.Valid {
+		// The request may have changed the balances already, so it is never processed a second time
+		if lapsed {
+			return nil, errors.Wrapf(ErrOutcomeUnknown, "key=%q", key)
+		}
+		return nil, errors.Wrapf(ErrInProgress, "key=%q", key)
+	}
+
+	return &Response{
+		StatusCode:  int(statusCode.Int64),
+		ContentType: contentType.String,
+		Body:        body,
+	}, nil
+}
+
+func (r *Repository) Complete(ctx context.Context, actor string, key string, owner string, response *Response) error {
+	// A request whose lease lapsed still stores its response, retries then get it instead of an unknown outcome
+	res, err := r.db.ExecContext(ctx, "UPDATE idempotency_keys SET status_code = $4, content_type = $5, response_body = $6 WHERE actor = $1 AND key = $2 AND owner = $3 AND status_code IS NULL",
+		actor, key, owner, response.StatusCode, response.ContentType, response.Body)
+	if err != nil {
+		return errors.Wrapf(err, "could not store response of idempotency key=%q", key)
+	}
+
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrapf(err, "could not count stored responses of idempotency key=%q", key)
+	}
+	if updated == 0 {
+		return errors.Wrapf(ErrLeaseLost, "key=%q", key)
+	}
+
+	return nil
+}
+
+func (r *Repository) Release(ctx context.Context, actor string, key string, owner string) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE actor = $1 AND key = $2 AND owner = $3 AND status_code IS NULL", actor, key, owner); err != nil {
+		return errors.Wrapf(err, "could not release idempotency key=%q", key)
+	}
+
+	return nil
+}
+
+func (r *Repository) Purge(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= now()")
+	if err != nil {
+		return 0, errors.Wrap(err, "could not purge expired idempotency keys")
+	}
+
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "could not count purged idempotency keys")
+	}
+
+	return purged, nil
+}
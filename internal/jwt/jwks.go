@@ -0,0 +1,154 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// KeySet holds the public keys of a JSON Web Key Set (RFC 7517) tokens are verified with.
+type KeySet struct {
+	keys []*key
+}
+
+type key struct {
+	id        string
+	algorithm string
+	public    crypto.PublicKey
+}
+
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyId     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	// N and E are the modulus and the exponent of RSA keys.
+	N string `json:"n"`
+	E string `json:"e"`
+	// Curve, X and Y are the curve and the coordinates of EC keys.
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+// LoadKeySet reads a key set from a local JWKS file.
+func LoadKeySet(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read key set %q", path)
+	}
+
+	keySet, err := ParseKeySet(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot parse key set %q", path)
+	}
+
+	return keySet, nil
+}
+
+// ParseKeySet parses the RSA and P-256 signing keys of a JWKS document, keys of other types are skipped.
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, errors.Wrap(err, "key set is not valid json")
+	}
+
+	keySet := &KeySet{}
+	for i, raw := range document.Keys {
+		if raw.Use != "" && raw.Use != "sig" {
+			continue
+		}
+
+		parsed, err := raw.key()
+		if err != nil {
+			return nil, errors.Wrapf(err, "key #%d kid=%q", i, raw.KeyId)
+		}
+		if parsed != nil {
+			keySet.keys = append(keySet.keys, parsed)
+		}
+	}
+
+	return keySet, nil
+}
+
+func (j *jwk) key() (*key, error) {
+	switch {
+	case j.KeyType == "RSA" && (j.Algorithm == "" || j.Algorithm == AlgorithmRS256):
+		n, err := decodeInt(j.N)
+		if err != nil {
+			return nil, errors.Wrap(err, "modulus")
+		}
+		e, err := decodeInt(j.E)
+		if err != nil {
+			return nil, errors.Wrap(err, "exponent")
+		}
+		if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("exponent out of range")
+		}
+
+		return &key{id: j.KeyId, algorithm: AlgorithmRS256, public: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case j.KeyType == "EC" && j.Curve == "P-256" && (j.Algorithm == "" || j.Algorithm == AlgorithmES256):
+		x, err := decodeInt(j.X)
+		if err != nil {
+			return nil, errors.Wrap(err, "x coordinate")
+		}
+		y, err := decodeInt(j.Y)
+		if err != nil {
+			return nil, errors.Wrap(err, "y coordinate")
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the P-256 curve")
+		}
+
+		return &key{id: j.KeyId, algorithm: AlgorithmES256, public: &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (s *KeySet) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.keys)
+}
+
+// find returns the key with the given id meant for algorithm, tokens without a key id can only be verified by a key
+// set holding a single key for their algorithm.
+func (s *KeySet) find(id string, algorithm string) (crypto.PublicKey, error) {
+	var found *key
+	if s != nil {
+		for _, candidate := range s.keys {
+			if candidate.algorithm != algorithm || (id != "" && candidate.id != id) {
+				continue
+			}
+			if found != nil {
+				return nil, errors.Wrapf(ErrUnknownKey, "several %s keys match kid=%q", algorithm, id)
+			}
+			found = candidate
+		}
+	}
+
+	if found == nil {
+		return nil, errors.Wrapf(ErrUnknownKey, "no %s key with kid=%q", algorithm, id)
+	}
+
+	return found.public, nil
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("not a base64url encoded integer")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,109 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrMalformed = errors.New("token is malformed")
+var ErrUnsupportedAlgorithm = errors.New("token signing algorithm is not supported")
+var ErrUnknownKey = errors.New("token is signed with an unknown key")
+var ErrSignature = errors.New("token signature is not valid")
+var ErrExpired = errors.New("token has expired")
+var ErrNotYetValid = errors.New("token is not valid yet")
+var ErrSubjectNotSet = errors.New("token has no subject")
+
+// TokenVerifier verifies tokens, Verifier is the implementation.
+type TokenVerifier interface {
+	Verify(token string) (*Claims, error)
+}
+
+// Claims are the claims of a verified token this service relies on.
+type Claims struct {
+	// Subject identifies the caller, it owns the accounts created with the token.
+	Subject   string
+	Roles     []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+}
+
+func (c *Claims) HasAnyRole(roles []string) bool {
+	for _, role := range c.Roles {
+		if slices.Contains(roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// rawClaims is the payload of a token, the registered claims are numeric dates in seconds.
+type rawClaims struct {
+	Subject   string       `json:"sub"`
+	Roles     []string     `json:"roles"`
+	ExpiresAt *json.Number `json:"exp"`
+	NotBefore *json.Number `json:"nbf"`
+}
+
+func (c *rawClaims) claims() (*Claims, error) {
+	if c.Subject == "" {
+		return nil, ErrSubjectNotSet
+	}
+	if c.ExpiresAt == nil {
+		return nil, errors.Wrap(ErrMalformed, "exp claim is mandatory")
+	}
+
+	claims := &Claims{
+		Subject: c.Subject,
+		Roles:   c.Roles,
+	}
+
+	var err error
+	if claims.ExpiresAt, err = numericDate(*c.ExpiresAt); err != nil {
+		return nil, errors.Wrap(err, "exp claim")
+	}
+	if c.NotBefore != nil {
+		if claims.NotBefore, err = numericDate(*c.NotBefore); err != nil {
+			return nil, errors.Wrap(err, "nbf claim")
+		}
+	}
+
+	return claims, nil
+}
+
+func numericDate(value json.Number) (time.Time, error) {
+	seconds, err := value.Float64()
+	if err != nil {
+		return time.Time{}, errors.Wrapf(ErrMalformed, "date=%q is not a number", value)
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), nil
+}
+
+// User is the caller a verified token identifies.
+type User struct {
+	Subject string
+	// Privileged users hold one of the privileged roles, they are not restricted to the accounts they own.
+	Privileged bool
+}
+
+// Actor identifies the user in the audit log.
+func (u *User) Actor() string {
+	return fmt.Sprintf("user:%s", u.Subject)
+}
+
+type userKey struct{}
+
+// With tells which user makes the request ctx belongs to.
+func With(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// From returns the user set by With, or nil if the request was not authenticated by a token.
+func From(ctx context.Context) *User {
+	user, _ := ctx.Value(userKey{}).(*User)
+	return user
+}
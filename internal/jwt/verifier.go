@@ -0,0 +1,149 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+// leeway tolerates the clock of the issuer being slightly ahead or behind.
+const leeway = 30 * time.Second
+
+// NewVerifier verifies HS256 tokens with secret and RS256 and ES256 tokens with the keys of keySet, either may be
+// left empty to reject the tokens they would verify.
+func NewVerifier(secret []byte, keySet *KeySet) (*Verifier, error) {
+	if len(secret) == 0 && keySet.Len() == 0 {
+		return nil, errors.New("either a secret or a key set is needed")
+	}
+	return &Verifier{
+		secret: secret,
+		keySet: keySet,
+		now:    time.Now,
+	}, nil
+}
+
+type Verifier struct {
+	secret []byte
+	keySet *KeySet
+	now    func() time.Time
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid"`
+}
+
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.Wrap(ErrMalformed, "a token has 3 parts")
+	}
+
+	var head header
+	if err := decodeJson(parts[0], &head); err != nil {
+		return nil, errors.Wrap(err, "header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(ErrMalformed, "signature is not base64url encoded")
+	}
+
+	// The algorithm of the header is only trusted along with a key meant for it
+	signed := []byte(parts[0] + "." + parts[1])
+	if err := v.verifySignature(&head, signed, signature); err != nil {
+		return nil, err
+	}
+
+	var raw rawClaims
+	if err := decodeJson(parts[1], &raw); err != nil {
+		return nil, errors.Wrap(err, "payload")
+	}
+	claims, err := raw.claims()
+	if err != nil {
+		return nil, err
+	}
+
+	now := v.now()
+	if now.After(claims.ExpiresAt.Add(leeway)) {
+		return nil, errors.Wrapf(ErrExpired, "expired at %s", claims.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	if !claims.NotBefore.IsZero() && now.Add(leeway).Before(claims.NotBefore) {
+		return nil, errors.Wrapf(ErrNotYetValid, "valid from %s", claims.NotBefore.UTC().Format(time.RFC3339))
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) verifySignature(head *header, signed []byte, signature []byte) error {
+	digest := sha256.Sum256(signed)
+
+	switch head.Algorithm {
+	case AlgorithmHS256:
+		if len(v.secret) == 0 {
+			return errors.Wrapf(ErrUnsupportedAlgorithm, "alg=%q", head.Algorithm)
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrSignature
+		}
+		return nil
+	case AlgorithmRS256:
+		key, err := v.keySet.find(head.KeyId, AlgorithmRS256)
+		if err != nil {
+			return err
+		}
+		if err := rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature); err != nil {
+			return ErrSignature
+		}
+		return nil
+	case AlgorithmES256:
+		key, err := v.keySet.find(head.KeyId, AlgorithmES256)
+		if err != nil {
+			return err
+		}
+		// The signature is r and s as big-endian unsigned integers of 32 bytes each
+		if len(signature) != 64 {
+			return ErrSignature
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key.(*ecdsa.PublicKey), digest[:], r, s) {
+			return ErrSignature
+		}
+		return nil
+	default:
+		return errors.Wrapf(ErrUnsupportedAlgorithm, "alg=%q", head.Algorithm)
+	}
+}
+
+func decodeJson(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errors.Wrap(ErrMalformed, "not base64url encoded")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return errors.Wrap(ErrMalformed, "not a json object")
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package jwt_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/jwt"
+)
+
+var secret = []byte("test-secret")
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	otherRsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	keySet := writeKeySet(t, map[string]any{"keys": []map[string]any{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": encodeInt(rsaKey.N), "e": encodeInt(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "crv": "P-256", "x": encodeInt(ecKey.X), "y": encodeInt(ecKey.Y)},
+		{"kty": "oct", "kid": "skipped", "k": "c2VjcmV0"},
+	}})
+
+	verifier, err := jwt.NewVerifier(secret, keySet)
+	assert.NoError(t, err)
+	keysOnly, err := jwt.NewVerifier(nil, keySet)
+	assert.NoError(t, err)
+
+	valid := map[string]any{"sub": "alice", "roles": []string{"teller"}, "exp": time.Now().Add(time.Hour).Unix()}
+
+	type testCase struct {
+		verifier *jwt.Verifier
+		token    string
+		expected error
+	}
+
+	testCases := map[string]testCase{
+		"HS256":                       {verifier: verifier, token: signHS256(map[string]any{"alg": "HS256"}, valid, secret)},
+		"RS256":                       {verifier: verifier, token: signRS256(map[string]any{"alg": "RS256", "kid": "rsa-1"}, valid, rsaKey)},
+		"ES256 without key id":        {verifier: verifier, token: signES256(map[string]any{"alg": "ES256"}, valid, ecKey)},
+		"HS256 with other secret":     {verifier: verifier, token: signHS256(map[string]any{"alg": "HS256"}, valid, []byte("other")), expected: jwt.ErrSignature},
+		"RS256 with other key":        {verifier: verifier, token: signRS256(map[string]any{"alg": "RS256", "kid": "rsa-1"}, valid, otherRsaKey), expected: jwt.ErrSignature},
+		"RS256 with unknown key id":   {verifier: verifier, token: signRS256(map[string]any{"alg": "RS256", "kid": "rsa-2"}, valid, rsaKey), expected: jwt.ErrUnknownKey},
+		"HS256 without secret":        {verifier: keysOnly, token: signHS256(map[string]any{"alg": "HS256"}, valid, secret), expected: jwt.ErrUnsupportedAlgorithm},
+		"unsigned":                    {verifier: verifier, token: encode(map[string]any{"alg": "none"}) + "." + encode(valid) + ".", expected: jwt.ErrUnsupportedAlgorithm},
+		"tampered payload":            {verifier: verifier, token: tamper(signHS256(map[string]any{"alg": "HS256"}, valid, secret), map[string]any{"sub": "mallory", "exp": time.Now().Add(time.Hour).Unix()}), expected: jwt.ErrSignature},
+		"expired":                     {verifier: verifier, token: signHS256(map[string]any{"alg": "HS256"}, map[string]any{"sub": "alice", "exp": time.Now().Add(-time.Hour).Unix()}, secret), expected: jwt.ErrExpired},
+		"expired within leeway":       {verifier: verifier, token: signHS256(map[string]any{"alg": "HS256"}, map[string]any{"sub": "alice", "exp": time.Now().Add(-10 * time.Second).Unix()}, secret)},
+		"not yet valid":               {verifier: verifier, token: signHS256(map[string]any{"alg": "HS256"}, map[string]any{"sub": "alice", "exp": time.Now().Add(2 * time.Hour).Unix(), "nbf": time.Now().Add(time.Hour).Unix()}, secret), expected: jwt.ErrNotYetValid},
+		"without subject":             {verifier: verifier, token: signHS256(map[string]any{"alg": "HS256"}, map[string]any{"exp": time.Now().Add(time.Hour).Unix()}, secret), expected: jwt.ErrSubjectNotSet},
+		"without expiry":              {verifier: verifier, token: signHS256(map[string]any{"alg": "HS256"}, map[string]any{"sub": "alice"}, secret), expected: jwt.ErrMalformed},
+		"not a token":                 {verifier: verifier, token: "not-a-token", expected: jwt.ErrMalformed},
+		"payload not json":            {verifier: verifier, token: signRaw(encode(map[string]any{"alg": "HS256"})+"."+base64.RawURLEncoding.EncodeToString([]byte("sub=alice")), secret), expected: jwt.ErrMalformed},
+		"signature of another header": {verifier: verifier, token: signHS256(map[string]any{"alg": "HS256", "kid": "x"}, valid, secret)},
+	}
+
+	for testName, test := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			claims, err := test.verifier.Verify(test.token)
+			if test.expected != nil {
+				assert.True(t, errors.Is(err, test.expected), "expected %v, got %v", test.expected, err)
+				assert.Nil(t, claims)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "alice", claims.Subject)
+		})
+	}
+}
+
+func TestClaimsHasAnyRole(t *testing.T) {
+	claims := &jwt.Claims{Roles: []string{"teller", "auditor"}}
+	assert.True(t, claims.HasAnyRole([]string{"admin", "teller"}))
+	assert.False(t, claims.HasAnyRole([]string{"admin"}))
+	assert.False(t, (&jwt.Claims{}).HasAnyRole([]string{"admin"}))
+}
+
+func TestLoadKeySet(t *testing.T) {
+	t.Run("invalid key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`), 0o600))
+
+		_, err := jwt.LoadKeySet(path)
+		assert.ErrorContains(t, err, "not on the P-256 curve")
+	})
+	t.Run("missing file", func(t *testing.T) {
+		_, err := jwt.LoadKeySet(filepath.Join(t.TempDir(), "missing.json"))
+		assert.Error(t, err)
+	})
+	t.Run("nothing to verify with", func(t *testing.T) {
+		_, err := jwt.NewVerifier(nil, nil)
+		assert.Error(t, err)
+	})
+}
+
+func writeKeySet(t *testing.T, document map[string]any) *jwt.KeySet {
+	data, err := json.Marshal(document)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, data, 0o600))
+
+	keySet, err := jwt.LoadKeySet(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, keySet.Len())
+	return keySet
+}
+
+func encode(v any) string {
+	data, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func signHS256(header map[string]any, claims map[string]any, key []byte) string {
+	return signRaw(encode(header)+"."+encode(claims), key)
+}
+
+func signRaw(signed string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(header map[string]any, claims map[string]any, key *rsa.PrivateKey) string {
+	signed := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signES256(header map[string]any, claims map[string]any, key *ecdsa.PrivateKey) string {
+	signed := encode(header) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, _ := ecdsa.Sign(rand.Reader, key, digest[:])
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// tamper replaces the payload of a token, keeping its header and signature.
+func tamper(token string, claims map[string]any) string {
+	parts := strings.Split(token, ".")
+	return parts[0] + "." + encode(claims) + "." + parts[2]
+}
@@ -0,0 +1,121 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+// System accounts are not backed by a row in the accounts table, they only exist in the books.
+// Reserved negative ids keep them apart from customer accounts.
+const (
+	FundingAccountId  int64 = -1
+	ExchangeAccountId int64 = -2
+	// PayoutAccountId collects the money withdrawn from customer accounts until it is paid out.
+	PayoutAccountId int64 = -3
+)
+
+const (
+	KindTopUp      Kind = "topup"
+	KindTransfer   Kind = "transfer"
+	KindExchange   Kind = "exchange"
+	KindWithdrawal Kind = "withdrawal"
+	KindReversal   Kind = "reversal"
+)
+
+var ErrEntryEmpty = errors.New("journal entry must have at least two postings")
+var ErrEntryUnbalanced = errors.New("journal entry postings do not sum up to zero")
+var ErrPostingZeroAmount = errors.New("posting amount cannot be zero")
+
+type Kind string
+
+type Entry struct {
+	Kind     Kind
+	Postings []Posting
+	// Exchange is only set on entries converting between currencies.
+	Exchange *Exchange
+	// ReversalOf is the id of the entry this one compensates, it is only set on reversals.
+	ReversalOf *int64
+}
+
+type Exchange struct {
+	QuoteId string
+	Rate    string
+}
+
+func (e *Entry) Validate() error {
+	if len(e.Postings) < 2 {
+		return ErrEntryEmpty
+	}
+
+	// Amounts of different currencies cannot offset each other, hence every currency has to balance on its own
+	sums := make(map[currency.Code]money.Amount)
+	for _, posting := range e.Postings {
+		if posting.Amount.IsZero() {
+			return errors.Wrapf(ErrPostingZeroAmount, "account id=%d", posting.AccountId)
+		}
+
+		code := posting.Amount.Currency()
+		sum, ok := sums[code]
+		if !ok {
+			sum = money.Zero(code)
+		}
+
+		var err error
+		if sums[code], err = sum.Add(posting.Amount); err != nil {
+			return errors.Wrapf(err, "account id=%d", posting.AccountId)
+		}
+	}
+
+	for code, sum := range sums {
+		if !sum.IsZero() {
+			return errors.Wrapf(ErrEntryUnbalanced, "currency=%s, sum=%s", code, sum)
+		}
+	}
+
+	return nil
+}
+
+// Posting moves Amount in or out of an account, a positive amount is a credit and a negative one is a debit.
+type Posting struct {
+	AccountId int64
+	Amount    money.Amount
+}
+
+func Debit(accountId int64, amount money.Amount) Posting {
+	return Posting{AccountId: accountId, Amount: money.New(-amount.Minor(), amount.Currency())}
+}
+
+func Credit(accountId int64, amount money.Amount) Posting {
+	return Posting{AccountId: accountId, Amount: amount}
+}
+
+// Post writes the entry within tx, so that it is committed or rolled back together with the balance updates it describes.
+func Post(ctx context.Context, tx *sql.Tx, entry *Entry) (int64, error) {
+	if err := entry.Validate(); err != nil {
+		return 0, errors.Wrapf(err, "invalid %s journal entry", entry.Kind)
+	}
+
+	var quoteId, rate *string
+	if entry.Exchange != nil {
+		quoteId, rate = &entry.Exchange.QuoteId, &entry.Exchange.Rate
+	}
+
+	res := tx.QueryRowContext(ctx, "INSERT INTO journal_entries (kind, fx_quote_id, fx_rate, reversal_of) VALUES ($1, $2, $3, $4) RETURNING id", entry.Kind, quoteId, rate, entry.ReversalOf)
+	var entryId int64
+	if err := res.Scan(&entryId); err != nil {
+		return 0, errors.Wrapf(err, "could not insert %s journal entry", entry.Kind)
+	}
+
+	for _, posting := range entry.Postings {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO postings (journal_entry_id, account_id, amount, currency) VALUES ($1, $2, $3, $4)", entryId, posting.AccountId, posting.Amount, posting.Amount.Currency()); err != nil {
+			return 0, errors.Wrapf(err, "could not insert posting of journal entry id=%d for account id=%d", entryId, posting.AccountId)
+		}
+	}
+
+	return entryId, nil
+}
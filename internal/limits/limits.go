@@ -0,0 +1,96 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+// DefaultTier is the tier of every account that has not been assigned one.
+const DefaultTier = "standard"
+
+const (
+	// MetricCount limits the number of outgoing transfers.
+	MetricCount Metric = "count"
+	// MetricAmount limits the sum of outgoing transfers, in minor units of the account currency.
+	MetricAmount Metric = "amount"
+)
+
+const (
+	PeriodHour Period = "hour"
+	PeriodDay  Period = "day"
+)
+
+var ErrLimitExceeded = errors.New("transfer limit exceeded")
+var ErrInvalidRule = errors.New("invalid limit rule")
+var ErrInvalidTier = errors.New("tier must be 1 to 32 lowercase letters, digits, dashes or underscores")
+
+type Metric string
+
+type Period string
+
+// Rule caps the outgoing transfers of an account within a calendar period in UTC, e.g. at most 10 transfers per hour.
+type Rule struct {
+	Metric Metric
+	Period Period
+	Max    int64
+}
+
+func (r Rule) Validate() error {
+	if r.Metric != MetricCount && r.Metric != MetricAmount {
+		return errors.Wrapf(ErrInvalidRule, "unknown metric=%q", r.Metric)
+	}
+	if r.Period != PeriodHour && r.Period != PeriodDay {
+		return errors.Wrapf(ErrInvalidRule, "unknown period=%q", r.Period)
+	}
+	if r.Max < 0 {
+		return errors.Wrapf(ErrInvalidRule, "max=%d must not be negative", r.Max)
+	}
+
+	return nil
+}
+
+// Policy is the set of rules an account is subject to, rules of the account override the rules of its tier
+// for the same metric and period.
+type Policy struct {
+	Tier  string
+	Rules []Rule
+}
+
+// Violation is returned, wrapped, when a transfer would break a rule, it unwraps to ErrLimitExceeded.
+type Violation struct {
+	Rule Rule
+	// Currency is the currency of the account, amount rules are expressed in its minor units.
+	Currency currency.Code
+	ResetsAt time.Time
+}
+
+func (v *Violation) Error() string {
+	limit := fmt.Sprintf("%d transfers", v.Rule.Max)
+	if v.Rule.Metric == MetricAmount {
+		limit = money.New(v.Rule.Max, v.Currency).String() + " outgoing"
+	}
+
+	return fmt.Sprintf("%s: at most %s per %s, resets at %s", ErrLimitExceeded, limit, v.Rule.Period, v.ResetsAt.UTC().Format(time.RFC3339))
+}
+
+func (v *Violation) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+type Configurer interface {
+	// SetAccountRules replaces the rules of the account, the rules of its tier keep applying to any other metric and period.
+	SetAccountRules(ctx context.Context, accountId int64, rules []Rule) error
+	// SetTierRules replaces the rules of the tier.
+	SetTierRules(ctx context.Context, tier string, rules []Rule) error
+	SetTier(ctx context.Context, accountId int64, tier string) error
+}
+
+type PolicyFinder interface {
+	Policy(ctx context.Context, accountId int64) (*Policy, error)
+}
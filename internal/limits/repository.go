@@ -0,0 +1,207 @@
+package limits
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/database"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+// usage is what an account has transferred out within the current period so far.
+type usage struct {
+	count    int64
+	amount   int64
+	resetsAt time.Time
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+var tierPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// policyQuery selects the rules of the account given as $1, its own rules take precedence over those of its tier.
+const policyQuery = `SELECT DISTINCT ON (l.metric, l.period) l.metric, l.period, l.max
+FROM transfer_limits l
+WHERE l.account_id = $1 OR l.tier = COALESCE((SELECT t.tier FROM account_tiers t WHERE t.account_id = $1), '` + DefaultTier + `')
+ORDER BY l.metric, l.period, l.account_id NULLS LAST`
+
+func NewRepository(db *sql.DB) (*Repository, error) {
+	if db == nil {
+		return nil, errors.New("db cannot be nil")
+	}
+	return &Repository{
+		db: db,
+	}, nil
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func (r *Repository) SetAccountRules(ctx context.Context, accountId int64, rules []Rule) error {
+	if err := validateRules(rules); err != nil {
+		return err
+	}
+
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM transfer_limits WHERE account_id = $1", accountId); err != nil {
+			return errors.Wrap(err, "could not delete previous rules")
+		}
+		for _, rule := range rules {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO transfer_limits (account_id, metric, period, max) VALUES ($1, $2, $3, $4)", accountId, rule.Metric, rule.Period, rule.Max); err != nil {
+				return errors.Wrapf(err, "could not insert %s per %s rule", rule.Metric, rule.Period)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "could not set limit rules of account with id=%d", accountId)
+	}
+
+	return nil
+}
+
+func (r *Repository) SetTierRules(ctx context.Context, tier string, rules []Rule) error {
+	if !tierPattern.MatchString(tier) {
+		return errors.Wrapf(ErrInvalidTier, "tier=%q", tier)
+	}
+	if err := validateRules(rules); err != nil {
+		return err
+	}
+
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM transfer_limits WHERE tier = $1", tier); err != nil {
+			return errors.Wrap(err, "could not delete previous rules")
+		}
+		for _, rule := range rules {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO transfer_limits (tier, metric, period, max) VALUES ($1, $2, $3, $4)", tier, rule.Metric, rule.Period, rule.Max); err != nil {
+				return errors.Wrapf(err, "could not insert %s per %s rule", rule.Metric, rule.Period)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "could not set limit rules of tier=%s", tier)
+	}
+
+	return nil
+}
+
+func (r *Repository) SetTier(ctx context.Context, accountId int64, tier string) error {
+	if !tierPattern.MatchString(tier) {
+		return errors.Wrapf(ErrInvalidTier, "tier=%q", tier)
+	}
+
+	_, err := r.db.ExecContext(ctx, "INSERT INTO account_tiers (account_id, tier) VALUES ($1, $2) ON CONFLICT (account_id) DO UPDATE SET tier = excluded.tier", accountId, tier)
+	if err != nil {
+		return errors.Wrapf(err, "could not assign tier=%s to account with id=%d", tier, accountId)
+	}
+
+	return nil
+}
+
+func (r *Repository) Policy(ctx context.Context, accountId int64) (*Policy, error) {
+	policy := &Policy{}
+	res := r.db.QueryRowContext(ctx, "SELECT COALESCE((SELECT tier FROM account_tiers WHERE account_id = $1), $2)", accountId, DefaultTier)
+	if err := res.Scan(&policy.Tier); err != nil {
+		return nil, errors.Wrapf(err, "could not read tier of account with id=%d", accountId)
+	}
+
+	rules, err := queryRules(ctx, r.db, accountId)
+	if err != nil {
+		return nil, err
+	}
+	policy.Rules = rules
+
+	return policy, nil
+}
+
+// Check fails with a Violation if transferring amount out of the account would break any of its rules. It has to
+// run within the transaction of the transfer, after the account has been locked, so that the usage it reads from the
+// transaction history cannot change before the transfer is recorded.
+func Check(ctx context.Context, tx *sql.Tx, accountId int64, amount money.Amount) error {
+	rules, err := queryRules(ctx, tx, accountId)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var hour, day usage
+	res := tx.QueryRowContext(ctx, `SELECT
+    COUNT(*) FILTER (WHERE created_at >= date_trunc('hour', now(), 'UTC')),
+    COALESCE(SUM(amount) FILTER (WHERE created_at >= date_trunc('hour', now(), 'UTC')), 0)::BIGINT,
+    date_trunc('hour', now(), 'UTC') + INTERVAL '1 hour',
+    COUNT(*),
+    COALESCE(SUM(amount), 0)::BIGINT,
+    date_trunc('day', now(), 'UTC') + INTERVAL '1 day'
+FROM transactions
+WHERE account_id = $1 AND direction = 'out' AND type = 'transfer' AND created_at >= date_trunc('day', now(), 'UTC')`, accountId)
+	if err := res.Scan(&hour.count, &hour.amount, &hour.resetsAt, &day.count, &day.amount, &day.resetsAt); err != nil {
+		return errors.Wrapf(err, "could not read transfer usage of account with id=%d", accountId)
+	}
+
+	for _, rule := range rules {
+		used := hour
+		if rule.Period == PeriodDay {
+			used = day
+		}
+		exceeded := used.count >= rule.Max
+		if rule.Metric == MetricAmount {
+			total, err := money.New(used.amount, amount.Currency()).Add(amount)
+			exceeded = err != nil || total.Minor() > rule.Max
+		}
+		if exceeded {
+			return errors.Wrapf(&Violation{Rule: rule, Currency: amount.Currency(), ResetsAt: used.resetsAt}, "account id=%d", accountId)
+		}
+	}
+
+	return nil
+}
+
+func queryRules(ctx context.Context, db querier, accountId int64) ([]Rule, error) {
+	rows, err := db.QueryContext(ctx, policyQuery, accountId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not query limit rules of account with id=%d", accountId)
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		var rule Rule
+		if err := rows.Scan(&rule.Metric, &rule.Period, &rule.Max); err != nil {
+			return nil, errors.Wrap(err, "could not scan database query result into struct")
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over database query result")
+	}
+
+	return rules, nil
+}
+
+func validateRules(rules []Rule) error {
+	seen := make(map[Rule]bool, len(rules))
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+
+		key := Rule{Metric: rule.Metric, Period: rule.Period}
+		if seen[key] {
+			return errors.Wrapf(ErrInvalidRule, "more than one %s per %s rule", rule.Metric, rule.Period)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
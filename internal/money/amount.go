@@ -0,0 +1,288 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+)
+
+// unitsMinor marks amounts that were given in minor units before their currency was known.
+const unitsMinor = -1
+
+var ErrOverflow = errors.New("amount out of range")
+var ErrCurrencyMismatch = errors.New("amounts are in different currencies")
+var ErrCurrencyNotSet = errors.New("amount is not bound to a currency")
+var ErrTooManyDecimals = errors.New("amount has more decimal places than its currency allows")
+var ErrMalformed = errors.New("amount must be an integer number of minor units or a decimal string")
+
+// Amount is a signed amount of money held as an integer number of minor units of its currency.
+//
+// Amounts decoded from JSON are not bound to a currency yet, they have to be bound with In before any arithmetic.
+// Integers are taken as minor units, while decimal strings such as "12.34" are taken as major units.
+type Amount struct {
+	value    int64
+	currency currency.Code
+	// decimals is the number of decimal places value is expressed in while the amount is not bound to a currency.
+	decimals int
+}
+
+func New(minor int64, code currency.Code) Amount {
+	return Amount{value: minor, currency: code}
+}
+
+func Zero(code currency.Code) Amount {
+	return New(0, code)
+}
+
+// Parse reads a decimal string in major units of the given currency, e.g. "12.34" EUR is 1234 minor units.
+func Parse(value string, code currency.Code) (Amount, error) {
+	unbound, err := parseDecimal(value)
+	if err != nil {
+		return Amount{}, err
+	}
+
+	return unbound.In(code)
+}
+
+// In binds the amount to the given currency, converting major units into minor units if needed.
+func (a Amount) In(code currency.Code) (Amount, error) {
+	if a.currency != "" {
+		if a.currency != code {
+			return Amount{}, errors.Wrapf(ErrCurrencyMismatch, "%s is not in %s", a, code)
+		}
+		return a, nil
+	}
+
+	exponent, err := code.Exponent()
+	if err != nil {
+		return Amount{}, err
+	}
+
+	if a.decimals == unitsMinor {
+		return New(a.value, code), nil
+	}
+
+	if a.decimals > exponent {
+		return Amount{}, errors.Wrapf(ErrTooManyDecimals, "%s allows %d", code, exponent)
+	}
+
+	minor := a.value
+	for i := a.decimals; i < exponent; i++ {
+		if minor, err = mul(minor, 10); err != nil {
+			return Amount{}, err
+		}
+	}
+
+	return New(minor, code), nil
+}
+
+func (a Amount) Minor() int64 {
+	return a.value
+}
+
+func (a Amount) Currency() currency.Code {
+	return a.currency
+}
+
+func (a Amount) IsBound() bool {
+	return a.currency != ""
+}
+
+func (a Amount) IsZero() bool {
+	return a.value == 0
+}
+
+func (a Amount) IsPositive() bool {
+	return a.value > 0
+}
+
+func (a Amount) IsNegative() bool {
+	return a.value < 0
+}
+
+func (a Amount) Add(b Amount) (Amount, error) {
+	if err := a.sameCurrency(b); err != nil {
+		return Amount{}, err
+	}
+
+	if (b.value > 0 && a.value > math.MaxInt64-b.value) || (b.value < 0 && a.value < math.MinInt64-b.value) {
+		return Amount{}, errors.Wrapf(ErrOverflow, "%s + %s", a, b)
+	}
+
+	return New(a.value+b.value, a.currency), nil
+}
+
+func (a Amount) Sub(b Amount) (Amount, error) {
+	negated, err := b.Neg()
+	if err != nil {
+		return Amount{}, err
+	}
+
+	return a.Add(negated)
+}
+
+func (a Amount) Neg() (Amount, error) {
+	if a.value == math.MinInt64 {
+		return Amount{}, errors.Wrapf(ErrOverflow, "-(%s)", a)
+	}
+
+	return Amount{value: -a.value, currency: a.currency, decimals: a.decimals}, nil
+}
+
+// Cmp returns -1, 0 or +1 depending on whether a is less than, equal to or greater than b.
+func (a Amount) Cmp(b Amount) (int, error) {
+	if err := a.sameCurrency(b); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case a.value < b.value:
+		return -1, nil
+	case a.value > b.value:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Decimal formats the amount in major units, e.g. 1234 minor units of EUR as "12.34".
+func (a Amount) Decimal() string {
+	decimals := a.decimals
+	if a.currency != "" {
+		decimals, _ = a.currency.Exponent()
+	}
+	if decimals <= 0 {
+		return strconv.FormatInt(a.value, 10)
+	}
+
+	digits := strconv.FormatUint(absUint(a.value), 10)
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	sign := ""
+	if a.value < 0 {
+		sign = "-"
+	}
+
+	return sign + digits[:len(digits)-decimals] + "." + digits[len(digits)-decimals:]
+}
+
+func (a Amount) String() string {
+	if a.currency == "" {
+		return a.Decimal()
+	}
+	return fmt.Sprintf("%s %s", a.Decimal(), a.currency)
+}
+
+// MarshalJSON writes the amount as an integer number of minor units, the currency is expected next to it.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(a.value, 10)), nil
+}
+
+// Value stores the amount as its number of minor units, the currency is expected in a column of its own.
+func (a Amount) Value() (driver.Value, error) {
+	if a.currency == "" {
+		return nil, ErrCurrencyNotSet
+	}
+
+	return a.value, nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return errors.Wrap(ErrMalformed, err.Error())
+		}
+
+		parsed, err := parseDecimal(value)
+		if err != nil {
+			return err
+		}
+
+		*a = parsed
+		return nil
+	}
+
+	minor, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return errors.Wrapf(ErrOverflow, "amount=%s", data)
+		}
+		return errors.Wrapf(ErrMalformed, "amount=%s", data)
+	}
+
+	*a = Amount{value: minor, decimals: unitsMinor}
+	return nil
+}
+
+func (a Amount) sameCurrency(b Amount) error {
+	if a.currency == "" || b.currency == "" {
+		return ErrCurrencyNotSet
+	}
+
+	if a.currency != b.currency {
+		return errors.Wrapf(ErrCurrencyMismatch, "%s and %s", a.currency, b.currency)
+	}
+
+	return nil
+}
+
+func parseDecimal(value string) (Amount, error) {
+	digits, negative := strings.CutPrefix(value, "-")
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" || !isDigits(whole) || !isDigits(fraction) || strings.HasSuffix(digits, ".") {
+		return Amount{}, errors.Wrapf(ErrMalformed, "amount=%q", value)
+	}
+
+	parsed, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return Amount{}, errors.Wrapf(ErrOverflow, "amount=%q", value)
+		}
+		return Amount{}, errors.Wrapf(ErrMalformed, "amount=%q", value)
+	}
+
+	if negative {
+		parsed = -parsed
+	}
+
+	return Amount{value: parsed, decimals: len(fraction)}, nil
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func mul(a int64, b int64) (int64, error) {
+	if a != 0 && (a*b)/b != a {
+		return 0, errors.Wrapf(ErrOverflow, "%d * %d", a, b)
+	}
+	return a * b, nil
+}
+
+func absUint(v int64) uint64 {
+	if v < 0 {
+		return uint64(-(v + 1)) + 1
+	}
+	return uint64(v)
+}
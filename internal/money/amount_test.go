@@ -0,0 +1,110 @@
+package money_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+func TestUnmarshalJSON(t *testing.T) {
+	type testCase struct {
+		json          string
+		currency      currency.Code
+		expectedMinor int64
+		expectedErr   error
+	}
+
+	testCases := map[string]testCase{
+		"integer minor units":             {json: `1234`, currency: "EUR", expectedMinor: 1234},
+		"decimal string":                  {json: `"12.34"`, currency: "EUR", expectedMinor: 1234},
+		"decimal string with one decimal": {json: `"12.3"`, currency: "EUR", expectedMinor: 1230},
+		"whole decimal string":            {json: `"12"`, currency: "EUR", expectedMinor: 1200},
+		"decimal string in 3 decimals":    {json: `"1.5"`, currency: "BHD", expectedMinor: 1500},
+		"decimal string in 0 decimals":    {json: `"1500"`, currency: "JPY", expectedMinor: 1500},
+		"negative decimal string":         {json: `"-0.05"`, currency: "EUR", expectedMinor: -5},
+		"too many decimals":               {json: `"12.345"`, currency: "EUR", expectedErr: money.ErrTooManyDecimals},
+		"decimals in currency without":    {json: `"1.5"`, currency: "JPY", expectedErr: money.ErrTooManyDecimals},
+		"malformed string":                {json: `"12,34"`, currency: "EUR", expectedErr: money.ErrMalformed},
+		"trailing dot":                    {json: `"12."`, currency: "EUR", expectedErr: money.ErrMalformed},
+		"float":                           {json: `12.34`, currency: "EUR", expectedErr: money.ErrMalformed},
+		"integer out of range":            {json: `9223372036854775808`, currency: "EUR", expectedErr: money.ErrOverflow},
+		"decimal string out of range":     {json: `"92233720368547758.08"`, currency: "EUR", expectedErr: money.ErrOverflow},
+		"decimal string overflowing":      {json: `"92233720368547759"`, currency: "EUR", expectedErr: money.ErrOverflow},
+	}
+
+	for testName, test := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			var amount money.Amount
+			err := json.Unmarshal([]byte(test.json), &amount)
+			if err == nil {
+				amount, err = amount.In(test.currency)
+			}
+
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedMinor, amount.Minor())
+			assert.Equal(t, test.currency, amount.Currency())
+		})
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(map[string]money.Amount{"amount": money.New(1234, "EUR")})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":1234}`, string(data))
+}
+
+func TestArithmetic(t *testing.T) {
+	t.Run("add and sub", func(t *testing.T) {
+		sum, err := money.New(150, "EUR").Add(money.New(50, "EUR"))
+		assert.NoError(t, err)
+		assert.Equal(t, money.New(200, "EUR"), sum)
+
+		diff, err := money.New(150, "EUR").Sub(money.New(200, "EUR"))
+		assert.NoError(t, err)
+		assert.Equal(t, money.New(-50, "EUR"), diff)
+	})
+	t.Run("overflow", func(t *testing.T) {
+		_, err := money.New(math.MaxInt64, "EUR").Add(money.New(1, "EUR"))
+		assert.ErrorIs(t, err, money.ErrOverflow)
+
+		_, err = money.New(math.MinInt64, "EUR").Sub(money.New(1, "EUR"))
+		assert.ErrorIs(t, err, money.ErrOverflow)
+
+		_, err = money.New(0, "EUR").Sub(money.New(math.MinInt64, "EUR"))
+		assert.ErrorIs(t, err, money.ErrOverflow)
+	})
+	t.Run("currency mismatch", func(t *testing.T) {
+		_, err := money.New(1, "EUR").Add(money.New(1, "USD"))
+		assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+
+		_, err = money.New(1, "EUR").Cmp(money.New(1, "USD"))
+		assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+	})
+	t.Run("unbound amount", func(t *testing.T) {
+		var unbound money.Amount
+		assert.NoError(t, json.Unmarshal([]byte(`100`), &unbound))
+
+		_, err := money.New(1, "EUR").Add(unbound)
+		assert.ErrorIs(t, err, money.ErrCurrencyNotSet)
+	})
+}
+
+func TestDecimal(t *testing.T) {
+	assert.Equal(t, "12.34", money.New(1234, "EUR").Decimal())
+	assert.Equal(t, "0.05", money.New(5, "EUR").Decimal())
+	assert.Equal(t, "-0.05", money.New(-5, "EUR").Decimal())
+	assert.Equal(t, "1500", money.New(1500, "JPY").Decimal())
+	assert.Equal(t, "1.500", money.New(1500, "BHD").Decimal())
+	assert.Equal(t, "-92233720368547758.08", money.New(math.MinInt64, "EUR").Decimal())
+	assert.Equal(t, "12.34 EUR", money.New(1234, "EUR").String())
+}
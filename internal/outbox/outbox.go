@@ -0,0 +1,53 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+var ErrNoAccounts = errors.New("event must concern at least one account")
+
+// Event describes a change which has been committed, it is what the sinks receive.
+type Event struct {
+	Id   int64  `json:"id"`
+	Type string `json:"type"`
+	// AccountIds are the accounts the event concerns, the events of any one account are published in the order they were added.
+	AccountIds []int64         `json:"account_ids"`
+	Payload    json.RawMessage `json:"payload"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Sink receives the published events. An event is only marked as published once every sink has accepted it,
+// so a sink may receive the same event more than once and has to tell duplicates apart by the event id.
+type Sink interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// Add writes an event within tx, so that it is committed or rolled back together with the change it describes.
+// The accounts have to be locked by tx, otherwise the events of an account could be numbered out of commit order.
+func Add(ctx context.Context, tx *sql.Tx, eventType string, accountIds []int64, payload any) error {
+	if len(accountIds) == 0 {
+		return errors.Wrapf(ErrNoAccounts, "event type=%s", eventType)
+	}
+
+	accountIds = slices.Clone(accountIds)
+	slices.Sort(accountIds)
+	accountIds = slices.Compact(accountIds)
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrapf(err, "could not encode payload of %s event", eventType)
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO outbox (type, account_ids, payload) VALUES ($1, $2, $3)", eventType, pq.Array(accountIds), encoded); err != nil {
+		return errors.Wrapf(err, "could not add %s event to the outbox", eventType)
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/database"
+)
+
+// NewRelay publishes up to batchSize events at once. The events are claimed for lease while they are published, which
+// has to outlast the time it takes the sinks to publish a whole batch.
+func NewRelay(db *sql.DB, batchSize int, lease time.Duration, sinks ...Sink) (*Relay, error) {
+	if db == nil {
+		return nil, errors.New("db cannot be nil")
+	}
+	if batchSize <= 0 {
+		return nil, errors.New("batch size must be positive")
+	}
+	if lease <= 0 {
+		return nil, errors.New("lease must be positive")
+	}
+	if len(sinks) == 0 {
+		return nil, errors.New("at least one sink is required")
+	}
+	return &Relay{
+		db:        db,
+		batchSize: batchSize,
+		lease:     lease,
+		sinks:     sinks,
+	}, nil
+}
+
+// Relay publishes the events added to the outbox to its sinks.
+type Relay struct {
+	db        *sql.DB
+	batchSize int
+	lease     time.Duration
+	sinks     []Sink
+}
+
+// Publish hands the oldest pending events to every sink and returns how many of them were published. An event
+// which fails to publish holds back the later events of its accounts until it is published, the events of other
+// accounts carry on. The first failure is returned along with the number of events published regardless.
+//
+// The events are claimed and acknowledged in two short transactions, no transaction is held open while the sinks
+// publish them. Events whose relay did not acknowledge them in time, e.g. because it crashed, are published again.
+func (r *Relay) Publish(ctx context.Context) (int, error) {
+	events, claimedUntil, err := r.claim(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not relay outbox events")
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	var failure error
+	blocked := make(map[int64]struct{})
+	publishedIds := make([]int64, 0, len(events))
+	for _, event := range events {
+		if !isBlocked(blocked, event) {
+			err := r.publish(ctx, event)
+			if err == nil {
+				publishedIds = append(publishedIds, event.Id)
+				continue
+			}
+			if failure == nil {
+				failure = errors.Wrapf(err, "could not publish %s event id=%d", event.Type, event.Id)
+			}
+		}
+
+		// An event held back also holds back the later events of all the other accounts it concerns
+		for _, id := range event.AccountIds {
+			blocked[id] = struct{}{}
+		}
+	}
+
+	// The events have been published already, they are acknowledged even if the relay is being stopped
+	if err := r.acknowledge(context.WithoutCancel(ctx), events, publishedIds, claimedUntil); err != nil {
+		return 0, errors.Wrap(err, "could not relay outbox events")
+	}
+
+	return len(publishedIds), failure
+}
+
+// claim takes the oldest pending events until the returned time, it takes none while the events claimed by another
+// relay are still being published, as publishing both batches at once would break the order of events.
+func (r *Relay) claim(ctx context.Context) ([]*Event, time.Time, error) {
+	var events []*Event
+	var claimedUntil time.Time
+	err := database.InTx(ctx, r.db, func(tx *sql.Tx) error {
+		events = nil
+
+		// Relays claiming at the same time would both see no claimed events, they take turns
+		var locked bool
+		if err := tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock(hashtext('outbox'))").Scan(&locked); err != nil {
+			return errors.Wrap(err, "could not acquire outbox lock")
+		}
+		if !locked {
+			return nil
+		}
+
+		var busy bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM outbox WHERE published_at IS NULL AND claimed_until > now())").Scan(&busy); err != nil {
+			return errors.Wrap(err, "could not check for claimed events")
+		}
+		if busy {
+			return nil
+		}
+
+		claimed, err := pending(ctx, tx, r.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(claimed) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, 0, len(claimed))
+		for _, event := range claimed {
+			ids = append(ids, event.Id)
+		}
+		res := tx.QueryRowContext(ctx, "UPDATE outbox SET claimed_until = now() + $2 * INTERVAL '1 millisecond' WHERE id = ANY($1) RETURNING claimed_until", pq.Array(ids), r.lease.Milliseconds())
+		if err := res.Scan(&claimedUntil); err != nil {
+			return errors.Wrap(err, "could not claim events")
+		}
+
+		events = claimed
+		return nil
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return events, claimedUntil, nil
+}
+
+// acknowledge marks the published events as such and releases the others. Events whose claim was taken over by
+// another relay once it lapsed are left to that relay.
+func (r *Relay) acknowledge(ctx context.Context, events []*Event, publishedIds []int64, claimedUntil time.Time) error {
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.Id)
+	}
+
+	_, err := r.db.ExecContext(ctx, `UPDATE outbox SET published_at = CASE WHEN id = ANY($2) THEN now() END, claimed_until = NULL
+WHERE id = ANY($1) AND published_at IS NULL AND claimed_until = $3`, pq.Array(ids), pq.Array(publishedIds), claimedUntil)
+	if err != nil {
+		return errors.Wrap(err, "could not mark events as published")
+	}
+
+	return nil
+}
+
+func (r *Relay) publish(ctx context.Context, event *Event) error {
+	for _, sink := range r.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pending(ctx context.Context, tx *sql.Tx, limit int) ([]*Event, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, type, account_ids, payload, created_at FROM outbox WHERE published_at IS NULL ORDER BY id LIMIT $1", limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "database query failed")
+	}
+	defer rows.Close()
+
+	events := make([]*Event, 0, limit)
+	for rows.Next() {
+		event := &Event{}
+		if err := rows.Scan(&event.Id, &event.Type, pq.Array(&event.AccountIds), &event.Payload, &event.CreatedAt); err != nil {
+			return nil, errors.Wrap(err, "could not scan database query result into struct")
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over database query result")
+	}
+
+	return events, nil
+}
+
+func isBlocked(blocked map[int64]struct{}, event *Event) bool {
+	for _, id := range event.AccountIds {
+		if _, ok := blocked[id]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrSinkRejected = errors.New("sink rejected the event")
+
+func NewWriterSink(w io.Writer) (*WriterSink, error) {
+	if w == nil {
+		return nil, errors.New("writer cannot be nil")
+	}
+	return &WriterSink{w: w}, nil
+}
+
+// WriterSink writes every event as a line of JSON, e.g. to the standard output or to an append-only file.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *WriterSink) Publish(_ context.Context, event *Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrapf(err, "could not encode event id=%d", event.Id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The line is written at once, so that a failed write never leaves half an event followed by another one
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return errors.Wrapf(err, "could not write event id=%d", event.Id)
+	}
+
+	return nil
+}
+
+func NewHttpSink(url string, timeout time.Duration) (*HttpSink, error) {
+	if url == "" {
+		return nil, errors.New("url cannot be empty")
+	}
+	if timeout <= 0 {
+		return nil, errors.New("timeout must be positive")
+	}
+	return &HttpSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// HttpSink posts every event as JSON to a webhook, any response other than 2xx fails the delivery.
+type HttpSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *HttpSink) Publish(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrapf(err, "could not encode event id=%d", event.Id)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "could not create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", strconv.FormatInt(event.Id, 10))
+	req.Header.Set("X-Event-Type", event.Type)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "could not deliver event id=%d", event.Id)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.Wrapf(ErrSinkRejected, "event id=%d, status code=%d", event.Id, res.StatusCode)
+	}
+
+	return nil
+}
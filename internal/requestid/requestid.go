@@ -0,0 +1,31 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header carries the id of a request, it is echoed back in the response.
+const Header = "X-Request-Id"
+
+// MaxLength is the length above which an id sent by a client is replaced by a generated one.
+const MaxLength = 128
+
+type key struct{}
+
+func New() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// From returns the id set by With, or an empty string if none was set.
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
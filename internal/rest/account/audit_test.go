@@ -0,0 +1,204 @@
+package account_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ktsivkov/su-exc/internal/audit"
+	"github.com/ktsivkov/su-exc/internal/requestid"
+)
+
+func TestAuditLog(t *testing.T) {
+	t.Run("state-changing requests are audited with the balances they changed", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 100)
+		targetAccId := insertAccount(t, db, 0)
+		w := postJson(t, db, fmt.Sprintf("/account/%d/transfer", srcAccId), map[string]any{"target": targetAccId, "amount": 40})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		entries := auditEntries(t, db)
+		assert.Len(t, entries, 1)
+		assert.Regexp(t, "^api_key:[0-9]+$", entries[0].Actor)
+		assert.Equal(t, w.Header().Get(requestid.Header), entries[0].RequestId)
+		assert.NotEmpty(t, entries[0].RequestId)
+		assert.Equal(t, "POST", entries[0].Method)
+		assert.Equal(t, "/account/{id:[0-9]+}/transfer", entries[0].Endpoint)
+		assert.Equal(t, fmt.Sprintf("/account/%d/transfer", srcAccId), entries[0].Path)
+		assert.Equal(t, http.StatusOK, entries[0].StatusCode)
+		assert.Equal(t, []auditBalance{{srcAccId, 100, 60}, {targetAccId, 0, 40}}, entries[0].Balances)
+		assert.Empty(t, entries[0].PrevHash)
+	})
+	t.Run("requests are audited with the status they answer with", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 100)
+		targetAccId := insertAccount(t, db, 0)
+		authorizeHold(t, db, srcAccId, targetAccId, 30)
+
+		entries := auditEntries(t, db)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, http.StatusCreated, entries[0].StatusCode)
+		assert.Equal(t, []auditBalance{{srcAccId, 100, 100}, {targetAccId, 0, 0}}, entries[0].Balances)
+	})
+	t.Run("failed requests are audited", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 10)
+		assert.Equal(t, http.StatusBadRequest, postJson(t, db, fmt.Sprintf("/account/%d/withdraw", accId), map[string]any{"amount": 20}).Code)
+
+		entries := auditEntries(t, db)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, http.StatusBadRequest, entries[0].StatusCode)
+		assert.Equal(t, []auditBalance{{accId, 10, 10}}, entries[0].Balances)
+	})
+	t.Run("reads are not audited", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 10)
+		w := sendJson(t, db, "GET", fmt.Sprintf("/account/%d", accId), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, w.Header().Get(requestid.Header))
+
+		assert.Empty(t, auditEntries(t, db))
+	})
+	t.Run("request id sent by the client is kept", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":5}")))
+		r.Header.Set(requestid.Header, "req-42")
+		runApplication(t, db, w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "req-42", w.Header().Get(requestid.Header))
+
+		entries := auditEntries(t, db)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "req-42", entries[0].RequestId)
+	})
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	t.Run("intact", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		writeAuditEntries(t, db)
+
+		report := verifyAuditLog(t, db)
+		assert.Equal(t, 3, report.Checked)
+		assert.True(t, report.Intact())
+	})
+	t.Run("entries cannot be changed", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		writeAuditEntries(t, db)
+
+		_, err := db.Exec("UPDATE audit_log SET status_code = 200 WHERE id = 2")
+		assert.Error(t, err)
+		_, err = db.Exec("DELETE FROM audit_log WHERE id = 2")
+		assert.Error(t, err)
+	})
+	t.Run("changed entry is detected", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		writeAuditEntries(t, db)
+		tamperWithAuditLog(t, db, "UPDATE audit_log SET actor = 'someone' WHERE id = 2")
+
+		report := verifyAuditLog(t, db)
+		assert.False(t, report.Intact())
+		assert.Len(t, report.Problems, 1)
+		assert.Equal(t, int64(2), report.Problems[0].EntryId)
+	})
+	t.Run("removed entry is detected", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		writeAuditEntries(t, db)
+		tamperWithAuditLog(t, db, "DELETE FROM audit_log WHERE id = 2")
+
+		report := verifyAuditLog(t, db)
+		assert.Equal(t, 2, report.Checked)
+		assert.Len(t, report.Problems, 1)
+		assert.Equal(t, int64(3), report.Problems[0].EntryId)
+	})
+}
+
+type auditBalance struct {
+	AccountId int64
+	Before    int64
+	After     int64
+}
+
+type auditEntry struct {
+	*audit.Entry
+	Balances []auditBalance
+}
+
+func auditEntries(t *testing.T, db *sql.DB) []auditEntry {
+	rows, err := db.Query("SELECT actor, request_id, method, endpoint, path, status_code, balances, prev_hash FROM audit_log ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var entries []auditEntry
+	for rows.Next() {
+		entry := auditEntry{Entry: &audit.Entry{}}
+		var balances []map[string]any
+		assert.NoError(t, rows.Scan(&entry.Actor, &entry.RequestId, &entry.Method, &entry.Endpoint, &entry.Path, &entry.StatusCode, jsonScanner{&balances}, &entry.PrevHash))
+		for _, balance := range balances {
+			entry.Balances = append(entry.Balances, auditBalance{
+				AccountId: int64(balance["account_id"].(float64)),
+				Before:    int64(balance["before"].(float64)),
+				After:     int64(balance["after"].(float64)),
+			})
+		}
+		entries = append(entries, entry)
+	}
+	assert.NoError(t, rows.Err())
+	return entries
+}
+
+// writeAuditEntries makes three audited requests.
+func writeAuditEntries(t *testing.T, db *sql.DB) {
+	accId := insertAccount(t, db, 0)
+	assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 100}).Code)
+	assert.Equal(t, http.StatusBadRequest, postJson(t, db, fmt.Sprintf("/account/%d/withdraw", accId), map[string]any{"amount": 200}).Code)
+	assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/withdraw", accId), map[string]any{"amount": 50}).Code)
+}
+
+// tamperWithAuditLog runs query with the trigger keeping the audit log append-only disabled.
+func tamperWithAuditLog(t *testing.T, db *sql.DB, query string) {
+	_, err := db.Exec("ALTER TABLE audit_log DISABLE TRIGGER audit_log_append_only")
+	assert.NoError(t, err)
+	defer func() {
+		_, err := db.Exec("ALTER TABLE audit_log ENABLE TRIGGER audit_log_append_only")
+		assert.NoError(t, err)
+	}()
+
+	_, err = db.Exec(query)
+	assert.NoError(t, err)
+}
+
+func verifyAuditLog(t *testing.T, db *sql.DB) *audit.Report {
+	auditRepo, err := audit.NewRepository(db)
+	assert.NoError(t, err)
+	report, err := auditRepo.Verify(context.Background())
+	assert.NoError(t, err)
+	return report
+}
@@ -0,0 +1,301 @@
+package account_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/apikey"
+)
+
+type apiKeyView struct {
+	Id        int64    `json:"id"`
+	Name      string   `json:"name"`
+	Prefix    string   `json:"prefix"`
+	Scopes    []string `json:"scopes"`
+	Accounts  []int64  `json:"accounts"`
+	RevokedAt *string  `json:"revoked_at"`
+	Key       string   `json:"key"`
+}
+
+func TestAuthentication(t *testing.T) {
+	t.Run("missing key", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		w := sendAs(t, db, "", "GET", fmt.Sprintf("/account/%d", accId), "")
+
+		res := problemResponse(t, w, http.StatusUnauthorized)
+		assert.Equal(t, "unauthenticated", res["code"])
+		assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	})
+	t.Run("unknown key", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		for name, secret := range map[string]string{"foreign": "not-a-key", "guessed": "suk_" + strings.Repeat("0", 64)} {
+			w := sendAs(t, db, secret, "GET", fmt.Sprintf("/account/%d", accId), "")
+
+			res := problemResponse(t, w, http.StatusUnauthorized)
+			assert.Equal(t, "api_key_invalid", res["code"], name)
+			assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"), name)
+		}
+	})
+	t.Run("key in header", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		_, secret := issueApiKey(t, db, nil, apikey.ScopeAccountsRead)
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", fmt.Sprintf("/account/%d", accId), nil)
+		r.Header.Set("X-Api-Key", secret)
+		apiRouter(t, db, slog.New(slog.NewJSONHandler(io.Discard, nil))).ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+	t.Run("rejected requests are audited", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		w := sendAs(t, db, "", "POST", fmt.Sprintf("/account/%d/topup", accId), `{"amount":100}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		entries := auditEntries(t, db)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "anonymous", entries[0].Actor)
+		assert.Equal(t, http.StatusUnauthorized, entries[0].StatusCode)
+		assert.Equal(t, 0, accountBalance(t, db, accId))
+	})
+	t.Run("key is the actor", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 100)
+		key, secret := issueApiKey(t, db, nil, apikey.ScopeTransfersWrite)
+
+		w := sendAs(t, db, secret, "POST", fmt.Sprintf("/account/%d/withdraw", accId), `{"amount":100}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		entries := auditEntries(t, db)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, fmt.Sprintf("api_key:%d", key.Id), entries[0].Actor)
+	})
+}
+
+func TestAuthorization(t *testing.T) {
+	t.Run("scopes", func(t *testing.T) {
+		type testCase struct {
+			scopes         []apikey.Scope
+			method         string
+			path           string
+			body           string
+			expectedStatus int
+		}
+
+		testCases := map[string]testCase{
+			"read with read scope":               {scopes: []apikey.Scope{apikey.ScopeAccountsRead}, method: "GET", path: "/account/1", expectedStatus: http.StatusOK},
+			"read without read scope":            {scopes: []apikey.Scope{apikey.ScopeTransfersWrite}, method: "GET", path: "/account/1", expectedStatus: http.StatusForbidden},
+			"list with read scope":               {scopes: []apikey.Scope{apikey.ScopeAccountsRead}, method: "GET", path: "/accounts", expectedStatus: http.StatusOK},
+			"create with create scope":           {scopes: []apikey.Scope{apikey.ScopeAccountsCreate}, method: "POST", path: "/accounts", expectedStatus: http.StatusCreated},
+			"create with read scope":             {scopes: []apikey.Scope{apikey.ScopeAccountsRead}, method: "POST", path: "/accounts", expectedStatus: http.StatusForbidden},
+			"transfer with transfer scope":       {scopes: []apikey.Scope{apikey.ScopeTransfersWrite}, method: "POST", path: "/account/1/transfer", body: `{"target":2,"amount":10}`, expectedStatus: http.StatusOK},
+			"transfer with read scope":           {scopes: []apikey.Scope{apikey.ScopeAccountsRead}, method: "POST", path: "/account/1/transfer", body: `{"target":2,"amount":10}`, expectedStatus: http.StatusForbidden},
+			"topup with transfer scope":          {scopes: []apikey.Scope{apikey.ScopeTransfersWrite}, method: "POST", path: "/account/1/topup", body: `{"amount":10}`, expectedStatus: http.StatusForbidden},
+			"topup with admin scope":             {scopes: []apikey.Scope{apikey.ScopeAdmin}, method: "POST", path: "/account/1/topup", body: `{"amount":10}`, expectedStatus: http.StatusOK},
+			"admin endpoint with transfer scope": {scopes: []apikey.Scope{apikey.ScopeTransfersWrite}, method: "POST", path: "/admin/accounts/1/freeze", expectedStatus: http.StatusForbidden},
+			"admin endpoint with admin scope":    {scopes: []apikey.Scope{apikey.ScopeAdmin}, method: "POST", path: "/admin/accounts/1/freeze", expectedStatus: http.StatusOK},
+			"admin scope grants every scope":     {scopes: []apikey.Scope{apikey.ScopeAdmin}, method: "POST", path: "/account/1/transfer", body: `{"target":2,"amount":10}`, expectedStatus: http.StatusOK},
+			"webhooks with transfer scope":       {scopes: []apikey.Scope{apikey.ScopeTransfersWrite}, method: "POST", path: "/webhooks", body: `{"url":"https://example.com","event_types":["TransferCompleted"]}`, expectedStatus: http.StatusForbidden},
+		}
+
+		for testName, test := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				db, onClose := setupDb(t)
+				defer onClose()
+
+				insertAccount(t, db, 100)
+				insertAccount(t, db, 0)
+				_, secret := issueApiKey(t, db, nil, test.scopes...)
+
+				w := sendAs(t, db, secret, test.method, test.path, test.body)
+				assert.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+				if test.expectedStatus == http.StatusForbidden {
+					assert.Equal(t, "scope_missing", problemResponse(t, w, http.StatusForbidden)["code"])
+				}
+			})
+		}
+	})
+	t.Run("accounts", func(t *testing.T) {
+		type testCase struct {
+			method         string
+			path           string
+			body           string
+			expectedStatus int
+		}
+
+		testCases := map[string]testCase{
+			"read own account":             {method: "GET", path: "/account/1", expectedStatus: http.StatusOK},
+			"read other account":           {method: "GET", path: "/account/2", expectedStatus: http.StatusForbidden},
+			"transfer from own account":    {method: "POST", path: "/account/1/transfer", body: `{"target":2,"amount":10}`, expectedStatus: http.StatusOK},
+			"transfer from other account":  {method: "POST", path: "/account/2/transfer", body: `{"target":1,"amount":10}`, expectedStatus: http.StatusForbidden},
+			"list every account":           {method: "GET", path: "/accounts", expectedStatus: http.StatusForbidden},
+			"reverse a transfer":           {method: "POST", path: "/transfers/1/reverse", body: `{}`, expectedStatus: http.StatusForbidden},
+			"quote not tied to an account": {method: "POST", path: "/fx/quotes", body: `{"source_currency":"EUR","target_currency":"USD"}`, expectedStatus: http.StatusCreated},
+		}
+
+		for testName, test := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				db, onClose := setupDb(t)
+				defer onClose()
+
+				own := insertAccount(t, db, 100)
+				insertAccount(t, db, 100)
+				_, secret := issueApiKey(t, db, []int64{own}, apikey.ScopeAccountsRead, apikey.ScopeTransfersWrite)
+
+				w := sendAs(t, db, secret, test.method, test.path, test.body)
+				assert.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+				if test.expectedStatus == http.StatusForbidden {
+					assert.Equal(t, "account_forbidden", problemResponse(t, w, http.StatusForbidden)["code"])
+				}
+			})
+		}
+	})
+	t.Run("top-ups of restricted keys", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		own := insertAccount(t, db, 0)
+		_, transferSecret := issueApiKey(t, db, []int64{own}, apikey.ScopeTransfersWrite)
+		_, adminSecret := issueApiKey(t, db, []int64{own}, apikey.ScopeAdmin)
+
+		w := sendAs(t, db, transferSecret, "POST", fmt.Sprintf("/account/%d/topup", own), `{"amount":100}`)
+		assert.Equal(t, "scope_missing", problemResponse(t, w, http.StatusForbidden)["code"])
+
+		w = sendAs(t, db, adminSecret, "POST", fmt.Sprintf("/account/%d/topup", own), `{"amount":100}`)
+		assert.Equal(t, "account_forbidden", problemResponse(t, w, http.StatusForbidden)["code"])
+		assert.Equal(t, 0, accountBalance(t, db, own))
+	})
+}
+
+func TestApiKeys(t *testing.T) {
+	t.Run("issue", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		w := postJson(t, db, "/admin/api-keys", map[string]any{
+			"name":     "checkout",
+			"scopes":   []string{"transfers:write", "accounts:read", "transfers:write"},
+			"accounts": []int64{accId},
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var issued apiKeyView
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&issued))
+		assert.Equal(t, "checkout", issued.Name)
+		assert.Equal(t, []string{"accounts:read", "transfers:write"}, issued.Scopes)
+		assert.Equal(t, []int64{accId}, issued.Accounts)
+		assert.True(t, strings.HasPrefix(issued.Key, issued.Prefix))
+		assert.Nil(t, issued.RevokedAt)
+
+		// Only the hash of the key is stored
+		var stored int
+		assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM api_keys WHERE key_hash = $1 OR prefix = $1", issued.Key).Scan(&stored))
+		assert.Equal(t, 0, stored)
+
+		w = sendAs(t, db, issued.Key, "GET", fmt.Sprintf("/account/%d", accId), "")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+	t.Run("issue bad request", func(t *testing.T) {
+		type testCase struct {
+			body         map[string]any
+			expectedCode string
+		}
+
+		testCases := map[string]testCase{
+			"missing name":     {body: map[string]any{"scopes": []string{"admin"}}, expectedCode: "name_not_set"},
+			"missing scopes":   {body: map[string]any{"name": "ci"}, expectedCode: "scopes_not_set"},
+			"unknown scope":    {body: map[string]any{"name": "ci", "scopes": []string{"root"}}, expectedCode: "unknown_scope"},
+			"invalid accounts": {body: map[string]any{"name": "ci", "scopes": []string{"admin"}, "accounts": []int{0}}, expectedCode: "account_not_positive"},
+		}
+
+		for testName, test := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				db, onClose := setupDb(t)
+				defer onClose()
+
+				w := postJson(t, db, "/admin/api-keys", test.body)
+				assert.Equal(t, test.expectedCode, problemResponse(t, w, http.StatusUnprocessableEntity)["code"])
+			})
+		}
+	})
+	t.Run("revoke", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		key, secret := issueApiKey(t, db, nil, apikey.ScopeAccountsRead)
+
+		w := postJson(t, db, fmt.Sprintf("/admin/api-keys/%d/revoke", key.Id), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var revoked apiKeyView
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&revoked))
+		assert.Equal(t, key.Id, revoked.Id)
+		assert.NotNil(t, revoked.RevokedAt)
+
+		w = sendAs(t, db, secret, "GET", fmt.Sprintf("/account/%d", accId), "")
+		assert.Equal(t, "api_key_revoked", problemResponse(t, w, http.StatusUnauthorized)["code"])
+
+		w = postJson(t, db, fmt.Sprintf("/admin/api-keys/%d/revoke", key.Id), nil)
+		assert.Equal(t, "api_key_already_revoked", problemResponse(t, w, http.StatusConflict)["code"])
+
+		w = postJson(t, db, "/admin/api-keys/999/revoke", nil)
+		assert.Equal(t, "api_key_not_found", problemResponse(t, w, http.StatusNotFound)["code"])
+	})
+}
+
+func apiKeyRepository(t *testing.T, db *sql.DB) *apikey.Repository {
+	repo, err := apikey.NewRepository(db)
+	assert.NoError(t, err)
+	return repo
+}
+
+// issueApiKey issues a key with the given scopes, restricted to accountIds unless it is nil, and returns it along
+// with its secret.
+func issueApiKey(t *testing.T, db *sql.DB, accountIds []int64, scopes ...apikey.Scope) (*apikey.Key, string) {
+	key, secret, err := apiKeyRepository(t, db).Issue(context.Background(), "test", scopes, accountIds)
+	assert.NoError(t, err)
+	return key, secret
+}
+
+// sendAs sends a request authenticated by secret, or without any key at all when it is empty.
+func sendAs(t *testing.T, db *sql.DB, secret string, method string, path string, body string) *httptest.ResponseRecorder {
+	var reqBody io.Reader = http.NoBody
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(method, path, reqBody)
+	r.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		r.Header.Set("Authorization", "Bearer "+secret)
+	}
+	apiRouter(t, db, slog.New(slog.NewJSONHandler(io.Discard, nil))).ServeHTTP(w, r)
+
+	return w
+}
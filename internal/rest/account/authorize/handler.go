@@ -0,0 +1,113 @@
+package authorize
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/limits"
+	"github.com/ktsivkov/su-exc/internal/rest/hold/view"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, finder account.Finder, authorizer account.Authorizer, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		sourceAccount, err := finder.FindById(ctx, req.Source)
+		if err != nil {
+			if errors.Is(err, account.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "source account does not exist", "id", req.Source)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("source account with id=%d does not exist", req.Source)), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "source account existence check failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		targetAccount, err := finder.FindById(ctx, req.Data.Target)
+		if err != nil {
+			if errors.Is(err, account.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "target account does not exist", "id", req.Data.Target)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("target account with id=%d does not exist", req.Data.Target)), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "target account existence check failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		amount, err := req.Data.Amount.In(sourceAccount.Currency)
+		if err != nil {
+			logger.WarnContext(ctx, "invalid request amount", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		hold, err := authorizer.Authorize(ctx, sourceAccount, targetAccount, amount)
+		if err != nil {
+			var violation *limits.Violation
+			if errors.As(err, &violation) {
+				logger.WarnContext(ctx, "transfer limit exceeded", "error", err)
+				// Too many transfers can simply be retried once the period resets, too much money cannot be sent as is
+				status := http.StatusUnprocessableEntity
+				if violation.Rule.Metric == limits.MetricCount {
+					status = http.StatusTooManyRequests
+					w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(time.Until(violation.ResetsAt).Seconds())))))
+				}
+				problem.Write(w, r, problem.FromError(status, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrInsufficientBalance) {
+				problem.Write(w, r, problem.FromError(http.StatusBadRequest, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrAccountFrozen) || errors.Is(err, account.ErrAccountClosed) {
+				logger.WarnContext(ctx, "account is not active", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrCurrencyMismatch) {
+				logger.WarnContext(ctx, "hold currency mismatch", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "hold authorization failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(view.NewHold(hold)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
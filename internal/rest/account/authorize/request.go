@@ -0,0 +1,43 @@
+package authorize
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestInvalidAmountLt0 = problem.Field("amount", "amount_not_positive", "amount must be positive")
+
+type Request struct {
+	Source int64
+	Data   *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	// Target is the account the held amount is transferred to once the hold is captured.
+	Target int64 `json:"target"`
+	// Amount is in the currency of the source account, either as an integer number of minor units or as a decimal string.
+	Amount money.Amount `json:"amount"`
+}
+
+func (d *RequestData) Validate() error {
+	if !d.Amount.IsPositive() {
+		return ErrRequestInvalidAmountLt0
+	}
+
+	return nil
+}
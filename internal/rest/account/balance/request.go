@@ -0,0 +1,27 @@
+package balance
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestAsOfNotSet = problem.Field("as_of", "as_of_not_set", "as_of is mandatory")
+var ErrRequestAsOfInFuture = problem.Field("as_of", "as_of_in_future", "as_of cannot be in the future")
+
+type Request struct {
+	Account int64
+	AsOf    time.Time
+}
+
+func (r *Request) Validate() error {
+	if r.AsOf.IsZero() {
+		return ErrRequestAsOfNotSet
+	}
+
+	if r.AsOf.After(time.Now()) {
+		return ErrRequestAsOfInFuture
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package balance
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+type RequestParser func(ctx context.Context, r *http.Request) (*Request, error)
+
+func GetRequestParser() RequestParser {
+	return func(ctx context.Context, r *http.Request) (*Request, error) {
+		variables := mux.Vars(r)
+		id, err := strconv.ParseInt(variables["id"], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot parse account id")
+		}
+
+		req := &Request{
+			Account: id,
+		}
+
+		if asOf := r.URL.Query().Get("as_of"); asOf != "" {
+			if req.AsOf, err = time.Parse(time.RFC3339, asOf); err != nil {
+				return nil, errors.Wrap(err, "cannot parse as_of, expected RFC3339 timestamp")
+			}
+		}
+
+		return req, nil
+	}
+}
@@ -0,0 +1,24 @@
+package balance
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+type Response struct {
+	AccountId int64         `json:"account_id"`
+	AsOf      time.Time     `json:"as_of"`
+	Balance   money.Amount  `json:"balance"`
+	Currency  currency.Code `json:"currency"`
+}
+
+func NewResponse(accountId int64, asOf time.Time, balance money.Amount) *Response {
+	return &Response{
+		AccountId: accountId,
+		AsOf:      asOf,
+		Balance:   balance,
+		Currency:  balance.Currency(),
+	}
+}
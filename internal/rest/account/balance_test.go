@@ -0,0 +1,175 @@
+package account_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+)
+
+func TestBalanceAsOf(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		beforeTopUp := databaseNow(t, db)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 100}).Code)
+		afterTopUp := databaseNow(t, db)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/withdraw", accId), map[string]any{"amount": 30}).Code)
+		afterWithdrawal := databaseNow(t, db)
+
+		assert.Equal(t, float64(0), balanceAsOf(t, db, accId, beforeTopUp)["balance"])
+		assert.Equal(t, float64(100), balanceAsOf(t, db, accId, afterTopUp)["balance"])
+		res := balanceAsOf(t, db, accId, afterWithdrawal)
+		assert.Equal(t, float64(accId), res["account_id"])
+		assert.Equal(t, float64(70), res["balance"])
+		assert.Equal(t, "EUR", res["currency"])
+	})
+	t.Run("transfers count for both accounts", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 0)
+		targetAccId := insertAccount(t, db, 0)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", srcAccId), map[string]any{"amount": 100}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", srcAccId), map[string]any{"target": targetAccId, "amount": 40}).Code)
+		now := databaseNow(t, db)
+
+		assert.Equal(t, float64(60), balanceAsOf(t, db, srcAccId, now)["balance"])
+		assert.Equal(t, float64(40), balanceAsOf(t, db, targetAccId, now)["balance"])
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("as_of not set", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, sendJson(t, db, "GET", fmt.Sprintf("/account/%d/balance", accId), nil).Code)
+		})
+		t.Run("as_of malformed", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			assert.Equal(t, http.StatusBadRequest, sendJson(t, db, "GET", fmt.Sprintf("/account/%d/balance?as_of=yesterday", accId), nil).Code)
+		})
+		t.Run("as_of in the future", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, sendJson(t, db, "GET", balanceAsOfPath(accId, time.Now().Add(time.Hour)), nil).Code)
+		})
+		t.Run("account does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			assert.Equal(t, http.StatusNotFound, sendJson(t, db, "GET", balanceAsOfPath(1, time.Now()), nil).Code)
+		})
+	})
+}
+
+func TestSnapshotBalances(t *testing.T) {
+	t.Run("snapshot is taken for accounts with postings since the previous one", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accountRepo := accountRepository(t, db)
+		movedAccId := insertAccount(t, db, 0)
+		idleAccId := insertAccount(t, db, 0)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", movedAccId), map[string]any{"amount": 100}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", idleAccId), map[string]any{"amount": 10}).Code)
+
+		first := databaseNow(t, db)
+		snapshots, err := accountRepo.SnapshotBalances(context.Background(), first)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, snapshots)
+
+		// Taking the same snapshot again does nothing
+		snapshots, err = accountRepo.SnapshotBalances(context.Background(), first)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, snapshots)
+
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", movedAccId), map[string]any{"amount": 50}).Code)
+		second := databaseNow(t, db)
+		snapshots, err = accountRepo.SnapshotBalances(context.Background(), second)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, snapshots)
+
+		assert.Equal(t, map[time.Time]int64{first: 100, second: 150}, balanceSnapshots(t, db, movedAccId))
+		assert.Equal(t, map[time.Time]int64{first: 10}, balanceSnapshots(t, db, idleAccId))
+	})
+	t.Run("balance is computed from the latest snapshot", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accountRepo := accountRepository(t, db)
+		accId := insertAccount(t, db, 0)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 100}).Code)
+		snapshotAt := databaseNow(t, db)
+		_, err := accountRepo.SnapshotBalances(context.Background(), snapshotAt)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 50}).Code)
+
+		// The postings before the snapshot are not summed up again, a snapshot off by 1000 shows it was used
+		_, err = db.Exec("UPDATE balance_snapshots SET balance = balance + 1000 WHERE account_id = $1", accId)
+		assert.NoError(t, err)
+
+		assert.Equal(t, float64(1100), balanceAsOf(t, db, accId, snapshotAt)["balance"])
+		assert.Equal(t, float64(1150), balanceAsOf(t, db, accId, databaseNow(t, db))["balance"])
+	})
+}
+
+func accountRepository(t *testing.T, db *sql.DB) *account.Repository {
+	repo, err := account.NewRepository(db, time.Hour)
+	assert.NoError(t, err)
+	return repo
+}
+
+// databaseNow returns the current time of the database, which dates the postings.
+func databaseNow(t *testing.T, db *sql.DB) time.Time {
+	var now time.Time
+	assert.NoError(t, db.QueryRow("SELECT clock_timestamp()").Scan(&now))
+	return now.UTC()
+}
+
+func balanceAsOfPath(accId int64, asOf time.Time) string {
+	return fmt.Sprintf("/account/%d/balance?as_of=%s", accId, url.QueryEscape(asOf.Format(time.RFC3339Nano)))
+}
+
+func balanceAsOf(t *testing.T, db *sql.DB, accId int64, asOf time.Time) map[string]any {
+	w := sendJson(t, db, "GET", balanceAsOfPath(accId, asOf), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var res map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return res
+}
+
+func balanceSnapshots(t *testing.T, db *sql.DB, accId int64) map[time.Time]int64 {
+	rows, err := db.Query("SELECT as_of, balance FROM balance_snapshots WHERE account_id = $1", accId)
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	snapshots := make(map[time.Time]int64)
+	for rows.Next() {
+		var asOf time.Time
+		var balance int64
+		assert.NoError(t, rows.Scan(&asOf, &balance))
+		snapshots[asOf.UTC()] = balance
+	}
+	assert.NoError(t, rows.Err())
+	return snapshots
+}
@@ -0,0 +1,115 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/limits"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, finder account.Finder, transferrer account.BatchTransferrer, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		sourceAccount, err := finder.FindById(ctx, req.Source)
+		if err != nil {
+			if errors.Is(err, account.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "source account does not exist", "id", req.Source)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("source account with id=%d does not exist", req.Source)), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "source account existence check failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		items := make([]account.BatchItem, 0, len(req.Data.Transfers))
+		for i, item := range req.Data.Transfers {
+			amount, err := item.Amount.In(sourceAccount.Currency)
+			if err != nil {
+				logger.WarnContext(ctx, "invalid request amount", "error", err)
+				problem.Write(w, r, problem.Invalid(errors.Wrapf(err, "transfer #%d", i)), logger)
+				return
+			}
+			items = append(items, account.BatchItem{TargetId: item.Target, Amount: amount})
+		}
+
+		mode := req.Data.Mode
+		if mode == "" {
+			mode = account.BatchModeAllOrNothing
+		}
+
+		results, err := transferrer.TransferBatch(ctx, sourceAccount, items, mode)
+		if err != nil {
+			var violation *limits.Violation
+			if errors.As(err, &violation) {
+				logger.WarnContext(ctx, "transfer limit exceeded", "error", err)
+				// Too many transfers can simply be retried once the period resets, too much money cannot be sent as is
+				status := http.StatusUnprocessableEntity
+				if violation.Rule.Metric == limits.MetricCount {
+					status = http.StatusTooManyRequests
+					w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(time.Until(violation.ResetsAt).Seconds())))))
+				}
+				problem.Write(w, r, problem.FromError(status, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrInsufficientBalance) {
+				problem.Write(w, r, problem.FromError(http.StatusBadRequest, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "target account does not exist", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusNotFound, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrAccountFrozen) || errors.Is(err, account.ErrAccountClosed) || errors.Is(err, account.ErrCurrencyMismatch) {
+				logger.WarnContext(ctx, "batch transfer rejected", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			if errors.Is(err, money.ErrOverflow) {
+				logger.WarnContext(ctx, "batch total out of range", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusUnprocessableEntity, err), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "batch transfer failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(NewResponse(mode, results)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package batch
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+// maxTransfers caps the size of a batch, larger payrolls have to be split into several batches.
+const maxTransfers = 1000
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestTransfersNotSet = problem.Field("transfers", "transfers_not_set", "transfers are mandatory")
+var ErrRequestTooManyTransfers = problem.Field("transfers", "too_many_transfers", fmt.Sprintf("a batch holds at most %d transfers", maxTransfers))
+var ErrRequestInvalidMode = problem.Field("mode", "invalid_mode", "mode must be either all_or_nothing or best_effort")
+var ErrRequestInvalidAmountLt0 = problem.Field("amount", "amount_not_positive", "amount must be positive")
+
+type Request struct {
+	Source int64
+	Data   *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	// Mode is optional, batches are all or nothing unless told otherwise.
+	Mode      account.BatchMode `json:"mode"`
+	Transfers []Item            `json:"transfers"`
+}
+
+type Item struct {
+	Target int64 `json:"target"`
+	// Amount is in the currency of the source account, either as an integer number of minor units or as a decimal string.
+	Amount money.Amount `json:"amount"`
+}
+
+func (d *RequestData) Validate() error {
+	if d.Mode != "" && !d.Mode.IsValid() {
+		return errors.Wrapf(ErrRequestInvalidMode, "mode=%q", d.Mode)
+	}
+
+	if len(d.Transfers) == 0 {
+		return ErrRequestTransfersNotSet
+	}
+
+	if len(d.Transfers) > maxTransfers {
+		return ErrRequestTooManyTransfers
+	}
+
+	for i, item := range d.Transfers {
+		if !item.Amount.IsPositive() {
+			return errors.Wrapf(ErrRequestInvalidAmountLt0.At(fmt.Sprintf("transfers[%d].amount", i)), "transfer #%d", i)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package batch
+
+import (
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+const (
+	ResultStatusSucceeded = "succeeded"
+	ResultStatusFailed    = "failed"
+)
+
+type Response struct {
+	Mode      account.BatchMode `json:"mode"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	// Results are in the order of the transfers of the request.
+	Results []*Result `json:"results"`
+}
+
+type Result struct {
+	Target     int64         `json:"target"`
+	Amount     money.Amount  `json:"amount"`
+	Currency   currency.Code `json:"currency"`
+	Status     string        `json:"status"`
+	TransferId *int64        `json:"transfer_id,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+func NewResponse(mode account.BatchMode, results []*account.BatchResult) *Response {
+	res := &Response{
+		Mode:    mode,
+		Results: make([]*Result, 0, len(results)),
+	}
+	for _, result := range results {
+		item := &Result{
+			Target:     result.TargetId,
+			Amount:     result.Amount,
+			Currency:   result.Amount.Currency(),
+			Status:     ResultStatusSucceeded,
+			TransferId: result.TransferId,
+		}
+		if result.Err != nil {
+			item.Status, item.Error = ResultStatusFailed, result.Err.Error()
+			res.Failed++
+		} else {
+			res.Succeeded++
+		}
+		res.Results = append(res.Results, item)
+	}
+	return res
+}
@@ -0,0 +1,166 @@
+package account_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type batchResponse struct {
+	Mode      string `json:"mode"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	Results   []struct {
+		Target     int64  `json:"target"`
+		Amount     int    `json:"amount"`
+		Status     string `json:"status"`
+		TransferId *int64 `json:"transfer_id"`
+		Error      string `json:"error"`
+	} `json:"results"`
+}
+
+func TestBatchTransfer(t *testing.T) {
+	t.Run("all or nothing", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 1000)
+		firstId := insertAccount(t, db, 0)
+		secondId := insertAccount(t, db, 0)
+
+		w := postJson(t, db, fmt.Sprintf("/account/%d/transfers:batch", accId), map[string]any{"transfers": []map[string]any{
+			{"target": firstId, "amount": 300},
+			{"target": secondId, "amount": "2.00"},
+		}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res batchResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+		assert.Equal(t, "all_or_nothing", res.Mode)
+		assert.Equal(t, 2, res.Succeeded)
+		assert.Equal(t, 0, res.Failed)
+		assert.Len(t, res.Results, 2)
+		assert.Equal(t, firstId, res.Results[0].Target)
+		assert.NotNil(t, res.Results[0].TransferId)
+		assert.Equal(t, 200, res.Results[1].Amount)
+		assert.Equal(t, 500, accountBalance(t, db, accId))
+		assert.Equal(t, 300, accountBalance(t, db, firstId))
+		assert.Equal(t, 200, accountBalance(t, db, secondId))
+	})
+	t.Run("all or nothing rolls back on a failing transfer", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 1000)
+		firstId := insertAccount(t, db, 0)
+		frozenId := insertAccount(t, db, 0)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/freeze", frozenId), nil).Code)
+
+		w := postJson(t, db, fmt.Sprintf("/account/%d/transfers:batch", accId), map[string]any{"transfers": []map[string]any{
+			{"target": firstId, "amount": 300},
+			{"target": frozenId, "amount": 200},
+		}})
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "transfer #1")
+		assert.Equal(t, 1000, accountBalance(t, db, accId))
+		assert.Equal(t, 0, accountBalance(t, db, firstId))
+	})
+	t.Run("best effort", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 1000)
+		firstId := insertAccount(t, db, 0)
+		frozenId := insertAccount(t, db, 0)
+		usdId := insertCurrencyAccount(t, db, 0, "USD")
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/freeze", frozenId), nil).Code)
+
+		w := postJson(t, db, fmt.Sprintf("/account/%d/transfers:batch", accId), map[string]any{"mode": "best_effort", "transfers": []map[string]any{
+			{"target": firstId, "amount": 300},
+			{"target": frozenId, "amount": 200},
+			{"target": usdId + 1, "amount": 100},
+			{"target": usdId, "amount": 100},
+			{"target": firstId, "amount": 50},
+		}})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res batchResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+		assert.Equal(t, 2, res.Succeeded)
+		assert.Equal(t, 3, res.Failed)
+		assert.Equal(t, []string{"succeeded", "failed", "failed", "failed", "succeeded"}, []string{res.Results[0].Status, res.Results[1].Status, res.Results[2].Status, res.Results[3].Status, res.Results[4].Status})
+		assert.Contains(t, res.Results[1].Error, "account is frozen")
+		assert.Contains(t, res.Results[2].Error, "account not found")
+		assert.Contains(t, res.Results[3].Error, "currency mismatch")
+		assert.Nil(t, res.Results[1].TransferId)
+		assert.Equal(t, 650, accountBalance(t, db, accId))
+		assert.Equal(t, 350, accountBalance(t, db, firstId))
+		assert.Equal(t, 350, postingsTotal(t, db, firstId))
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("total exceeds the balance up front", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 1000)
+			firstId := insertAccount(t, db, 0)
+
+			w := postJson(t, db, fmt.Sprintf("/account/%d/transfers:batch", accId), map[string]any{"mode": "best_effort", "transfers": []map[string]any{
+				{"target": firstId, "amount": 600},
+				{"target": firstId, "amount": 401},
+			}})
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, 1000, accountBalance(t, db, accId))
+			assert.Equal(t, 0, accountBalance(t, db, firstId))
+		})
+		t.Run("no transfers", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 1000)
+
+			w := postJson(t, db, fmt.Sprintf("/account/%d/transfers:batch", accId), map[string]any{"transfers": []map[string]any{}})
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+		t.Run("unknown mode", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 1000)
+			firstId := insertAccount(t, db, 0)
+
+			w := postJson(t, db, fmt.Sprintf("/account/%d/transfers:batch", accId), map[string]any{"mode": "some", "transfers": []map[string]any{{"target": firstId, "amount": 1}}})
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+		t.Run("negative amount", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 1000)
+			firstId := insertAccount(t, db, 0)
+
+			w := postJson(t, db, fmt.Sprintf("/account/%d/transfers:batch", accId), map[string]any{"transfers": []map[string]any{
+				{"target": firstId, "amount": 1},
+				{"target": firstId, "amount": -1},
+			}})
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			assert.Contains(t, w.Body.String(), "transfer #1")
+		})
+		t.Run("source account does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			w := postJson(t, db, "/account/1/transfers:batch", map[string]any{"transfers": []map[string]any{{"target": 2, "amount": 1}}})
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	})
+}
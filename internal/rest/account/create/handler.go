@@ -1,25 +1,70 @@
 package create
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 
+	"github.com/pkg/errors"
+
 	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/customer"
+	"github.com/ktsivkov/su-exc/internal/jwt"
+	"github.com/ktsivkov/su-exc/internal/rest/account/view"
+	"github.com/ktsivkov/su-exc/internal/rest/negotiate"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
 )
 
-func Handler(creator account.Creator, logger *slog.Logger) http.HandlerFunc {
-	return func(w http.ResponseWriter, req *http.Request) {
-		ctx := req.Context()
-		id, err := creator.Create(ctx)
+func Handler(requestParser RequestParser, creator account.Creator, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
 		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		// Accounts created by a user are owned by them, only they may move money out of them
+		var owner string
+		if user := jwt.From(ctx); user != nil {
+			owner = user.Subject
+		}
+
+		created, err := creator.Create(ctx, req.Data.Currency, owner, req.Data.CustomerId)
+		if err != nil {
+			if errors.Is(err, customer.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "customer id not found", "id", *req.Data.CustomerId)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeCustomerNotFound, fmt.Sprintf("customer with id=%d does not exist", *req.Data.CustomerId)), logger)
+				return
+			}
+
 			logger.Error("account creation failed", "error", err)
-			w.WriteHeader(http.StatusInternalServerError)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/account/%d", created.Id))
+		if negotiate.Legacy(r) {
+			w.Header().Set("Content-Type", negotiate.Text)
+			w.WriteHeader(http.StatusCreated)
+			if _, err := fmt.Fprintf(w, "%d", created.Id); err != nil {
+				logger.Error("could not write bytes to client", "error", err)
+			}
 			return
 		}
 
+		w.Header().Set("Content-Type", negotiate.JSON)
 		w.WriteHeader(http.StatusCreated)
-		if _, err := fmt.Fprintf(w, "%d", id); err != nil {
+		if err := json.NewEncoder(w).Encode(view.NewAccount(created)); err != nil {
 			logger.Error("could not write bytes to client", "error", err)
 		}
 	}
@@ -0,0 +1,45 @@
+package create
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestInvalidCustomerId = problem.Field("customer_id", "invalid_customer_id", "customer_id must be a positive integer")
+
+type Request struct {
+	Data *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	Currency currency.Code `json:"currency"`
+	// CustomerId links the account to a customer, if set.
+	CustomerId *int64 `json:"customer_id"`
+}
+
+func (d *RequestData) Validate() error {
+	if _, err := currency.Parse(string(d.Currency)); err != nil {
+		return err
+	}
+
+	if d.CustomerId != nil && *d.CustomerId <= 0 {
+		return ErrRequestInvalidCustomerId
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package create
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+)
+
+type RequestParser func(ctx context.Context, r *http.Request) (*Request, error)
+
+func GetRequestParser() RequestParser {
+	return func(ctx context.Context, r *http.Request) (*Request, error) {
+		req := &Request{
+			Data: &RequestData{},
+		}
+
+		// Clients predating multi-currency accounts send no body at all
+		if r.Body == nil || r.Body == http.NoBody {
+			req.Data.Currency = currency.Default
+			return req, nil
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(req.Data); err != nil {
+			return nil, errors.Wrap(err, "request body not a valid json")
+		}
+
+		return req, nil
+	}
+}
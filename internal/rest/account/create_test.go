@@ -1,11 +1,16 @@
 package account_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/rest/account/view"
 )
 
 func TestCreate(t *testing.T) {
@@ -21,7 +26,101 @@ func TestCreate(t *testing.T) {
 		runApplication(t, db, w, r)
 
 		assert.Equal(t, http.StatusCreated, w.Code)
-		row := db.QueryRow("SELECT id FROM accounts WHERE id=$1", w.Body.String())
-		assert.NoError(t, row.Err())
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var created view.Account
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+		assert.Equal(t, fmt.Sprintf("/account/%d", created.Id), w.Header().Get("Location"))
+		assert.Equal(t, "EUR", string(created.Currency))
+		assert.Equal(t, "active", string(created.Status))
+		assert.True(t, created.Balance.IsZero())
+
+		row := db.QueryRow("SELECT id FROM accounts WHERE id=$1", created.Id)
+		assert.NoError(t, row.Scan(&created.Id))
+	})
+	t.Run("create account in currency", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest("POST", "/accounts", bytes.NewBuffer([]byte("{\"currency\":\"JPY\"}")))
+		assert.NoError(t, err)
+		r.Header.Set("Content-Type", "application/json")
+
+		runApplication(t, db, w, r)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var created view.Account
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+		assert.Equal(t, "JPY", string(created.Currency))
+
+		row := db.QueryRow("SELECT currency FROM accounts WHERE id=$1", created.Id)
+		var currency string
+		assert.NoError(t, row.Scan(&currency))
+		assert.Equal(t, "JPY", currency)
+	})
+	t.Run("create account as plain text", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest("POST", "/accounts", nil)
+		assert.NoError(t, err)
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept", "text/plain")
+
+		runApplication(t, db, w, r)
+
+		// Old clients read the bare id of the created account
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+		id := w.Body.String()
+		assert.Equal(t, "/account/"+id, w.Header().Get("Location"))
+		row := db.QueryRow("SELECT id FROM accounts WHERE id=$1", id)
+		assert.NoError(t, row.Scan(new(int64)))
+	})
+	t.Run("fail", func(t *testing.T) {
+		type testCase struct {
+			body               []byte
+			expectedStatusCode int
+		}
+
+		testCases := map[string]testCase{
+			"invalid json request body": {
+				body:               []byte("{\"}"),
+				expectedStatusCode: http.StatusBadRequest,
+			},
+			"unknown currency": {
+				body:               []byte("{\"currency\":\"XYZ\"}"),
+				expectedStatusCode: http.StatusUnprocessableEntity,
+			},
+			"lowercase currency": {
+				body:               []byte("{\"currency\":\"eur\"}"),
+				expectedStatusCode: http.StatusUnprocessableEntity,
+			},
+			"missing currency": {
+				body:               []byte("{}"),
+				expectedStatusCode: http.StatusUnprocessableEntity,
+			},
+		}
+
+		for testName, test := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				db, onClose := setupDb(t)
+				defer onClose()
+
+				w := httptest.NewRecorder()
+				r, _ := http.NewRequest("POST", "/accounts", bytes.NewBuffer(test.body))
+				r.Header.Set("Content-Type", "application/json")
+
+				runApplication(t, db, w, r)
+				assert.Equal(t, test.expectedStatusCode, w.Code)
+
+				var accounts int
+				assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM accounts").Scan(&accounts))
+				assert.Equal(t, 0, accounts)
+			})
+		}
 	})
 }
@@ -0,0 +1,258 @@
+package account_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/customer"
+)
+
+type customerView struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	ExternalRef string `json:"external_ref"`
+	KycTier     string `json:"kyc_tier"`
+}
+
+type customerAccountsView struct {
+	Customer customerView `json:"customer"`
+	Accounts []struct {
+		Id         int64  `json:"id"`
+		CustomerId *int64 `json:"customer_id"`
+	} `json:"accounts"`
+	Balances []struct {
+		Currency  string `json:"currency"`
+		Accounts  int    `json:"accounts"`
+		Balance   int64  `json:"balance"`
+		Available int64  `json:"available_balance"`
+	} `json:"balances"`
+	NextCursor string `json:"next_cursor"`
+}
+
+func TestCustomers(t *testing.T) {
+	t.Run("create, find and update", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		w := postJson(t, db, "/customers", map[string]any{"name": " Alice ", "email": "alice@example.com", "external_ref": "crm-1"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		created := customerResponse(t, w.Body.Bytes())
+		assert.Equal(t, fmt.Sprintf("/customers/%d", created.Id), w.Header().Get("Location"))
+		assert.Equal(t, customerView{Id: created.Id, Name: "Alice", Email: "alice@example.com", ExternalRef: "crm-1", KycTier: "none"}, created)
+
+		w = putJson(t, db, fmt.Sprintf("/customers/%d", created.Id), map[string]any{"name": "Alice Smith", "email": "alice@example.org", "kyc_tier": "full"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = sendJson(t, db, "GET", fmt.Sprintf("/customers/%d", created.Id), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, customerView{Id: created.Id, Name: "Alice Smith", Email: "alice@example.org", KycTier: "full"}, customerResponse(t, w.Body.Bytes()))
+	})
+	t.Run("invalid details", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		type testCase struct {
+			body         map[string]any
+			expectedCode string
+		}
+
+		testCases := map[string]testCase{
+			"without name":      {body: map[string]any{"email": "alice@example.com"}, expectedCode: "name_not_set"},
+			"without email":     {body: map[string]any{"name": "Alice"}, expectedCode: "email_not_set"},
+			"invalid email":     {body: map[string]any{"name": "Alice", "email": "Alice <alice@example.com>"}, expectedCode: "invalid_customer_email"},
+			"unknown kyc tier":  {body: map[string]any{"name": "Alice", "email": "alice@example.com", "kyc_tier": "gold"}, expectedCode: "unknown_kyc_tier"},
+			"name too long":     {body: map[string]any{"name": strings.Repeat("a", 201), "email": "alice@example.com"}, expectedCode: "invalid_customer_name"},
+			"external ref long": {body: map[string]any{"name": "Alice", "email": "alice@example.com", "external_ref": strings.Repeat("a", 201)}, expectedCode: "invalid_external_ref"},
+		}
+
+		for testName, test := range testCases {
+			w := postJson(t, db, "/customers", test.body)
+			assert.Equal(t, test.expectedCode, problemResponse(t, w, http.StatusUnprocessableEntity)["code"], testName)
+		}
+	})
+	t.Run("external reference is unique", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		insertCustomer(t, db, "crm-1")
+		otherId := insertCustomer(t, db, "crm-2")
+
+		w := postJson(t, db, "/customers", map[string]any{"name": "Bob", "email": "bob@example.com", "external_ref": "crm-1"})
+		assert.Equal(t, "external_ref_taken", problemResponse(t, w, http.StatusConflict)["code"])
+
+		w = putJson(t, db, fmt.Sprintf("/customers/%d", otherId), map[string]any{"name": "Bob", "email": "bob@example.com", "external_ref": "crm-1"})
+		assert.Equal(t, "external_ref_taken", problemResponse(t, w, http.StatusConflict)["code"])
+	})
+	t.Run("customer does not exist", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		responses := map[string]*httptest.ResponseRecorder{
+			"find":           sendJson(t, db, "GET", "/customers/42", nil),
+			"update":         putJson(t, db, "/customers/42", map[string]any{"name": "Alice", "email": "alice@example.com"}),
+			"delete":         sendJson(t, db, "DELETE", "/customers/42", nil),
+			"accounts":       sendJson(t, db, "GET", "/customers/42/accounts", nil),
+			"create account": postJson(t, db, "/accounts", map[string]any{"currency": "EUR", "customer_id": 42}),
+		}
+
+		for testName, w := range responses {
+			assert.Equal(t, "customer_not_found", problemResponse(t, w, http.StatusNotFound)["code"], testName)
+		}
+	})
+}
+
+func TestCustomerAccounts(t *testing.T) {
+	t.Run("accounts are linked on creation and their balances summed up", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		customerId := insertCustomer(t, db, "")
+		var accIds []int64
+		for _, code := range []string{"USD", "EUR", "EUR"} {
+			w := postJson(t, db, "/accounts", map[string]any{"currency": code, "customer_id": customerId})
+			assert.Equal(t, http.StatusCreated, w.Code)
+
+			var res map[string]any
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			assert.Equal(t, float64(customerId), res["customer_id"])
+			accIds = append(accIds, int64(res["id"].(float64)))
+		}
+		insertAccount(t, db, 500)
+
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accIds[0]), map[string]any{"amount": 100}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accIds[1]), map[string]any{"amount": 200}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accIds[2]), map[string]any{"amount": 300}).Code)
+		assert.Equal(t, http.StatusCreated, postJson(t, db, fmt.Sprintf("/account/%d/holds", accIds[2]), map[string]any{"target": accIds[1], "amount": 50}).Code)
+
+		w := sendJson(t, db, "GET", fmt.Sprintf("/customers/%d/accounts", customerId), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var res customerAccountsView
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, customerId, res.Customer.Id)
+		assert.Len(t, res.Accounts, 3)
+		for i, acc := range res.Accounts {
+			assert.Equal(t, accIds[i], acc.Id)
+		}
+		assert.Len(t, res.Balances, 2)
+		assert.Equal(t, "EUR", res.Balances[0].Currency)
+		assert.Equal(t, 2, res.Balances[0].Accounts)
+		assert.Equal(t, int64(500), res.Balances[0].Balance)
+		assert.Equal(t, int64(450), res.Balances[0].Available)
+		assert.Equal(t, "USD", res.Balances[1].Currency)
+		assert.Equal(t, int64(100), res.Balances[1].Balance)
+	})
+	t.Run("accounts are paginated, balances are not", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		customerId := insertCustomer(t, db, "")
+		var accIds []int64
+		for i := 0; i < 3; i++ {
+			accIds = append(accIds, insertCustomerAccount(t, db, customerId, "active"))
+		}
+		_, err := db.Exec("UPDATE accounts SET balance = 100 WHERE customer_id = $1", customerId)
+		assert.NoError(t, err)
+
+		var pages [][]int64
+		cursor := ""
+		for {
+			w := sendJson(t, db, "GET", fmt.Sprintf("/customers/%d/accounts?limit=2&cursor=%s", customerId, cursor), nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var res customerAccountsView
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+			var page []int64
+			for _, acc := range res.Accounts {
+				page = append(page, acc.Id)
+			}
+			pages = append(pages, page)
+			assert.Len(t, res.Balances, 1)
+			assert.Equal(t, 3, res.Balances[0].Accounts)
+			assert.Equal(t, int64(300), res.Balances[0].Balance)
+
+			if res.NextCursor == "" {
+				break
+			}
+			cursor = res.NextCursor
+		}
+		assert.Equal(t, [][]int64{accIds[:2], accIds[2:]}, pages)
+
+		w := sendJson(t, db, "GET", fmt.Sprintf("/customers/%d/accounts?limit=101", customerId), nil)
+		assert.Equal(t, "invalid_limit", problemResponse(t, w, http.StatusUnprocessableEntity)["code"])
+	})
+	t.Run("customer without accounts", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		customerId := insertCustomer(t, db, "")
+
+		w := sendJson(t, db, "GET", fmt.Sprintf("/customers/%d/accounts", customerId), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, mustField(t, w.Body.Bytes(), "accounts"))
+		assert.JSONEq(t, `[]`, mustField(t, w.Body.Bytes(), "balances"))
+	})
+	t.Run("customers with open accounts cannot be deleted", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		customerId := insertCustomer(t, db, "")
+		accId := insertCustomerAccount(t, db, customerId, "active")
+		insertCustomerAccount(t, db, customerId, "frozen")
+
+		w := sendJson(t, db, "DELETE", fmt.Sprintf("/customers/%d", customerId), nil)
+		assert.Equal(t, "customer_has_open_accounts", problemResponse(t, w, http.StatusConflict)["code"])
+
+		_, err := db.Exec("UPDATE accounts SET status = 'closed' WHERE customer_id = $1", customerId)
+		assert.NoError(t, err)
+
+		w = sendJson(t, db, "DELETE", fmt.Sprintf("/customers/%d", customerId), nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, http.StatusNotFound, sendJson(t, db, "GET", fmt.Sprintf("/customers/%d", customerId), nil).Code)
+
+		// The closed accounts outlive the customer
+		w = sendJson(t, db, "GET", fmt.Sprintf("/account/%d", accId), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "customer_id")
+	})
+}
+
+func customerRepository(t *testing.T, db *sql.DB) *customer.Repository {
+	repo, err := customer.NewRepository(db)
+	assert.NoError(t, err)
+	return repo
+}
+
+func customerResponse(t *testing.T, body []byte) customerView {
+	var res customerView
+	assert.NoError(t, json.Unmarshal(body, &res))
+	return res
+}
+
+func insertCustomer(t *testing.T, db *sql.DB, externalRef string) int64 {
+	row := db.QueryRow("INSERT INTO customers (name, email, external_ref) VALUES ('Customer', 'customer@example.com', NULLIF($1, '')) RETURNING id", externalRef)
+	var id int64
+	assert.NoError(t, row.Scan(&id))
+	return id
+}
+
+func insertCustomerAccount(t *testing.T, db *sql.DB, customerId int64, status string) int64 {
+	row := db.QueryRow("INSERT INTO accounts (customer_id, status) VALUES ($1, $2) RETURNING id", customerId, status)
+	var id int64
+	assert.NoError(t, row.Scan(&id))
+	return id
+}
+
+func mustField(t *testing.T, body []byte, field string) string {
+	var res map[string]json.RawMessage
+	assert.NoError(t, json.Unmarshal(body, &res))
+	return string(res[field])
+}
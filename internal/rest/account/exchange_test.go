@@ -0,0 +1,243 @@
+package account_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/ledger"
+)
+
+type quoteResponse struct {
+	Id        string    `json:"id"`
+	Source    string    `json:"source_currency"`
+	Target    string    `json:"target_currency"`
+	Rate      string    `json:"rate"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func TestExchange(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		t.Run("quote", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			quote := createQuote(t, db, "EUR", "USD")
+			assert.NotEmpty(t, quote.Id)
+			assert.Equal(t, "EUR", quote.Source)
+			assert.Equal(t, "USD", quote.Target)
+			assert.Equal(t, "1.25", quote.Rate)
+			assert.True(t, quote.ExpiresAt.After(time.Now()))
+
+			inverse := createQuote(t, db, "USD", "EUR")
+			assert.Equal(t, "0.8", inverse.Rate)
+		})
+		t.Run("transfer with quote", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertCurrencyAccount(t, db, 1000, "EUR")
+			targetAccId := insertCurrencyAccount(t, db, 0, "USD")
+			quote := createQuote(t, db, "EUR", "USD")
+
+			w := sendExchange(t, db, srcAccId, targetAccId, 400, quote.Id)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			assert.Equal(t, 600, accountBalance(t, db, srcAccId))
+			assert.Equal(t, 500, accountBalance(t, db, targetAccId))
+
+			res := transferResponse(t, w)
+			assert.Equal(t, int64(400), res.Amount)
+			assert.Equal(t, "EUR", res.Currency)
+			assert.Equal(t, int64(500), res.TargetAmount)
+			assert.Equal(t, "USD", res.TargetCurrency)
+			assert.Equal(t, int64(600), res.SourceBalance)
+
+			// The rate is recorded on the journal entry and the books balance per currency
+			var kind, rate, quoteId string
+			assert.NoError(t, db.QueryRow("SELECT kind, fx_rate, fx_quote_id FROM journal_entries").Scan(&kind, &rate, &quoteId))
+			assert.Equal(t, string(ledger.KindExchange), kind)
+			assert.Equal(t, "1.25", rate)
+			assert.Equal(t, quote.Id, quoteId)
+
+			rows, err := db.Query("SELECT currency, SUM(amount) FROM postings GROUP BY currency")
+			assert.NoError(t, err)
+			defer rows.Close()
+			for rows.Next() {
+				var currency string
+				var sum int
+				assert.NoError(t, rows.Scan(&currency, &sum))
+				assert.Equal(t, 0, sum, currency)
+			}
+			assert.NoError(t, rows.Err())
+		})
+		t.Run("transfer with quote into currency without minor units", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertCurrencyAccount(t, db, 1000, "EUR")
+			targetAccId := insertCurrencyAccount(t, db, 0, "JPY")
+			quote := createQuote(t, db, "EUR", "JPY")
+
+			// 1.99 EUR at 160 is 318.4 JPY, rounded down
+			w := sendExchange(t, db, srcAccId, targetAccId, 199, quote.Id)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			assert.Equal(t, 801, accountBalance(t, db, srcAccId))
+			assert.Equal(t, 318, accountBalance(t, db, targetAccId))
+		})
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("quote bad request", func(t *testing.T) {
+			type testCase struct {
+				body               []byte
+				expectedStatusCode int
+			}
+
+			testCases := map[string]testCase{
+				"invalid json request body": {
+					body:               []byte("{\"}"),
+					expectedStatusCode: http.StatusBadRequest,
+				},
+				"unknown currency": {
+					body:               []byte("{\"source_currency\":\"EUR\",\"target_currency\":\"XYZ\"}"),
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+				"same currency": {
+					body:               []byte("{\"source_currency\":\"EUR\",\"target_currency\":\"EUR\"}"),
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+				"rate not available": {
+					body:               []byte("{\"source_currency\":\"GBP\",\"target_currency\":\"USD\"}"),
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+			}
+
+			for testName, test := range testCases {
+				t.Run(testName, func(t *testing.T) {
+					db, onClose := setupDb(t)
+					defer onClose()
+
+					w := httptest.NewRecorder()
+					r, _ := http.NewRequest("POST", "/fx/quotes", bytes.NewBuffer(test.body))
+					r.Header.Set("Content-Type", "application/json")
+					runApplication(t, db, w, r)
+					assert.Equal(t, test.expectedStatusCode, w.Code)
+				})
+			}
+		})
+		t.Run("different currencies without quote", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertCurrencyAccount(t, db, 1000, "EUR")
+			targetAccId := insertCurrencyAccount(t, db, 0, "USD")
+
+			w := sendExchange(t, db, srcAccId, targetAccId, 400, "")
+			assert.Equal(t, http.StatusConflict, w.Code)
+		})
+		t.Run("quote used twice", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertCurrencyAccount(t, db, 1000, "EUR")
+			targetAccId := insertCurrencyAccount(t, db, 0, "USD")
+			quote := createQuote(t, db, "EUR", "USD")
+
+			w := sendExchange(t, db, srcAccId, targetAccId, 400, quote.Id)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			w = sendExchange(t, db, srcAccId, targetAccId, 400, quote.Id)
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			assert.Equal(t, 600, accountBalance(t, db, srcAccId))
+		})
+		t.Run("quote expired", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertCurrencyAccount(t, db, 1000, "EUR")
+			targetAccId := insertCurrencyAccount(t, db, 0, "USD")
+			quote := createQuote(t, db, "EUR", "USD")
+
+			_, err := db.Exec("UPDATE fx_quotes SET expires_at = now() - INTERVAL '1 second'")
+			assert.NoError(t, err)
+
+			w := sendExchange(t, db, srcAccId, targetAccId, 400, quote.Id)
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			assert.Equal(t, 1000, accountBalance(t, db, srcAccId))
+		})
+		t.Run("quote for a different currency pair", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertCurrencyAccount(t, db, 1000, "EUR")
+			targetAccId := insertCurrencyAccount(t, db, 0, "USD")
+			quote := createQuote(t, db, "USD", "EUR")
+
+			w := sendExchange(t, db, srcAccId, targetAccId, 400, quote.Id)
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+		t.Run("unknown quote", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertCurrencyAccount(t, db, 1000, "EUR")
+			targetAccId := insertCurrencyAccount(t, db, 0, "USD")
+
+			w := sendExchange(t, db, srcAccId, targetAccId, 400, "does-not-exist")
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+		t.Run("insufficient balance keeps quote unused", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertCurrencyAccount(t, db, 100, "EUR")
+			targetAccId := insertCurrencyAccount(t, db, 0, "USD")
+			quote := createQuote(t, db, "EUR", "USD")
+
+			w := sendExchange(t, db, srcAccId, targetAccId, 400, quote.Id)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			w = sendExchange(t, db, srcAccId, targetAccId, 100, quote.Id)
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	})
+}
+
+func createQuote(t *testing.T, db *sql.DB, source string, target string) *quoteResponse {
+	reqBodyJsonBytes, _ := json.Marshal(map[string]any{
+		"source_currency": source,
+		"target_currency": target,
+	})
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/fx/quotes", bytes.NewBuffer(reqBodyJsonBytes))
+	r.Header.Set("Content-Type", "application/json")
+	runApplication(t, db, w, r)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	res := &quoteResponse{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(res))
+	return res
+}
+
+func sendExchange(t *testing.T, db *sql.DB, source int64, target int64, amount int, quoteId string) *httptest.ResponseRecorder {
+	reqBodyJsonBytes, _ := json.Marshal(map[string]any{
+		"target": target,
+		"amount": amount,
+		"quote":  quoteId,
+	})
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/transfer", source), bytes.NewBuffer(reqBodyJsonBytes))
+	r.Header.Set("Content-Type", "application/json")
+	runApplication(t, db, w, r)
+	return w
+}
@@ -0,0 +1,55 @@
+package find
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/jwt"
+	"github.com/ktsivkov/su-exc/internal/rest/account/view"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, finder account.Finder, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		record, err := finder.FindById(ctx, req.Id)
+		if err != nil {
+			if errors.Is(err, account.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "account id not found", "id", req.Id)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("account with id=%d does not exist", req.Id)), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "account lookup failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		// Users only read the accounts they own, privileged users any. Answering the accounts of others as missing keeps
+		// their ids from being enumerated
+		if user := jwt.From(ctx); user != nil && !user.Privileged && !record.IsOwnedBy(user.Subject) {
+			logger.WarnContext(ctx, "account not owned by the caller", "id", req.Id, "subject", user.Subject)
+			problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("account with id=%d does not exist", req.Id)), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(view.NewAccount(record)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+package find
+
+type Request struct {
+	Id int64
+}
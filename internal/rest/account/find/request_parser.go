@@ -0,0 +1,26 @@
+package find
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+type RequestParser func(ctx context.Context, r *http.Request) (*Request, error)
+
+func GetRequestParser() RequestParser {
+	return func(ctx context.Context, r *http.Request) (*Request, error) {
+		variables := mux.Vars(r)
+		id, err := strconv.ParseInt(variables["id"], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot parse account id")
+		}
+
+		return &Request{
+			Id: id,
+		}, nil
+	}
+}
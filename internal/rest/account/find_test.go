@@ -0,0 +1,43 @@
+package account_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 150)
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", fmt.Sprintf("/account/%d", accId), nil)
+		runApplication(t, db, w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var res map[string]any
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+		assert.Equal(t, map[string]any{"id": float64(accId), "currency": "EUR", "balance": float64(150), "available_balance": float64(150), "status": "active", "overdraft_limit": float64(0)}, res)
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("account does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("GET", fmt.Sprintf("/account/%d", 0), nil)
+			runApplication(t, db, w, r)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	})
+}
@@ -0,0 +1,200 @@
+package account_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type holdResponse struct {
+	Id             int64  `json:"id"`
+	Source         int64  `json:"source"`
+	Target         int64  `json:"target"`
+	Amount         int    `json:"amount"`
+	Status         string `json:"status"`
+	CapturedAmount int    `json:"captured_amount"`
+	TransferId     int64  `json:"transfer_id"`
+}
+
+func TestHolds(t *testing.T) {
+	t.Run("authorize reserves the available balance", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 200)
+		targetAccId := insertAccount(t, db, 0)
+
+		w := postJson(t, db, fmt.Sprintf("/account/%d/holds", srcAccId), map[string]any{"target": targetAccId, "amount": 150})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var hold holdResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&hold))
+		assert.Equal(t, holdResponse{Id: hold.Id, Source: srcAccId, Target: targetAccId, Amount: 150, Status: "active"}, hold)
+
+		balance, available := accountBalances(t, db, srcAccId)
+		assert.Equal(t, 200, balance)
+		assert.Equal(t, 50, available)
+
+		// Neither another hold nor a transfer can take the reserved funds
+		w = postJson(t, db, fmt.Sprintf("/account/%d/holds", srcAccId), map[string]any{"target": targetAccId, "amount": 51})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = postJson(t, db, fmt.Sprintf("/account/%d/transfer", srcAccId), map[string]any{"target": targetAccId, "amount": 51})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		w = postJson(t, db, fmt.Sprintf("/account/%d/transfer", srcAccId), map[string]any{"target": targetAccId, "amount": 50})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		assert.Equal(t, 150, accountBalance(t, db, srcAccId))
+	})
+	t.Run("full capture", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 200)
+		targetAccId := insertAccount(t, db, 0)
+		holdId := authorizeHold(t, db, srcAccId, targetAccId, 150)
+
+		w := postJson(t, db, fmt.Sprintf("/holds/%d/capture", holdId), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var hold holdResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&hold))
+		assert.Equal(t, "captured", hold.Status)
+		assert.Equal(t, 150, hold.CapturedAmount)
+		assert.Positive(t, hold.TransferId)
+
+		balance, available := accountBalances(t, db, srcAccId)
+		assert.Equal(t, 50, balance)
+		assert.Equal(t, 50, available)
+		assert.Equal(t, 150, accountBalance(t, db, targetAccId))
+		assert.Equal(t, -150, postingsTotal(t, db, srcAccId))
+		assert.Equal(t, 150, postingsTotal(t, db, targetAccId))
+	})
+	t.Run("partial capture releases the remainder", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 200)
+		targetAccId := insertAccount(t, db, 0)
+		holdId := authorizeHold(t, db, srcAccId, targetAccId, 150)
+
+		w := postJson(t, db, fmt.Sprintf("/holds/%d/capture", holdId), map[string]any{"amount": 100})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		balance, available := accountBalances(t, db, srcAccId)
+		assert.Equal(t, 100, balance)
+		assert.Equal(t, 100, available)
+		assert.Equal(t, 100, accountBalance(t, db, targetAccId))
+	})
+	t.Run("void releases the funds", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 200)
+		targetAccId := insertAccount(t, db, 0)
+		holdId := authorizeHold(t, db, srcAccId, targetAccId, 150)
+
+		w := postJson(t, db, fmt.Sprintf("/holds/%d/void", holdId), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var hold holdResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&hold))
+		assert.Equal(t, "voided", hold.Status)
+
+		balance, available := accountBalances(t, db, srcAccId)
+		assert.Equal(t, 200, balance)
+		assert.Equal(t, 200, available)
+		assert.Equal(t, 0, accountBalance(t, db, targetAccId))
+	})
+	t.Run("expired hold releases the funds", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 200)
+		targetAccId := insertAccount(t, db, 0)
+		holdId := authorizeHold(t, db, srcAccId, targetAccId, 150)
+
+		_, err := db.Exec("UPDATE holds SET expires_at = now() - INTERVAL '1 second' WHERE id = $1", holdId)
+		assert.NoError(t, err)
+
+		_, available := accountBalances(t, db, srcAccId)
+		assert.Equal(t, 200, available)
+
+		w := postJson(t, db, fmt.Sprintf("/holds/%d/capture", holdId), nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, 0, accountBalance(t, db, targetAccId))
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("capture more than held", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertAccount(t, db, 200)
+			targetAccId := insertAccount(t, db, 0)
+			holdId := authorizeHold(t, db, srcAccId, targetAccId, 150)
+
+			w := postJson(t, db, fmt.Sprintf("/holds/%d/capture", holdId), map[string]any{"amount": 151})
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			assert.Equal(t, 200, accountBalance(t, db, srcAccId))
+		})
+		t.Run("hold already settled", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertAccount(t, db, 200)
+			targetAccId := insertAccount(t, db, 0)
+			holdId := authorizeHold(t, db, srcAccId, targetAccId, 50)
+
+			assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/holds/%d/capture", holdId), nil).Code)
+			assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/holds/%d/capture", holdId), nil).Code)
+			assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/holds/%d/void", holdId), nil).Code)
+			assert.Equal(t, 50, accountBalance(t, db, targetAccId))
+		})
+		t.Run("hold does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			assert.Equal(t, http.StatusNotFound, postJson(t, db, "/holds/1/capture", nil).Code)
+			assert.Equal(t, http.StatusNotFound, postJson(t, db, "/holds/1/void", nil).Code)
+		})
+		t.Run("invalid hold amount", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertAccount(t, db, 200)
+			targetAccId := insertAccount(t, db, 0)
+
+			w := postJson(t, db, fmt.Sprintf("/account/%d/holds", srcAccId), map[string]any{"target": targetAccId, "amount": 0})
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+	})
+}
+
+func authorizeHold(t *testing.T, db *sql.DB, source int64, target int64, amount int) int64 {
+	w := postJson(t, db, fmt.Sprintf("/account/%d/holds", source), map[string]any{"target": target, "amount": amount})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var hold holdResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&hold))
+	return hold.Id
+}
+
+// accountBalances reads the ledger and the available balance of the account through the read API.
+func accountBalances(t *testing.T, db *sql.DB, id int64) (int, int) {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", fmt.Sprintf("/account/%d", id), nil)
+	runApplication(t, db, w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var res struct {
+		Balance   int `json:"balance"`
+		Available int `json:"available_balance"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+	return res.Balance, res.Available
+}
@@ -0,0 +1,215 @@
+package account_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/apikey"
+)
+
+func TestIdempotency(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		t.Run("repeated transfer is replayed", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertAccount(t, db, 200)
+			targetAccId := insertAccount(t, db, 0)
+			body := fmt.Sprintf("{\"amount\":100,\"target\":%d}", targetAccId)
+
+			for i := 0; i < 3; i++ {
+				w := httptest.NewRecorder()
+				r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/transfer", srcAccId), bytes.NewBuffer([]byte(body)))
+				r.Header.Set("Content-Type", "application/json")
+				r.Header.Set("Idempotency-Key", "transfer-1")
+				runApplication(t, db, w, r)
+
+				assert.Equal(t, http.StatusOK, w.Code)
+				if i > 0 {
+					assert.Equal(t, "true", w.Header().Get("Idempotency-Replayed"))
+				}
+			}
+
+			assert.Equal(t, 100, accountBalance(t, db, srcAccId))
+			assert.Equal(t, 100, accountBalance(t, db, targetAccId))
+		})
+		t.Run("rejected transfer is replayed", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertAccount(t, db, 50)
+			targetAccId := insertAccount(t, db, 0)
+			body := fmt.Sprintf("{\"amount\":100,\"target\":%d}", targetAccId)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/transfer", srcAccId), bytes.NewBuffer([]byte(body)))
+			r.Header.Set("Idempotency-Key", "transfer-1")
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			originalBody := w.Body.String()
+
+			// The balance is sufficient now, but the key still refers to the rejected attempt
+			_, err := db.Exec("UPDATE accounts SET balance = 500 WHERE id = $1", srcAccId)
+			assert.NoError(t, err)
+
+			w = httptest.NewRecorder()
+			r, _ = http.NewRequest("POST", fmt.Sprintf("/account/%d/transfer", srcAccId), bytes.NewBuffer([]byte(body)))
+			r.Header.Set("Idempotency-Key", "transfer-1")
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, originalBody, w.Body.String())
+			assert.Equal(t, 500, accountBalance(t, db, srcAccId))
+		})
+		t.Run("expired key is processed again", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			for i := 0; i < 2; i++ {
+				w := httptest.NewRecorder()
+				r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":10}")))
+				r.Header.Set("Idempotency-Key", "topup-1")
+				runApplication(t, db, w, r)
+				assert.Equal(t, http.StatusOK, w.Code)
+
+				_, err := db.Exec("UPDATE idempotency_keys SET expires_at = now() - INTERVAL '1 second'")
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, 20, accountBalance(t, db, accId))
+		})
+		t.Run("requests without key are not deduplicated", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			for i := 0; i < 2; i++ {
+				w := httptest.NewRecorder()
+				r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":10}")))
+				runApplication(t, db, w, r)
+				assert.Equal(t, http.StatusOK, w.Code)
+			}
+
+			assert.Equal(t, 20, accountBalance(t, db, accId))
+		})
+		t.Run("key is scoped to its actor", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+			_, firstSecret := issueApiKey(t, db, nil, apikey.ScopeAdmin)
+			_, secondSecret := issueApiKey(t, db, nil, apikey.ScopeAdmin)
+
+			for _, secret := range []string{firstSecret, secondSecret} {
+				w := httptest.NewRecorder()
+				r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":10}")))
+				r.Header.Set("X-Api-Key", secret)
+				r.Header.Set("Idempotency-Key", "topup-1")
+				apiRouter(t, db, slog.New(slog.NewJSONHandler(io.Discard, nil))).ServeHTTP(w, r)
+
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.Empty(t, w.Header().Get("Idempotency-Replayed"))
+			}
+
+			assert.Equal(t, 20, accountBalance(t, db, accId))
+		})
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("key reused with a different body", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":10}")))
+			r.Header.Set("Idempotency-Key", "topup-1")
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			w = httptest.NewRecorder()
+			r, _ = http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":20}")))
+			r.Header.Set("Idempotency-Key", "topup-1")
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+			assert.Equal(t, 10, accountBalance(t, db, accId))
+		})
+		t.Run("key reused for a different account", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			firstAccId := insertAccount(t, db, 0)
+			secondAccId := insertAccount(t, db, 0)
+
+			for _, accId := range []int64{firstAccId, secondAccId} {
+				w := httptest.NewRecorder()
+				r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":10}")))
+				r.Header.Set("Idempotency-Key", "topup-1")
+				runApplication(t, db, w, r)
+				if accId == secondAccId {
+					assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+				}
+			}
+
+			assert.Equal(t, 0, accountBalance(t, db, secondAccId))
+		})
+		t.Run("key is in progress", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":10}")))
+			r.Header.Set("Idempotency-Key", "topup-1")
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			// Simulate an instance that claimed the key but did not finish processing yet
+			_, err := db.Exec("UPDATE idempotency_keys SET status_code = NULL, content_type = NULL, response_body = NULL")
+			assert.NoError(t, err)
+
+			w = httptest.NewRecorder()
+			r, _ = http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":10}")))
+			r.Header.Set("Idempotency-Key", "topup-1")
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusConflict, w.Code)
+
+			assert.Equal(t, 10, accountBalance(t, db, accId))
+		})
+		t.Run("key whose lease lapsed is not processed again", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":10}")))
+			r.Header.Set("Idempotency-Key", "topup-1")
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			// Simulate an instance that applied the top-up and crashed before storing the response
+			_, err := db.Exec("UPDATE idempotency_keys SET status_code = NULL, content_type = NULL, response_body = NULL, locked_until = now() - INTERVAL '1 second'")
+			assert.NoError(t, err)
+
+			w = httptest.NewRecorder()
+			r, _ = http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":10}")))
+			r.Header.Set("Idempotency-Key", "topup-1")
+			runApplication(t, db, w, r)
+			assert.Equal(t, "idempotency_outcome_unknown", problemResponse(t, w, http.StatusConflict)["code"])
+
+			assert.Equal(t, 10, accountBalance(t, db, accId))
+		})
+	})
+}
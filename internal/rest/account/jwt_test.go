@@ -0,0 +1,181 @@
+package account_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var tokenSecret = []byte("test-token-secret")
+
+func TestTokenAuthentication(t *testing.T) {
+	t.Run("invalid tokens", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+
+		type testCase struct {
+			token        string
+			expectedCode string
+		}
+
+		testCases := map[string]testCase{
+			"expired":           {token: signToken(map[string]any{"sub": "alice", "exp": time.Now().Add(-time.Hour).Unix()}, tokenSecret), expectedCode: "token_expired"},
+			"other secret":      {token: signToken(map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}, []byte("other")), expectedCode: "token_invalid"},
+			"without subject":   {token: signToken(map[string]any{"exp": time.Now().Add(time.Hour).Unix()}, tokenSecret), expectedCode: "token_invalid"},
+			"not yet valid":     {token: signToken(map[string]any{"sub": "alice", "exp": time.Now().Add(2 * time.Hour).Unix(), "nbf": time.Now().Add(time.Hour).Unix()}, tokenSecret), expectedCode: "token_not_yet_valid"},
+			"not a json object": {token: "a.b.c", expectedCode: "token_invalid"},
+		}
+
+		for testName, test := range testCases {
+			w := sendAs(t, db, test.token, "POST", fmt.Sprintf("/account/%d/transfer", accId), `{"target_account_id":1,"amount":1}`)
+
+			res := problemResponse(t, w, http.StatusUnauthorized)
+			assert.Equal(t, test.expectedCode, res["code"], testName)
+			assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"), testName)
+		}
+	})
+	t.Run("subject is the actor and owns the accounts it creates", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		w := sendAs(t, db, userToken("alice"), "POST", "/account", "")
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var res map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, "alice", res["owner"])
+		assert.Equal(t, "alice", accountOwner(t, db, int64(res["id"].(float64))))
+
+		entries := auditEntries(t, db)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "user:alice", entries[0].Actor)
+	})
+}
+
+func TestTokenAuthorization(t *testing.T) {
+	t.Run("transfer from an owned account", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		sourceId := insertOwnedAccount(t, db, 1000, "alice")
+		targetId := insertOwnedAccount(t, db, 0, "bob")
+
+		w := sendAs(t, db, userToken("alice"), "POST", fmt.Sprintf("/account/%d/transfer", sourceId), fmt.Sprintf(`{"target_account_id":%d,"amount":300}`, targetId))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 700, accountBalance(t, db, sourceId))
+		assert.Equal(t, 300, accountBalance(t, db, targetId))
+	})
+	t.Run("transfer from an account of another owner", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		sourceId := insertOwnedAccount(t, db, 1000, "bob")
+		unownedId := insertAccount(t, db, 1000)
+		targetId := insertOwnedAccount(t, db, 0, "alice")
+
+		for _, accId := range []int64{sourceId, unownedId} {
+			w := sendAs(t, db, userToken("alice"), "POST", fmt.Sprintf("/account/%d/transfer", accId), fmt.Sprintf(`{"target_account_id":%d,"amount":300}`, targetId))
+			assert.Equal(t, "account_not_found", problemResponse(t, w, http.StatusNotFound)["code"])
+			assert.Equal(t, 1000, accountBalance(t, db, accId))
+		}
+		assert.Equal(t, 0, accountBalance(t, db, targetId))
+
+		// The accounts of others cannot be told apart from the ones which do not exist
+		w := sendAs(t, db, userToken("alice"), "POST", fmt.Sprintf("/account/%d/transfer", targetId+1000), fmt.Sprintf(`{"target_account_id":%d,"amount":300}`, targetId))
+		assert.Equal(t, "account_not_found", problemResponse(t, w, http.StatusNotFound)["code"])
+	})
+	t.Run("privileged user transfers from any account", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		sourceId := insertOwnedAccount(t, db, 1000, "bob")
+		targetId := insertOwnedAccount(t, db, 0, "alice")
+
+		w := sendAs(t, db, userToken("carol", "admin"), "POST", fmt.Sprintf("/account/%d/transfer", sourceId), fmt.Sprintf(`{"target_account_id":%d,"amount":300}`, targetId))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 700, accountBalance(t, db, sourceId))
+	})
+	t.Run("read owned accounts", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		ownedId := insertOwnedAccount(t, db, 1000, "alice")
+		otherId := insertOwnedAccount(t, db, 1000, "bob")
+		unownedId := insertAccount(t, db, 1000)
+
+		asOf := time.Now().UTC().Format(time.RFC3339)
+		for _, path := range []string{"/account/%d", "/account/%d/balance?as_of=" + asOf, "/account/%d/transactions"} {
+			w := sendAs(t, db, userToken("alice"), "GET", fmt.Sprintf(path, ownedId), "")
+			assert.Equal(t, http.StatusOK, w.Code, path)
+
+			// The accounts of others are answered just like the ones which do not exist
+			for _, accId := range []int64{otherId, unownedId, unownedId + 1000} {
+				w = sendAs(t, db, userToken("alice"), "GET", fmt.Sprintf(path, accId), "")
+				assert.Equal(t, "account_not_found", problemResponse(t, w, http.StatusNotFound)["code"], path)
+			}
+
+			w = sendAs(t, db, userToken("carol", "admin"), "GET", fmt.Sprintf(path, otherId), "")
+			assert.Equal(t, http.StatusOK, w.Code, path)
+		}
+	})
+	t.Run("topup requires a privileged role", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertOwnedAccount(t, db, 0, "alice")
+
+		w := sendAs(t, db, userToken("alice", "teller"), "POST", fmt.Sprintf("/account/%d/topup", accId), `{"amount":100}`)
+		assert.Equal(t, "privileged_role_required", problemResponse(t, w, http.StatusForbidden)["code"])
+		assert.Equal(t, 0, accountBalance(t, db, accId))
+
+		w = sendAs(t, db, userToken("carol", "admin"), "POST", fmt.Sprintf("/account/%d/topup", accId), `{"amount":100}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 100, accountBalance(t, db, accId))
+	})
+	t.Run("admin routes require a privileged role", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		w := sendAs(t, db, userToken("alice"), "POST", "/admin/api-keys", `{"name":"ci","scopes":["admin"]}`)
+		assert.Equal(t, "privileged_role_required", problemResponse(t, w, http.StatusForbidden)["code"])
+	})
+}
+
+// userToken returns an HS256 token of subject holding roles, valid for an hour.
+func userToken(subject string, roles ...string) string {
+	return signToken(map[string]any{"sub": subject, "roles": roles, "exp": time.Now().Add(time.Hour).Unix()}, tokenSecret)
+}
+
+func signToken(claims map[string]any, secret []byte) string {
+	header, _ := json.Marshal(map[string]any{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func insertOwnedAccount(t *testing.T, db *sql.DB, balance int, owner string) int64 {
+	row := db.QueryRow("INSERT INTO accounts (balance, owner) VALUES ($1, $2) RETURNING id", balance, owner)
+	var id int64
+	assert.NoError(t, row.Scan(&id))
+	return id
+}
+
+func accountOwner(t *testing.T, db *sql.DB, id int64) string {
+	row := db.QueryRow("SELECT COALESCE(owner, '') FROM accounts WHERE id=$1", id)
+	var owner string
+	assert.NoError(t, row.Scan(&owner))
+	return owner
+}
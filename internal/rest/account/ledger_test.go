@@ -0,0 +1,115 @@
+package account_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/ledger"
+)
+
+func TestLedger(t *testing.T) {
+	t.Run("top-up and transfer are journaled", func(t *testing.T) {
+		topUpAmount := 250
+		transferAmount := 100
+
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 0)
+		targetAccId := insertAccount(t, db, 0)
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", srcAccId), bytes.NewBuffer([]byte(fmt.Sprintf("{\"amount\":%d}", topUpAmount))))
+		r.Header.Set("Content-Type", "application/json")
+		runApplication(t, db, w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		reqBodyJsonBytes, _ := json.Marshal(map[string]any{
+			"target": targetAccId,
+			"amount": transferAmount,
+		})
+		w = httptest.NewRecorder()
+		r, _ = http.NewRequest("POST", fmt.Sprintf("/account/%d/transfer", srcAccId), bytes.NewBuffer(reqBodyJsonBytes))
+		r.Header.Set("Content-Type", "application/json")
+		runApplication(t, db, w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// Every journal entry is balanced
+		rows, err := db.Query("SELECT je.kind, COUNT(p.id), SUM(p.amount) FROM journal_entries je JOIN postings p ON p.journal_entry_id = je.id GROUP BY je.id ORDER BY je.id")
+		assert.NoError(t, err)
+		defer rows.Close()
+
+		var kinds []string
+		for rows.Next() {
+			var kind string
+			var postings, sum int
+			assert.NoError(t, rows.Scan(&kind, &postings, &sum))
+			assert.Equal(t, 2, postings)
+			assert.Equal(t, 0, sum)
+			kinds = append(kinds, kind)
+		}
+		assert.NoError(t, rows.Err())
+		assert.Equal(t, []string{string(ledger.KindTopUp), string(ledger.KindTransfer)}, kinds)
+
+		// Postings reflect the balances and the books sum up to zero
+		assert.Equal(t, accountBalance(t, db, srcAccId), postingsTotal(t, db, srcAccId))
+		assert.Equal(t, accountBalance(t, db, targetAccId), postingsTotal(t, db, targetAccId))
+		assert.Equal(t, -topUpAmount, postingsTotal(t, db, ledger.FundingAccountId))
+
+		var booksTotal int
+		assert.NoError(t, db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM postings").Scan(&booksTotal))
+		assert.Equal(t, 0, booksTotal)
+	})
+	t.Run("failed transfer is not journaled", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 0)
+		targetAccId := insertAccount(t, db, 0)
+
+		reqBodyJsonBytes, _ := json.Marshal(map[string]any{
+			"target": targetAccId,
+			"amount": 100,
+		})
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/transfer", srcAccId), bytes.NewBuffer(reqBodyJsonBytes))
+		r.Header.Set("Content-Type", "application/json")
+		runApplication(t, db, w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var entries int
+		assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM journal_entries").Scan(&entries))
+		assert.Equal(t, 0, entries)
+	})
+	t.Run("journal is append-only", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":10}")))
+		r.Header.Set("Content-Type", "application/json")
+		runApplication(t, db, w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		_, err := db.Exec("UPDATE postings SET amount = amount * 2")
+		assert.Error(t, err)
+		_, err = db.Exec("DELETE FROM journal_entries")
+		assert.Error(t, err)
+	})
+}
+
+func postingsTotal(t *testing.T, db *sql.DB, accId int64) int {
+	row := db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM postings WHERE account_id=$1", accId)
+	var total int
+	assert.NoError(t, row.Scan(&total))
+	return total
+}
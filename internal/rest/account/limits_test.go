@@ -0,0 +1,157 @@
+package account_test
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferLimits(t *testing.T) {
+	t.Run("count limit", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 1000)
+		targetId := insertAccount(t, db, 0)
+		setLimits(t, db, fmt.Sprintf("/admin/accounts/%d/limits", accId), map[string]any{"metric": "count", "period": "hour", "max": 2})
+
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 100}).Code)
+		}
+		w := postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 100})
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Contains(t, w.Body.String(), "at most 2 transfers per hour, resets at")
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		assert.NoError(t, err)
+		assert.True(t, retryAfter > 0 && retryAfter <= 3600)
+		assert.Equal(t, 800, accountBalance(t, db, accId))
+	})
+	t.Run("amount limit", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 1000)
+		targetId := insertAccount(t, db, 0)
+		setLimits(t, db, fmt.Sprintf("/admin/accounts/%d/limits", accId), map[string]any{"metric": "amount", "period": "day", "max": 500})
+
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 300}).Code)
+		w := postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 201})
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Contains(t, w.Body.String(), "at most 5.00 EUR outgoing per day, resets at")
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 200}).Code)
+		assert.Equal(t, 500, accountBalance(t, db, accId))
+	})
+	t.Run("incoming transfers do not count", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 1000)
+		otherId := insertAccount(t, db, 1000)
+		setLimits(t, db, fmt.Sprintf("/admin/accounts/%d/limits", accId), map[string]any{"metric": "count", "period": "day", "max": 1})
+
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", otherId), map[string]any{"target": accId, "amount": 100}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": otherId, "amount": 100}).Code)
+	})
+	t.Run("holds count once captured", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 1000)
+		targetId := insertAccount(t, db, 0)
+		setLimits(t, db, fmt.Sprintf("/admin/accounts/%d/limits", accId), map[string]any{"metric": "amount", "period": "day", "max": 500})
+
+		// Holds do not use up the limits before they are captured, their captures are checked like any other transfer
+		firstHold := authorizeHold(t, db, accId, targetId, 300)
+		secondHold := authorizeHold(t, db, accId, targetId, 300)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/holds/%d/capture", firstHold), nil).Code)
+
+		w := postJson(t, db, fmt.Sprintf("/holds/%d/capture", secondHold), nil)
+		assert.Equal(t, "limit_exceeded", problemResponse(t, w, http.StatusUnprocessableEntity)["code"])
+
+		w = postJson(t, db, fmt.Sprintf("/account/%d/holds", accId), map[string]any{"target": targetId, "amount": 201})
+		assert.Equal(t, "limit_exceeded", problemResponse(t, w, http.StatusUnprocessableEntity)["code"])
+		assert.Equal(t, 700, accountBalance(t, db, accId))
+		assert.Equal(t, 300, accountBalance(t, db, targetId))
+	})
+	t.Run("tier limits", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 1000)
+		targetId := insertAccount(t, db, 0)
+		setLimits(t, db, "/admin/tiers/business/limits", map[string]any{"metric": "count", "period": "day", "max": 1})
+		w := putJson(t, db, fmt.Sprintf("/admin/accounts/%d/tier", accId), map[string]any{"tier": "business"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"tier":"business","limits":[{"metric":"count","period":"day","max":1}]}`, w.Body.String())
+
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 100}).Code)
+		assert.Equal(t, http.StatusTooManyRequests, postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 100}).Code)
+		// Accounts outside the tier are not affected
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", targetId), map[string]any{"target": accId, "amount": 100}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", targetId), map[string]any{"target": accId, "amount": 100}).Code)
+	})
+	t.Run("account limits override tier limits", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 1000)
+		targetId := insertAccount(t, db, 0)
+		setLimits(t, db, "/admin/tiers/standard/limits", map[string]any{"metric": "count", "period": "day", "max": 1})
+		w := setLimits(t, db, fmt.Sprintf("/admin/accounts/%d/limits", accId), map[string]any{"metric": "count", "period": "day", "max": 2})
+		assert.JSONEq(t, `{"tier":"standard","limits":[{"metric":"count","period":"day","max":2}]}`, w.Body.String())
+
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 100}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 100}).Code)
+		assert.Equal(t, http.StatusTooManyRequests, postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 100}).Code)
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("unknown metric", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			w := putJson(t, db, fmt.Sprintf("/admin/accounts/%d/limits", accId), map[string]any{"limits": []map[string]any{{"metric": "velocity", "period": "hour", "max": 1}}})
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+		t.Run("duplicate rule", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+			rule := map[string]any{"metric": "count", "period": "hour", "max": 1}
+
+			w := putJson(t, db, fmt.Sprintf("/admin/accounts/%d/limits", accId), map[string]any{"limits": []map[string]any{rule, rule}})
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+		t.Run("invalid tier", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, putJson(t, db, fmt.Sprintf("/admin/accounts/%d/tier", accId), map[string]any{"tier": "Gold Plus"}).Code)
+		})
+		t.Run("account does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			assert.Equal(t, http.StatusNotFound, putJson(t, db, "/admin/accounts/1/limits", map[string]any{"limits": []map[string]any{}}).Code)
+		})
+	})
+}
+
+func setLimits(t *testing.T, db *sql.DB, path string, limits ...map[string]any) *httptest.ResponseRecorder {
+	w := putJson(t, db, path, map[string]any{"limits": limits})
+	assert.Equal(t, http.StatusOK, w.Code)
+	return w
+}
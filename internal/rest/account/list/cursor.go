@@ -0,0 +1,37 @@
+package list
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+)
+
+// Cursor is the keyset position of the last account of a page, along with the sort order it is valid for.
+type Cursor struct {
+	SortBy     account.SortField `json:"s"`
+	Descending bool              `json:"d,omitempty"`
+	Id         int64             `json:"i"`
+	Balance    int64             `json:"b,omitempty"`
+}
+
+func EncodeCursor(cursor *Cursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(encoded string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "cursor is not valid base64")
+	}
+
+	cursor := &Cursor{}
+	if err := json.Unmarshal(raw, cursor); err != nil || cursor.Id < 1 {
+		return nil, errors.New("cursor is malformed")
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,44 @@
+package list
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, lister account.Lister, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		query := req.Query()
+		query.Limit++
+		records, err := lister.List(ctx, query)
+		if err != nil {
+			logger.ErrorContext(ctx, "account listing failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(NewResponse(req, records)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
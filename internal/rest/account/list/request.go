@@ -0,0 +1,64 @@
+package list
+
+import (
+	"fmt"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+const DefaultLimit = 20
+const MaxLimit = 100
+
+var ErrRequestInvalidLimit = problem.Field("limit", "invalid_limit", fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+var ErrRequestInvalidSort = problem.Field("sort", "invalid_sort", fmt.Sprintf("sort must be one of %q, %q optionally prefixed with '-' for descending order", account.SortById, account.SortByBalance))
+var ErrRequestInvalidBalanceRange = problem.Field("min_balance", "invalid_balance_range", "min_balance cannot be greater than max_balance")
+var ErrRequestCursorSortMismatch = problem.Field("cursor", "cursor_sort_mismatch", "cursor was issued for a different sort order")
+
+type Request struct {
+	SortBy     account.SortField
+	Descending bool
+	Cursor     *Cursor
+	MinBalance *int64
+	MaxBalance *int64
+	Limit      int
+}
+
+func (r *Request) Validate() error {
+	if r.Limit < 1 || r.Limit > MaxLimit {
+		return ErrRequestInvalidLimit
+	}
+
+	if r.SortBy != account.SortById && r.SortBy != account.SortByBalance {
+		return ErrRequestInvalidSort
+	}
+
+	if r.MinBalance != nil && r.MaxBalance != nil && *r.MinBalance > *r.MaxBalance {
+		return ErrRequestInvalidBalanceRange
+	}
+
+	if r.Cursor != nil && (r.Cursor.SortBy != r.SortBy || r.Cursor.Descending != r.Descending) {
+		return ErrRequestCursorSortMismatch
+	}
+
+	return nil
+}
+
+func (r *Request) Query() *account.ListQuery {
+	query := &account.ListQuery{
+		SortBy:     r.SortBy,
+		Descending: r.Descending,
+		MinBalance: r.MinBalance,
+		MaxBalance: r.MaxBalance,
+		Limit:      r.Limit,
+	}
+
+	if r.Cursor != nil {
+		query.After = &account.Position{
+			Id:      r.Cursor.Id,
+			Balance: r.Cursor.Balance,
+		}
+	}
+
+	return query
+}
@@ -0,0 +1,67 @@
+package list
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+)
+
+type RequestParser func(ctx context.Context, r *http.Request) (*Request, error)
+
+func GetRequestParser() RequestParser {
+	return func(ctx context.Context, r *http.Request) (*Request, error) {
+		query := r.URL.Query()
+
+		sort := query.Get("sort")
+		if sort == "" {
+			sort = string(account.SortById)
+		}
+
+		req := &Request{
+			SortBy:     account.SortField(strings.TrimPrefix(sort, "-")),
+			Descending: strings.HasPrefix(sort, "-"),
+			Limit:      DefaultLimit,
+		}
+
+		var err error
+		if cursor := query.Get("cursor"); cursor != "" {
+			if req.Cursor, err = DecodeCursor(cursor); err != nil {
+				return nil, errors.Wrap(err, "cannot parse cursor")
+			}
+		}
+
+		if limit := query.Get("limit"); limit != "" {
+			if req.Limit, err = strconv.Atoi(limit); err != nil {
+				return nil, errors.Wrap(err, "cannot parse limit")
+			}
+		}
+
+		if req.MinBalance, err = parseOptionalInt64(query.Get("min_balance")); err != nil {
+			return nil, errors.Wrap(err, "cannot parse min_balance")
+		}
+
+		if req.MaxBalance, err = parseOptionalInt64(query.Get("max_balance")); err != nil {
+			return nil, errors.Wrap(err, "cannot parse max_balance")
+		}
+
+		return req, nil
+	}
+}
+
+func parseOptionalInt64(value string) (*int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
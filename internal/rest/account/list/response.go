@@ -0,0 +1,38 @@
+package list
+
+import (
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/rest/account/view"
+)
+
+type Response struct {
+	Accounts   []*view.Account `json:"accounts"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+func NewResponse(req *Request, records []*account.Record) *Response {
+	res := &Response{
+		Accounts: make([]*view.Account, 0, len(records)),
+	}
+
+	// One more record than requested is fetched to detect whether there is a next page
+	if len(records) > req.Limit {
+		records = records[:req.Limit]
+		last := records[req.Limit-1]
+		cursor := &Cursor{
+			SortBy:     req.SortBy,
+			Descending: req.Descending,
+			Id:         last.Id,
+		}
+		if req.SortBy == account.SortByBalance {
+			cursor.Balance = last.Balance.Minor()
+		}
+		res.NextCursor = EncodeCursor(cursor)
+	}
+
+	for _, record := range records {
+		res.Accounts = append(res.Accounts, view.NewAccount(record))
+	}
+
+	return res
+}
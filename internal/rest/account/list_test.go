@@ -0,0 +1,156 @@
+package account_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type listResponse struct {
+	Accounts []struct {
+		Id      int64 `json:"id"`
+		Balance int   `json:"balance"`
+	} `json:"accounts"`
+	NextCursor string `json:"next_cursor"`
+}
+
+func TestList(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		// Ids 1..5, two accounts share the same balance to exercise the keyset tie-breaker
+		for _, balance := range []int{300, 100, 200, 100, 500} {
+			insertAccount(t, db, balance)
+		}
+
+		t.Run("sorted by id", func(t *testing.T) {
+			res := listAccounts(t, db, url.Values{})
+			assert.Equal(t, []int64{1, 2, 3, 4, 5}, accountIds(res))
+			assert.Empty(t, res.NextCursor)
+
+			res = listAccounts(t, db, url.Values{"sort": {"-id"}})
+			assert.Equal(t, []int64{5, 4, 3, 2, 1}, accountIds(res))
+		})
+		t.Run("sorted by balance", func(t *testing.T) {
+			res := listAccounts(t, db, url.Values{"sort": {"balance"}})
+			assert.Equal(t, []int64{2, 4, 3, 1, 5}, accountIds(res))
+
+			res = listAccounts(t, db, url.Values{"sort": {"-balance"}})
+			assert.Equal(t, []int64{5, 1, 3, 4, 2}, accountIds(res))
+		})
+		t.Run("paginated", func(t *testing.T) {
+			for _, sort := range []string{"id", "-id", "balance", "-balance"} {
+				t.Run(sort, func(t *testing.T) {
+					var pagedIds []int64
+					query := url.Values{"sort": {sort}, "limit": {"2"}}
+					for pages := 0; ; pages++ {
+						if !assert.Less(t, pages, 3) {
+							break
+						}
+
+						res := listAccounts(t, db, query)
+						pagedIds = append(pagedIds, accountIds(res)...)
+						if res.NextCursor == "" {
+							break
+						}
+						query.Set("cursor", res.NextCursor)
+					}
+
+					assert.Equal(t, accountIds(listAccounts(t, db, url.Values{"sort": {sort}})), pagedIds)
+				})
+			}
+		})
+		t.Run("filtered by balance", func(t *testing.T) {
+			res := listAccounts(t, db, url.Values{"min_balance": {"200"}})
+			assert.Equal(t, []int64{1, 3, 5}, accountIds(res))
+
+			res = listAccounts(t, db, url.Values{"min_balance": {"100"}, "max_balance": {"200"}})
+			assert.Equal(t, []int64{2, 3, 4}, accountIds(res))
+
+			res = listAccounts(t, db, url.Values{"max_balance": {"50"}})
+			assert.Empty(t, res.Accounts)
+		})
+	})
+	t.Run("fail", func(t *testing.T) {
+		type testCase struct {
+			query              url.Values
+			expectedStatusCode int
+		}
+
+		testCases := map[string]testCase{
+			"malformed cursor": {
+				query:              url.Values{"cursor": {"!"}},
+				expectedStatusCode: http.StatusBadRequest,
+			},
+			"malformed balance": {
+				query:              url.Values{"min_balance": {"a lot"}},
+				expectedStatusCode: http.StatusBadRequest,
+			},
+			"unknown sort field": {
+				query:              url.Values{"sort": {"name"}},
+				expectedStatusCode: http.StatusUnprocessableEntity,
+			},
+			"limit out of range": {
+				query:              url.Values{"limit": {"0"}},
+				expectedStatusCode: http.StatusUnprocessableEntity,
+			},
+			"inverted balance range": {
+				query:              url.Values{"min_balance": {"10"}, "max_balance": {"5"}},
+				expectedStatusCode: http.StatusUnprocessableEntity,
+			},
+		}
+
+		for testName, test := range testCases {
+			t.Run(testName, func(t *testing.T) {
+				db, onClose := setupDb(t)
+				defer onClose()
+
+				w := httptest.NewRecorder()
+				r, _ := http.NewRequest("GET", fmt.Sprintf("/accounts?%s", test.query.Encode()), nil)
+				runApplication(t, db, w, r)
+				assert.Equal(t, test.expectedStatusCode, w.Code)
+			})
+		}
+		t.Run("cursor of a different sort order", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			insertAccount(t, db, 0)
+			insertAccount(t, db, 0)
+
+			res := listAccounts(t, db, url.Values{"limit": {"1"}})
+			assert.NotEmpty(t, res.NextCursor)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("GET", fmt.Sprintf("/accounts?%s", url.Values{"sort": {"-balance"}, "cursor": {res.NextCursor}}.Encode()), nil)
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+	})
+}
+
+func listAccounts(t *testing.T, db *sql.DB, query url.Values) *listResponse {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", fmt.Sprintf("/accounts?%s", query.Encode()), nil)
+	runApplication(t, db, w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	res := &listResponse{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(res))
+	return res
+}
+
+func accountIds(res *listResponse) []int64 {
+	ids := make([]int64, 0, len(res.Accounts))
+	for _, acc := range res.Accounts {
+		ids = append(ids, acc.Id)
+	}
+	return ids
+}
@@ -0,0 +1,220 @@
+package account_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/outbox"
+)
+
+func TestOutbox(t *testing.T) {
+	t.Run("changes add their events", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		w := postJson(t, db, "/accounts", map[string]any{"currency": "EUR"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		accId := insertAccount(t, db, 0)
+
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 500}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/withdraw", accId), map[string]any{"amount": 100}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": 1, "amount": 150}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/freeze", accId), nil).Code)
+
+		assert.Equal(t, []string{"AccountCreated", "AccountToppedUp", "AccountWithdrawn", "TransferCompleted", "AccountFrozen"}, outboxEventTypes(t, db))
+
+		var payload map[string]any
+		assert.NoError(t, db.QueryRow("SELECT payload FROM outbox WHERE type = 'TransferCompleted'").Scan(jsonScanner{&payload}))
+		assert.Equal(t, map[string]any{
+			"transfer_id":     float64(3),
+			"source_id":       float64(accId),
+			"target_id":       float64(1),
+			"source_amount":   float64(150),
+			"source_currency": "EUR",
+			"source_balance":  float64(250),
+			"target_amount":   float64(150),
+			"target_currency": "EUR",
+			"target_balance":  float64(150),
+		}, payload)
+	})
+	t.Run("rejected changes add no events", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		sourceId := insertAccount(t, db, 100)
+		targetId := insertAccount(t, db, 0)
+
+		assert.Equal(t, http.StatusBadRequest, postJson(t, db, fmt.Sprintf("/account/%d/transfer", sourceId), map[string]any{"target": targetId, "amount": 150}).Code)
+		assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/unfreeze", sourceId), nil).Code)
+
+		assert.Empty(t, outboxEventTypes(t, db))
+	})
+	t.Run("holds add their events", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		sourceId := insertAccount(t, db, 500)
+		targetId := insertAccount(t, db, 0)
+
+		captured := authorizeHold(t, db, sourceId, targetId, 200)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/holds/%d/capture", captured), map[string]any{"amount": 150}).Code)
+		voided := authorizeHold(t, db, sourceId, targetId, 100)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/holds/%d/void", voided), nil).Code)
+
+		assert.Equal(t, []string{"HoldAuthorized", "TransferCompleted", "HoldCaptured", "HoldAuthorized", "HoldVoided"}, outboxEventTypes(t, db))
+	})
+	t.Run("relay publishes pending events in order", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		for _, amount := range []int{100, 200, 300} {
+			assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": amount}).Code)
+		}
+
+		var buf bytes.Buffer
+		sink, err := outbox.NewWriterSink(&buf)
+		assert.NoError(t, err)
+		relay, err := outbox.NewRelay(db, 10, time.Minute, sink)
+		assert.NoError(t, err)
+
+		published, err := relay.Publish(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, published)
+
+		var amounts []float64
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			var event struct {
+				Type       string         `json:"type"`
+				AccountIds []int64        `json:"account_ids"`
+				Payload    map[string]any `json:"payload"`
+			}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+			assert.Equal(t, "AccountToppedUp", event.Type)
+			assert.Equal(t, []int64{accId}, event.AccountIds)
+			amounts = append(amounts, event.Payload["amount"].(float64))
+		}
+		assert.Equal(t, []float64{100, 200, 300}, amounts)
+
+		published, err = relay.Publish(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, published)
+	})
+	t.Run("failed event holds back the later events of its accounts", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		failingId := insertAccount(t, db, 0)
+		otherId := insertAccount(t, db, 0)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", failingId), map[string]any{"amount": 100}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", otherId), map[string]any{"amount": 100}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/transfer", otherId), map[string]any{"target": failingId, "amount": 50}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", otherId), map[string]any{"amount": 100}).Code)
+
+		sink := &recordingSink{failing: map[int64]bool{1: true}}
+		relay, err := outbox.NewRelay(db, 10, time.Minute, sink)
+		assert.NoError(t, err)
+
+		// The transfer concerns both accounts, so it holds back the last top-up of the other account too
+		published, err := relay.Publish(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, 1, published)
+		assert.Equal(t, []int64{2}, sink.published)
+
+		sink.failing = nil
+		published, err = relay.Publish(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 3, published)
+		assert.Equal(t, []int64{2, 1, 3, 4}, sink.published)
+	})
+	t.Run("events claimed by another relay are published once its lease is over", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		for _, amount := range []int{100, 200} {
+			assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": amount}).Code)
+		}
+
+		sink := &recordingSink{}
+		relay, err := outbox.NewRelay(db, 10, time.Minute, sink)
+		assert.NoError(t, err)
+
+		// Simulate a relay which claimed the first event and is still publishing it
+		_, err = db.Exec("UPDATE outbox SET claimed_until = now() + INTERVAL '1 hour' WHERE id = 1")
+		assert.NoError(t, err)
+
+		published, err := relay.Publish(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, published)
+		assert.Empty(t, sink.published)
+
+		// Simulate the relay crashing before it acknowledged the event
+		_, err = db.Exec("UPDATE outbox SET claimed_until = now() - INTERVAL '1 second' WHERE id = 1")
+		assert.NoError(t, err)
+
+		published, err = relay.Publish(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, published)
+		assert.Equal(t, []int64{1, 2}, sink.published)
+
+		var claimed int
+		assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM outbox WHERE claimed_until IS NOT NULL OR published_at IS NULL").Scan(&claimed))
+		assert.Equal(t, 0, claimed)
+	})
+}
+
+func outboxEventTypes(t *testing.T, db *sql.DB) []string {
+	rows, err := db.Query("SELECT type FROM outbox ORDER BY id")
+	assert.NoError(t, err)
+	defer rows.Close()
+
+	var types []string
+	for rows.Next() {
+		var eventType string
+		assert.NoError(t, rows.Scan(&eventType))
+		types = append(types, eventType)
+	}
+	assert.NoError(t, rows.Err())
+	return types
+}
+
+type jsonScanner struct {
+	target any
+}
+
+func (s jsonScanner) Scan(src any) error {
+	raw, ok := src.([]byte)
+	if !ok {
+		return errors.Errorf("unexpected json column type %T", src)
+	}
+	return json.Unmarshal(raw, s.target)
+}
+
+// recordingSink records the ids of the events it accepts and fails the events with an id in failing.
+type recordingSink struct {
+	failing   map[int64]bool
+	published []int64
+}
+
+func (s *recordingSink) Publish(_ context.Context, event *outbox.Event) error {
+	if s.failing[event.Id] {
+		return errors.Errorf("event id=%d failed", event.Id)
+	}
+	if !slices.Contains(s.published, event.Id) {
+		s.published = append(s.published, event.Id)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package account_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverdraft(t *testing.T) {
+	t.Run("set limit", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+
+		w := putJson(t, db, fmt.Sprintf("/admin/accounts/%d/overdraft", accId), map[string]any{"limit": "5.00"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "\"overdraft_limit\":500")
+	})
+	t.Run("transfer within the limit", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 100)
+		targetId := insertAccount(t, db, 0)
+		assert.Equal(t, http.StatusOK, putJson(t, db, fmt.Sprintf("/admin/accounts/%d/overdraft", accId), map[string]any{"limit": 500}).Code)
+
+		w := postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 600})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, -500, accountBalance(t, db, accId))
+		assert.Equal(t, 600, accountBalance(t, db, targetId))
+	})
+	t.Run("withdraw within the limit", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		assert.Equal(t, http.StatusOK, putJson(t, db, fmt.Sprintf("/admin/accounts/%d/overdraft", accId), map[string]any{"limit": 500}).Code)
+
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/withdraw", accId), map[string]any{"amount": 200}).Code)
+		assert.Equal(t, -200, accountBalance(t, db, accId))
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("transfer beyond the limit", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 100)
+			targetId := insertAccount(t, db, 0)
+			assert.Equal(t, http.StatusOK, putJson(t, db, fmt.Sprintf("/admin/accounts/%d/overdraft", accId), map[string]any{"limit": 500}).Code)
+
+			w := postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 601})
+
+			res := problemResponse(t, w, http.StatusBadRequest)
+			assert.Equal(t, "insufficient_balance", res["code"])
+			assert.Equal(t, float64(100), res["available_balance"])
+			assert.Equal(t, float64(500), res["overdraft_limit"])
+			assert.Equal(t, "EUR", res["currency"])
+			assert.Contains(t, w.Body.String(), "available balance=1.00 EUR")
+			assert.Equal(t, 100, accountBalance(t, db, accId))
+		})
+		t.Run("holds count towards the limit", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 100)
+			targetId := insertAccount(t, db, 0)
+			assert.Equal(t, http.StatusOK, putJson(t, db, fmt.Sprintf("/admin/accounts/%d/overdraft", accId), map[string]any{"limit": 500}).Code)
+			authorizeHold(t, db, accId, targetId, 400)
+
+			w := postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": targetId, "amount": 201})
+
+			res := problemResponse(t, w, http.StatusBadRequest)
+			assert.Equal(t, float64(-300), res["available_balance"])
+			assert.Equal(t, float64(500), res["overdraft_limit"])
+			assert.Equal(t, 100, accountBalance(t, db, accId))
+		})
+		t.Run("lowered limit blocks further debits", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+			assert.Equal(t, http.StatusOK, putJson(t, db, fmt.Sprintf("/admin/accounts/%d/overdraft", accId), map[string]any{"limit": 500}).Code)
+			assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/withdraw", accId), map[string]any{"amount": 300}).Code)
+			assert.Equal(t, http.StatusOK, putJson(t, db, fmt.Sprintf("/admin/accounts/%d/overdraft", accId), map[string]any{"limit": 0}).Code)
+
+			assert.Equal(t, http.StatusBadRequest, postJson(t, db, fmt.Sprintf("/account/%d/withdraw", accId), map[string]any{"amount": 1}).Code)
+			assert.Equal(t, -300, accountBalance(t, db, accId))
+		})
+		t.Run("negative limit", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, putJson(t, db, fmt.Sprintf("/admin/accounts/%d/overdraft", accId), map[string]any{"limit": -1}).Code)
+		})
+		t.Run("account does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			assert.Equal(t, http.StatusNotFound, putJson(t, db, "/admin/accounts/1/overdraft", map[string]any{"limit": 1}).Code)
+		})
+	})
+}
@@ -0,0 +1,118 @@
+package account_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/requestid"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func TestProblemResponses(t *testing.T) {
+	t.Run("malformed request", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		w := postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), nil)
+
+		res := problemResponse(t, w, http.StatusBadRequest)
+		assert.Equal(t, "body_not_set", res["code"])
+		assert.Equal(t, "request parsing error: request body is mandatory", res["detail"])
+		assert.Equal(t, fmt.Sprintf("/account/%d/topup", accId), res["instance"])
+		assert.Equal(t, w.Header().Get(requestid.Header), res["request_id"])
+	})
+	t.Run("invalid field", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 100)
+		targetAccId := insertAccount(t, db, 0)
+		w := postJson(t, db, fmt.Sprintf("/account/%d/transfer", srcAccId), map[string]any{"target": targetAccId, "amount": -1})
+
+		res := problemResponse(t, w, http.StatusUnprocessableEntity)
+		assert.Equal(t, "amount_not_positive", res["code"])
+		assert.Equal(t, []any{map[string]any{"field": "amount", "code": "amount_not_positive", "detail": "amount must be positive"}}, res["errors"])
+	})
+	t.Run("invalid item of a list", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 100)
+		w := postJson(t, db, fmt.Sprintf("/account/%d/transfers:batch", accId), map[string]any{"transfers": []map[string]any{{"target": 2, "amount": 1}, {"target": 2, "amount": 0}}})
+
+		res := problemResponse(t, w, http.StatusUnprocessableEntity)
+		assert.Equal(t, "transfers[1].amount", res["errors"].([]any)[0].(map[string]any)["field"])
+	})
+	t.Run("unsupported currency", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		w := postJson(t, db, "/accounts", map[string]any{"currency": "XXX"})
+
+		assert.Equal(t, problem.CodeUnsupportedCurrency, problem.Code(problemResponse(t, w, http.StatusUnprocessableEntity)["code"].(string)))
+	})
+	t.Run("account does not exist", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		w := postJson(t, db, "/account/1/topup", map[string]any{"amount": 10})
+
+		res := problemResponse(t, w, http.StatusNotFound)
+		assert.Equal(t, string(problem.CodeAccountNotFound), res["code"])
+		assert.Equal(t, "target account with id=1 does not exist", res["detail"])
+	})
+	t.Run("insufficient balance", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 10)
+		w := postJson(t, db, fmt.Sprintf("/account/%d/withdraw", accId), map[string]any{"amount": 20})
+
+		assert.Equal(t, string(problem.CodeInsufficientBalance), problemResponse(t, w, http.StatusBadRequest)["code"])
+	})
+	t.Run("request id sent by the client", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", "/account/1/topup", bytes.NewBuffer([]byte("{\"amount\":10}")))
+		r.Header.Set(requestid.Header, "req-7")
+		runApplication(t, db, w, r)
+
+		assert.Equal(t, "req-7", problemResponse(t, w, http.StatusNotFound)["request_id"])
+	})
+	t.Run("route does not exist", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		res := problemResponse(t, sendJson(t, db, "GET", "/nowhere", nil), http.StatusNotFound)
+		assert.Equal(t, string(problem.CodeRouteNotFound), res["code"])
+		assert.NotEmpty(t, res["request_id"])
+	})
+	t.Run("method not allowed", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		res := problemResponse(t, sendJson(t, db, "DELETE", "/accounts", nil), http.StatusMethodNotAllowed)
+		assert.Equal(t, string(problem.CodeMethodNotAllowed), res["code"])
+	})
+}
+
+func problemResponse(t *testing.T, w *httptest.ResponseRecorder, status int) map[string]any {
+	assert.Equal(t, status, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+	var res map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "about:blank", res["type"])
+	assert.Equal(t, http.StatusText(status), res["title"])
+	assert.Equal(t, float64(status), res["status"])
+	return res
+}
@@ -0,0 +1,119 @@
+package account_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reversalResponse struct {
+	Id         int64 `json:"id"`
+	TransferId int64 `json:"transfer_id"`
+	Source     int64 `json:"source"`
+	Target     int64 `json:"target"`
+	Amount     int   `json:"amount"`
+}
+
+func TestReversal(t *testing.T) {
+	t.Run("full reversal", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 200)
+		targetAccId := insertAccount(t, db, 0)
+		transferId := transferAndGetId(t, db, srcAccId, targetAccId, 150)
+
+		w := postJson(t, db, fmt.Sprintf("/transfers/%d/reverse", transferId), nil)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var reversal reversalResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&reversal))
+		assert.Equal(t, reversalResponse{Id: reversal.Id, TransferId: transferId, Source: targetAccId, Target: srcAccId, Amount: 150}, reversal)
+		assert.NotEqual(t, transferId, reversal.Id)
+
+		assert.Equal(t, 200, accountBalance(t, db, srcAccId))
+		assert.Equal(t, 0, accountBalance(t, db, targetAccId))
+
+		res := listTransactions(t, db, srcAccId, nil)
+		assert.Equal(t, "reversal", res.Transactions[0].Type)
+		assert.Equal(t, reversal.Id, res.Transactions[0].EntryId)
+
+		// A transfer cannot be reversed twice
+		w = postJson(t, db, fmt.Sprintf("/transfers/%d/reverse", transferId), nil)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, 200, accountBalance(t, db, srcAccId))
+	})
+	t.Run("partial reversals up to the transferred amount", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 200)
+		targetAccId := insertAccount(t, db, 0)
+		transferId := transferAndGetId(t, db, srcAccId, targetAccId, 100)
+		path := fmt.Sprintf("/transfers/%d/reverse", transferId)
+
+		assert.Equal(t, http.StatusCreated, postJson(t, db, path, map[string]any{"amount": 30}).Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, postJson(t, db, path, map[string]any{"amount": 71}).Code)
+		assert.Equal(t, http.StatusCreated, postJson(t, db, path, map[string]any{"amount": 70}).Code)
+		assert.Equal(t, http.StatusConflict, postJson(t, db, path, map[string]any{"amount": 1}).Code)
+
+		assert.Equal(t, 200, accountBalance(t, db, srcAccId))
+		assert.Equal(t, 0, accountBalance(t, db, targetAccId))
+	})
+	t.Run("recipient without enough balance", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 200)
+		targetAccId := insertAccount(t, db, 0)
+		transferId := transferAndGetId(t, db, srcAccId, targetAccId, 150)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/withdraw", targetAccId), map[string]any{"amount": 100}).Code)
+		path := fmt.Sprintf("/transfers/%d/reverse", transferId)
+
+		assert.Equal(t, http.StatusBadRequest, postJson(t, db, path, nil).Code)
+		assert.Equal(t, 50, accountBalance(t, db, targetAccId))
+
+		assert.Equal(t, http.StatusCreated, postJson(t, db, path, map[string]any{"allow_negative_balance": true}).Code)
+		assert.Equal(t, -100, accountBalance(t, db, targetAccId))
+		assert.Equal(t, 200, accountBalance(t, db, srcAccId))
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("transfer does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			assert.Equal(t, http.StatusNotFound, postJson(t, db, "/transfers/1/reverse", nil).Code)
+		})
+		t.Run("entry is not a transfer", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+			assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 100}).Code)
+			topUpId := listTransactions(t, db, accId, nil).Transactions[0].EntryId
+
+			assert.Equal(t, http.StatusNotFound, postJson(t, db, fmt.Sprintf("/transfers/%d/reverse", topUpId), nil).Code)
+			assert.Equal(t, 100, accountBalance(t, db, accId))
+		})
+		t.Run("invalid amount", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			transferId := transferAndGetId(t, db, insertAccount(t, db, 200), insertAccount(t, db, 0), 100)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, postJson(t, db, fmt.Sprintf("/transfers/%d/reverse", transferId), map[string]any{"amount": 0}).Code)
+		})
+	})
+}
+
+// transferAndGetId moves amount between the accounts and returns the id of the transfer.
+func transferAndGetId(t *testing.T, db *sql.DB, source int64, target int64, amount int) int64 {
+	w := postJson(t, db, fmt.Sprintf("/account/%d/transfer", source), map[string]any{"target": target, "amount": amount})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	return listTransactions(t, db, source, nil).Transactions[0].EntryId
+}
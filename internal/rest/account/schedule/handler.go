@@ -0,0 +1,91 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+	"github.com/ktsivkov/su-exc/internal/rest/scheduled/view"
+)
+
+func Handler(requestParser RequestParser, finder account.Finder, scheduler account.Scheduler, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		sourceAccount, err := finder.FindById(ctx, req.Source)
+		if err != nil {
+			if errors.Is(err, account.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "source account does not exist", "id", req.Source)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("source account with id=%d does not exist", req.Source)), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "source account existence check failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		targetAccount, err := finder.FindById(ctx, req.Data.Target)
+		if err != nil {
+			if errors.Is(err, account.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "target account does not exist", "id", req.Data.Target)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("target account with id=%d does not exist", req.Data.Target)), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "target account existence check failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		amount, err := req.Data.Amount.In(sourceAccount.Currency)
+		if err != nil {
+			logger.WarnContext(ctx, "invalid request amount", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		scheduled, err := scheduler.Schedule(ctx, sourceAccount, targetAccount, amount, *req.Data.ExecuteAt, req.Data.Recurrence)
+		if err != nil {
+			if errors.Is(err, account.ErrAccountFrozen) || errors.Is(err, account.ErrAccountClosed) {
+				logger.WarnContext(ctx, "account is not active", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrCurrencyMismatch) {
+				logger.WarnContext(ctx, "scheduled transfer currency mismatch", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "transfer scheduling failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(view.NewScheduledTransfer(scheduled)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestInvalidAmountLt0 = problem.Field("amount", "amount_not_positive", "amount must be positive")
+var ErrRequestExecuteAtNotSet = problem.Field("execute_at", "execute_at_not_set", "execute_at is mandatory")
+var ErrRequestExecuteAtInPast = problem.Field("execute_at", "execute_at_in_past", "execute_at must be in the future")
+var ErrRequestInvalidRecurrence = problem.Field("recurrence", "invalid_recurrence", "recurrence must be one of daily, weekly or monthly")
+
+type Request struct {
+	Source int64
+	Data   *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	Target int64 `json:"target"`
+	// Amount is in the currency of the source account, either as an integer number of minor units or as a decimal string.
+	Amount money.Amount `json:"amount"`
+	// ExecuteAt is when a one-off transfer runs, or when a recurring transfer runs for the first time.
+	ExecuteAt *time.Time `json:"execute_at"`
+	// Recurrence is optional, the transfer only runs once when omitted.
+	Recurrence *account.Recurrence `json:"recurrence"`
+}
+
+func (d *RequestData) Validate() error {
+	if !d.Amount.IsPositive() {
+		return ErrRequestInvalidAmountLt0
+	}
+
+	if d.ExecuteAt == nil {
+		return ErrRequestExecuteAtNotSet
+	}
+
+	if !d.ExecuteAt.After(time.Now()) {
+		return ErrRequestExecuteAtInPast
+	}
+
+	if d.Recurrence != nil && !d.Recurrence.IsValid() {
+		return errors.Wrapf(ErrRequestInvalidRecurrence, "recurrence=%q", *d.Recurrence)
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package account_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+)
+
+type scheduledTransferResponse struct {
+	Id         int64      `json:"id"`
+	Source     int64      `json:"source"`
+	Target     int64      `json:"target"`
+	Amount     int        `json:"amount"`
+	Recurrence string     `json:"recurrence"`
+	NextRunAt  *time.Time `json:"next_run_at"`
+	Status     string     `json:"status"`
+	Runs       []struct {
+		Outcome    string  `json:"outcome"`
+		Error      *string `json:"error"`
+		TransferId *int64  `json:"transfer_id"`
+	} `json:"runs"`
+}
+
+func TestScheduleTransfer(t *testing.T) {
+	t.Run("one-off", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 250)
+		targetId := insertAccount(t, db, 0)
+
+		w := postJson(t, db, fmt.Sprintf("/account/%d/scheduled-transfers", accId), map[string]any{"target": targetId, "amount": 100, "execute_at": time.Now().Add(time.Hour)})
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var res scheduledTransferResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+		assert.Equal(t, accId, res.Source)
+		assert.Equal(t, targetId, res.Target)
+		assert.Equal(t, 100, res.Amount)
+		assert.Equal(t, "active", res.Status)
+		assert.Empty(t, res.Recurrence)
+		assert.NotNil(t, res.NextRunAt)
+		assert.Equal(t, 250, accountBalance(t, db, accId))
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("execute_at in the past", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+			targetId := insertAccount(t, db, 0)
+
+			w := postJson(t, db, fmt.Sprintf("/account/%d/scheduled-transfers", accId), map[string]any{"target": targetId, "amount": 100, "execute_at": time.Now().Add(-time.Minute)})
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+		t.Run("execute_at missing", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+			targetId := insertAccount(t, db, 0)
+
+			w := postJson(t, db, fmt.Sprintf("/account/%d/scheduled-transfers", accId), map[string]any{"target": targetId, "amount": 100})
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+		t.Run("unknown recurrence", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+			targetId := insertAccount(t, db, 0)
+
+			w := postJson(t, db, fmt.Sprintf("/account/%d/scheduled-transfers", accId), map[string]any{"target": targetId, "amount": 100, "execute_at": time.Now().Add(time.Hour), "recurrence": "hourly"})
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+		t.Run("target account does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+
+			w := postJson(t, db, fmt.Sprintf("/account/%d/scheduled-transfers", accId), map[string]any{"target": accId + 1, "amount": 100, "execute_at": time.Now().Add(time.Hour)})
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	})
+}
+
+func TestRunScheduledTransfers(t *testing.T) {
+	t.Run("one-off", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 250)
+		targetId := insertAccount(t, db, 0)
+		id := scheduleTransfer(t, db, accId, targetId, 100, "")
+
+		assert.Equal(t, 1, runDueScheduledTransfers(t, db, "scheduler"))
+
+		assert.Equal(t, 150, accountBalance(t, db, accId))
+		assert.Equal(t, 100, accountBalance(t, db, targetId))
+		res := findScheduledTransfer(t, db, id)
+		assert.Equal(t, "completed", res.Status)
+		assert.Nil(t, res.NextRunAt)
+		assert.Len(t, res.Runs, 1)
+		assert.Equal(t, "succeeded", res.Runs[0].Outcome)
+		assert.NotNil(t, res.Runs[0].TransferId)
+
+		// A completed transfer is never run again
+		assert.Equal(t, 0, runDueScheduledTransfers(t, db, "scheduler"))
+		assert.Equal(t, 150, accountBalance(t, db, accId))
+	})
+	t.Run("recurring", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 250)
+		targetId := insertAccount(t, db, 0)
+		id := scheduleTransfer(t, db, accId, targetId, 100, "daily")
+
+		assert.Equal(t, 1, runDueScheduledTransfers(t, db, "scheduler"))
+
+		res := findScheduledTransfer(t, db, id)
+		assert.Equal(t, "active", res.Status)
+		assert.NotNil(t, res.NextRunAt)
+		assert.True(t, res.NextRunAt.After(time.Now()))
+		assert.Len(t, res.Runs, 1)
+		assert.Equal(t, 150, accountBalance(t, db, accId))
+
+		// The next run is not due yet
+		assert.Equal(t, 0, runDueScheduledTransfers(t, db, "scheduler"))
+		assert.Equal(t, 150, accountBalance(t, db, accId))
+	})
+	t.Run("failed run is recorded", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 50)
+		targetId := insertAccount(t, db, 0)
+		id := scheduleTransfer(t, db, accId, targetId, 100, "")
+
+		assert.Equal(t, 1, runDueScheduledTransfers(t, db, "scheduler"))
+
+		assert.Equal(t, 50, accountBalance(t, db, accId))
+		assert.Equal(t, 0, accountBalance(t, db, targetId))
+		res := findScheduledTransfer(t, db, id)
+		assert.Equal(t, "completed", res.Status)
+		assert.Len(t, res.Runs, 1)
+		assert.Equal(t, "failed", res.Runs[0].Outcome)
+		assert.Contains(t, *res.Runs[0].Error, "insufficient balance")
+		assert.Nil(t, res.Runs[0].TransferId)
+	})
+	t.Run("leased transfers are skipped by other schedulers", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 250)
+		targetId := insertAccount(t, db, 0)
+		scheduleTransfer(t, db, accId, targetId, 100, "")
+		accountRepo, err := account.NewRepository(db, time.Hour)
+		assert.NoError(t, err)
+
+		claimed, err := accountRepo.ClaimScheduledTransfers(context.Background(), "first", 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Len(t, claimed, 1)
+
+		assert.Equal(t, 0, runDueScheduledTransfers(t, db, "second"))
+		_, err = accountRepo.RunScheduledTransfer(context.Background(), claimed[0], "second")
+		assert.ErrorIs(t, err, account.ErrLeaseLost)
+		assert.Equal(t, 250, accountBalance(t, db, accId))
+
+		_, err = accountRepo.RunScheduledTransfer(context.Background(), claimed[0], "first")
+		assert.NoError(t, err)
+		// The run is not repeated even by the scheduler which holds the lease
+		_, err = accountRepo.RunScheduledTransfer(context.Background(), claimed[0], "first")
+		assert.ErrorIs(t, err, account.ErrLeaseLost)
+		assert.Equal(t, 150, accountBalance(t, db, accId))
+	})
+}
+
+// scheduleTransfer schedules a transfer through the API and makes it due right away.
+func scheduleTransfer(t *testing.T, db *sql.DB, source int64, target int64, amount int, recurrence string) int64 {
+	body := map[string]any{"target": target, "amount": amount, "execute_at": time.Now().Add(time.Hour)}
+	if recurrence != "" {
+		body["recurrence"] = recurrence
+	}
+	w := postJson(t, db, fmt.Sprintf("/account/%d/scheduled-transfers", source), body)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var res scheduledTransferResponse
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+
+	_, err := db.Exec("UPDATE scheduled_transfers SET start_at = start_at - INTERVAL '2 hours', next_run_at = next_run_at - INTERVAL '2 hours' WHERE id = $1", res.Id)
+	assert.NoError(t, err)
+
+	return res.Id
+}
+
+// runDueScheduledTransfers does what a single tick of the scheduler does and returns the number of transfers run.
+func runDueScheduledTransfers(t *testing.T, db *sql.DB, owner string) int {
+	accountRepo, err := account.NewRepository(db, time.Hour)
+	assert.NoError(t, err)
+
+	claimed, err := accountRepo.ClaimScheduledTransfers(context.Background(), owner, 10, time.Minute)
+	assert.NoError(t, err)
+	for _, scheduled := range claimed {
+		_, err := accountRepo.RunScheduledTransfer(context.Background(), scheduled, owner)
+		assert.NoError(t, err)
+	}
+
+	return len(claimed)
+}
+
+func findScheduledTransfer(t *testing.T, db *sql.DB, id int64) *scheduledTransferResponse {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", fmt.Sprintf("/scheduled-transfers/%d", id), nil)
+	runApplication(t, db, w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	res := &scheduledTransferResponse{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(res))
+	return res
+}
@@ -0,0 +1,188 @@
+package account_test
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreeze(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 250)
+
+		w := postJson(t, db, fmt.Sprintf("/admin/accounts/%d/freeze", accId), nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "\"status\":\"frozen\"")
+		assert.Equal(t, "frozen", accountStatus(t, db, accId))
+	})
+	t.Run("frozen account rejects debits and credits", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 250)
+		otherId := insertAccount(t, db, 250)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/freeze", accId), nil).Code)
+
+		assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 100}).Code)
+		assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/account/%d/withdraw", accId), map[string]any{"amount": 100}).Code)
+		assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/account/%d/transfer", accId), map[string]any{"target": otherId, "amount": 100}).Code)
+		assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/account/%d/transfer", otherId), map[string]any{"target": accId, "amount": 100}).Code)
+		assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/account/%d/holds", accId), map[string]any{"target": otherId, "amount": 100}).Code)
+		assert.Equal(t, 250, accountBalance(t, db, accId))
+		assert.Equal(t, 250, accountBalance(t, db, otherId))
+	})
+	t.Run("unfreeze restores the account", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 250)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/freeze", accId), nil).Code)
+
+		w := postJson(t, db, fmt.Sprintf("/admin/accounts/%d/unfreeze", accId), nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "active", accountStatus(t, db, accId))
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 100}).Code)
+		assert.Equal(t, 350, accountBalance(t, db, accId))
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("account does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			assert.Equal(t, http.StatusNotFound, postJson(t, db, "/admin/accounts/1/freeze", nil).Code)
+		})
+		t.Run("already frozen", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+			assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/freeze", accId), nil).Code)
+
+			assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/freeze", accId), nil).Code)
+		})
+		t.Run("unfreeze an active account", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+
+			assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/unfreeze", accId), nil).Code)
+			assert.Equal(t, "active", accountStatus(t, db, accId))
+		})
+	})
+}
+
+func TestClose(t *testing.T) {
+	t.Run("success with zero balance", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+
+		w := postJson(t, db, fmt.Sprintf("/admin/accounts/%d/close", accId), nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "closed", accountStatus(t, db, accId))
+	})
+	t.Run("success with the balance swept to another account", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 250)
+		sweepId := insertAccount(t, db, 50)
+
+		w := postJson(t, db, fmt.Sprintf("/admin/accounts/%d/close", accId), map[string]any{"sweep_to": sweepId})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "closed", accountStatus(t, db, accId))
+		assert.Equal(t, 0, accountBalance(t, db, accId))
+		assert.Equal(t, 300, accountBalance(t, db, sweepId))
+		assert.Equal(t, -250, postingsTotal(t, db, accId))
+		assert.Equal(t, 250, postingsTotal(t, db, sweepId))
+	})
+	t.Run("success with a frozen account", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 250)
+		sweepId := insertAccount(t, db, 0)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/freeze", accId), nil).Code)
+
+		w := postJson(t, db, fmt.Sprintf("/admin/accounts/%d/close", accId), map[string]any{"sweep_to": sweepId})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 250, accountBalance(t, db, sweepId))
+	})
+	t.Run("closed account rejects everything", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+		otherId := insertAccount(t, db, 250)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/close", accId), nil).Code)
+
+		assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 100}).Code)
+		assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/account/%d/transfer", otherId), map[string]any{"target": accId, "amount": 100}).Code)
+		assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/unfreeze", accId), nil).Code)
+		assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/close", accId), nil).Code)
+		assert.Equal(t, 250, accountBalance(t, db, otherId))
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("nonzero balance without sweep", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+
+			assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/close", accId), nil).Code)
+			assert.Equal(t, "active", accountStatus(t, db, accId))
+			assert.Equal(t, 250, accountBalance(t, db, accId))
+		})
+		t.Run("sweep account does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+
+			assert.Equal(t, http.StatusNotFound, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/close", accId), map[string]any{"sweep_to": accId + 1}).Code)
+			assert.Equal(t, "active", accountStatus(t, db, accId))
+		})
+		t.Run("sweep account in another currency", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+			sweepId := insertCurrencyAccount(t, db, 0, "USD")
+
+			assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/close", accId), map[string]any{"sweep_to": sweepId}).Code)
+			assert.Equal(t, 250, accountBalance(t, db, accId))
+		})
+		t.Run("active holds", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+			sweepId := insertAccount(t, db, 0)
+			authorizeHold(t, db, accId, sweepId, 100)
+
+			assert.Equal(t, http.StatusConflict, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/close", accId), map[string]any{"sweep_to": sweepId}).Code)
+			assert.Equal(t, "active", accountStatus(t, db, accId))
+			assert.Equal(t, 250, accountBalance(t, db, accId))
+		})
+	})
+}
+
+func accountStatus(t *testing.T, db *sql.DB, id int64) string {
+	row := db.QueryRow("SELECT status FROM accounts WHERE id=$1", id)
+	var status string
+	assert.NoError(t, row.Scan(&status))
+	return status
+}
@@ -1,6 +1,7 @@
 package topup
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -8,6 +9,10 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/account/view"
+	"github.com/ktsivkov/su-exc/internal/rest/negotiate"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
 )
 
 func Handler(requestParser RequestParser, finder account.Finder, topUpper account.TopUpper, logger *slog.Logger) http.HandlerFunc {
@@ -17,19 +22,13 @@ func Handler(requestParser RequestParser, finder account.Finder, topUpper accoun
 		req, err := requestParser(ctx, r)
 		if err != nil {
 			logger.WarnContext(ctx, "cannot parse request", "error", err)
-			w.WriteHeader(http.StatusBadRequest)
-			if _, err := fmt.Fprintf(w, "request parsing error: %s", err); err != nil {
-				logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
-			}
+			problem.Write(w, r, problem.Malformed(err), logger)
 			return
 		}
 
 		if err := req.Validate(); err != nil {
 			logger.WarnContext(ctx, "invalid request", "error", err)
-			w.WriteHeader(http.StatusUnprocessableEntity)
-			if _, err := fmt.Fprintf(w, "request validation error: %s", err); err != nil {
-				logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
-			}
+			problem.Write(w, r, problem.Invalid(err), logger)
 			return
 		}
 
@@ -37,30 +36,61 @@ func Handler(requestParser RequestParser, finder account.Finder, topUpper accoun
 		if err != nil {
 			if errors.Is(err, account.ErrDoesNotExist) {
 				logger.WarnContext(ctx, "account id not found", "id", req.Target)
-				w.WriteHeader(http.StatusNotFound)
-				if _, err := fmt.Fprintf(w, "target account with id=%d does not exist", req.Target); err != nil {
-					logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
-				}
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("target account with id=%d does not exist", req.Target)), logger)
 				return
 			}
 
 			logger.ErrorContext(ctx, "account existence check failed", "error", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			if _, err := fmt.Fprint(w, "could not process the request"); err != nil {
-				logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
-			}
+			problem.Write(w, r, problem.Internal(), logger)
 			return
 		}
 
-		if err := topUpper.TopUp(ctx, targetAccount, req.Data.Amount); err != nil {
-			logger.ErrorContext(ctx, "account top-up failed", "error", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			if _, err := fmt.Fprint(w, "could not process the request"); err != nil {
-				logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		code := req.Data.Currency
+		if code == "" {
+			code = targetAccount.Currency
+		}
+
+		amount, err := req.Data.Amount.In(code)
+		if err != nil {
+			logger.WarnContext(ctx, "invalid request amount", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		topped, err := topUpper.TopUp(ctx, targetAccount, amount)
+		if err != nil {
+			if errors.Is(err, account.ErrAccountFrozen) || errors.Is(err, account.ErrAccountClosed) {
+				logger.WarnContext(ctx, "account is not active", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
 			}
+
+			if errors.Is(err, account.ErrCurrencyMismatch) {
+				logger.WarnContext(ctx, "top-up currency mismatch", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			if errors.Is(err, money.ErrOverflow) {
+				logger.WarnContext(ctx, "top-up would overflow the balance", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusUnprocessableEntity, err), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "account top-up failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		if negotiate.Legacy(r) {
+			w.WriteHeader(http.StatusOK)
 			return
 		}
 
+		w.Header().Set("Content-Type", negotiate.JSON)
 		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(view.NewAccount(topped)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
 	}
 }
@@ -2,10 +2,14 @@ package topup
 
 import (
 	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
 )
 
-var ErrRequestDataNotSet = errors.New("request data is mandatory")
-var ErrRequestInvalidAmountLt0 = errors.New("amount cannot be less than 1")
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestInvalidAmountLt0 = problem.Field("amount", "amount_not_positive", "amount must be positive")
 
 type Request struct {
 	Target int64
@@ -25,13 +29,20 @@ func (r *Request) Validate() error {
 }
 
 type RequestData struct {
-	Amount int `json:"amount"`
+	// Amount is either an integer number of minor units or a decimal string of major units, e.g. 1234 or "12.34".
+	Amount money.Amount `json:"amount"`
+	// Currency is optional, the amount is in the currency of the account when omitted.
+	Currency currency.Code `json:"currency"`
 }
 
 func (d *RequestData) Validate() error {
-	if d.Amount < 1 {
+	if !d.Amount.IsPositive() {
 		return ErrRequestInvalidAmountLt0
 	}
 
+	if d.Currency != "" && !d.Currency.IsValid() {
+		return errors.Wrapf(currency.ErrUnsupported, "code=%q", d.Currency)
+	}
+
 	return nil
 }
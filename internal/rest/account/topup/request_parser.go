@@ -8,9 +8,11 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
 )
 
-var ErrRequestBodyNotSet = errors.New("request body is mandatory")
+var ErrRequestBodyNotSet = problem.Field("", "body_not_set", "request body is mandatory")
 
 type RequestParser func(ctx context.Context, r *http.Request) (*Request, error)
 
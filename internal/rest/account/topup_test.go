@@ -2,13 +2,17 @@ package account_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/rest/account/view"
 )
 
 func TestTopUp(t *testing.T) {
@@ -61,14 +65,97 @@ func TestTopUp(t *testing.T) {
 			// Assertions
 			assert.Equal(t, http.StatusOK, w.Code)
 
+			var topped view.Account
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&topped))
+			assert.Equal(t, accId, topped.Id)
+			assert.Equal(t, int64(initialBalance+addedBalance), topped.Balance.Minor())
+			assert.Equal(t, int64(initialBalance+addedBalance), topped.Available.Minor())
+
 			// Confirm DB state
 			accRow = db.QueryRow("SELECT balance FROM accounts WHERE ID=$1", accId)
 			var balance int
 			assert.NoError(t, accRow.Scan(&balance))
 			assert.Equal(t, initialBalance+addedBalance, balance)
 		})
+		t.Run("as plain text", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 0)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":250}")))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Accept", "text/plain")
+
+			runApplication(t, db, w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, w.Body.String())
+			assert.Equal(t, 250, accountBalance(t, db, accId))
+		})
+	})
+	t.Run("success in matching currency", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertCurrencyAccount(t, db, 0, "USD")
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":250,\"currency\":\"USD\"}")))
+		r.Header.Set("Content-Type", "application/json")
+
+		runApplication(t, db, w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 250, accountBalance(t, db, accId))
+	})
+	t.Run("success in major units", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 0)
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":\"12.34\"}")))
+		r.Header.Set("Content-Type", "application/json")
+
+		runApplication(t, db, w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 1234, accountBalance(t, db, accId))
 	})
 	t.Run("fail", func(t *testing.T) {
+		t.Run("balance overflow", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, math.MaxInt64-10)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":100}")))
+			r.Header.Set("Content-Type", "application/json")
+
+			runApplication(t, db, w, r)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+			assert.Equal(t, math.MaxInt64-10, accountBalance(t, db, accId))
+		})
+		t.Run("currency mismatch", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertCurrencyAccount(t, db, 0, "USD")
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", accId), bytes.NewBuffer([]byte("{\"amount\":250,\"currency\":\"EUR\"}")))
+			r.Header.Set("Content-Type", "application/json")
+
+			runApplication(t, db, w, r)
+
+			assert.Equal(t, http.StatusConflict, w.Code)
+			assert.Equal(t, 0, accountBalance(t, db, accId))
+		})
 		t.Run("account does not exist", func(t *testing.T) {
 			db, onClose := setupDb(t)
 			defer onClose()
@@ -106,6 +193,18 @@ func TestTopUp(t *testing.T) {
 					body:               bytes.NewBuffer([]byte("{\"amount\":0}")),
 					expectedStatusCode: http.StatusUnprocessableEntity,
 				},
+				"too many decimal places": {
+					body:               bytes.NewBuffer([]byte("{\"amount\":\"1.234\"}")),
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+				"amount out of range": {
+					body:               bytes.NewBuffer([]byte("{\"amount\":\"92233720368547758.1\"}")),
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+				"unknown currency": {
+					body:               bytes.NewBuffer([]byte("{\"amount\":10,\"currency\":\"XYZ\"}")),
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
 			}
 
 			for testName, test := range testCases {
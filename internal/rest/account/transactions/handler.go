@@ -0,0 +1,68 @@
+package transactions
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/jwt"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, finder account.Finder, historyFinder account.HistoryFinder, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		record, err := finder.FindById(ctx, req.Account)
+		if err != nil {
+			if errors.Is(err, account.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "account id not found", "id", req.Account)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("account with id=%d does not exist", req.Account)), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "account existence check failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		// Users only read the transactions of the accounts they own, answered as missing otherwise, privileged users any
+		if user := jwt.From(ctx); user != nil && !user.Privileged && !record.IsOwnedBy(user.Subject) {
+			logger.WarnContext(ctx, "account not owned by the caller", "id", req.Account, "subject", user.Subject)
+			problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("account with id=%d does not exist", req.Account)), logger)
+			return
+		}
+
+		query := req.Query()
+		query.Limit++
+		history, err := historyFinder.History(ctx, query)
+		if err != nil {
+			logger.ErrorContext(ctx, "account history lookup failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(NewResponse(history, req.Limit)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
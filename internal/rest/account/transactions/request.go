@@ -0,0 +1,63 @@
+package transactions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+const DefaultLimit = 20
+const MaxLimit = 100
+
+var ErrRequestInvalidLimit = problem.Field("limit", "invalid_limit", fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+var ErrRequestInvalidDateRange = problem.Field("from", "invalid_date_range", "from must be before to")
+var ErrRequestInvalidDirection = problem.Field("direction", "invalid_direction", fmt.Sprintf("direction must be one of %q, %q", account.DirectionIn, account.DirectionOut))
+var ErrRequestInvalidType = problem.Field("type", "invalid_type", fmt.Sprintf("type must be one of %q, %q, %q, %q", account.TransactionTypeTopUp, account.TransactionTypeTransfer, account.TransactionTypeWithdrawal, account.TransactionTypeReversal))
+
+type Request struct {
+	Account   int64
+	Cursor    int64
+	Limit     int
+	From      time.Time
+	To        time.Time
+	Direction account.Direction
+	Type      account.TransactionType
+}
+
+func (r *Request) Validate() error {
+	if r.Limit < 1 || r.Limit > MaxLimit {
+		return ErrRequestInvalidLimit
+	}
+
+	if !r.From.IsZero() && !r.To.IsZero() && !r.From.Before(r.To) {
+		return ErrRequestInvalidDateRange
+	}
+
+	switch r.Direction {
+	case "", account.DirectionIn, account.DirectionOut:
+	default:
+		return ErrRequestInvalidDirection
+	}
+
+	switch r.Type {
+	case "", account.TransactionTypeTopUp, account.TransactionTypeTransfer, account.TransactionTypeWithdrawal, account.TransactionTypeReversal:
+	default:
+		return ErrRequestInvalidType
+	}
+
+	return nil
+}
+
+func (r *Request) Query() *account.HistoryQuery {
+	return &account.HistoryQuery{
+		AccountId: r.Account,
+		Before:    r.Cursor,
+		Limit:     r.Limit,
+		From:      r.From,
+		To:        r.To,
+		Direction: r.Direction,
+		Type:      r.Type,
+	}
+}
@@ -0,0 +1,78 @@
+package transactions
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+)
+
+type RequestParser func(ctx context.Context, r *http.Request) (*Request, error)
+
+func GetRequestParser() RequestParser {
+	return func(ctx context.Context, r *http.Request) (*Request, error) {
+		variables := mux.Vars(r)
+		id, err := strconv.ParseInt(variables["id"], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot parse account id")
+		}
+
+		query := r.URL.Query()
+		req := &Request{
+			Account:   id,
+			Limit:     DefaultLimit,
+			Direction: account.Direction(query.Get("direction")),
+			Type:      account.TransactionType(query.Get("type")),
+		}
+
+		if cursor := query.Get("cursor"); cursor != "" {
+			if req.Cursor, err = DecodeCursor(cursor); err != nil {
+				return nil, errors.Wrap(err, "cannot parse cursor")
+			}
+		}
+
+		if limit := query.Get("limit"); limit != "" {
+			if req.Limit, err = strconv.Atoi(limit); err != nil {
+				return nil, errors.Wrap(err, "cannot parse limit")
+			}
+		}
+
+		if from := query.Get("from"); from != "" {
+			if req.From, err = time.Parse(time.RFC3339, from); err != nil {
+				return nil, errors.Wrap(err, "cannot parse from, expected RFC3339 timestamp")
+			}
+		}
+
+		if to := query.Get("to"); to != "" {
+			if req.To, err = time.Parse(time.RFC3339, to); err != nil {
+				return nil, errors.Wrap(err, "cannot parse to, expected RFC3339 timestamp")
+			}
+		}
+
+		return req, nil
+	}
+}
+
+func EncodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func DecodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.Wrap(err, "cursor is not valid base64")
+	}
+
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("cursor is malformed")
+	}
+
+	return id, nil
+}
@@ -0,0 +1,54 @@
+package transactions
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+type Response struct {
+	Transactions []*TransactionResponse `json:"transactions"`
+	NextCursor   string                 `json:"next_cursor,omitempty"`
+}
+
+type TransactionResponse struct {
+	Id           int64                   `json:"id"`
+	EntryId      int64                   `json:"entry_id"`
+	Type         account.TransactionType `json:"type"`
+	Direction    account.Direction       `json:"direction"`
+	Counterparty *int64                  `json:"counterparty"`
+	Amount       money.Amount            `json:"amount"`
+	Currency     currency.Code           `json:"currency"`
+	Balance      money.Amount            `json:"balance"`
+	CreatedAt    time.Time               `json:"created_at"`
+}
+
+func NewResponse(transactions []*account.Transaction, limit int) *Response {
+	res := &Response{
+		Transactions: make([]*TransactionResponse, 0, len(transactions)),
+	}
+
+	// One more record than requested is fetched to detect whether there is a next page
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+		res.NextCursor = EncodeCursor(transactions[limit-1].Id)
+	}
+
+	for _, transaction := range transactions {
+		res.Transactions = append(res.Transactions, &TransactionResponse{
+			Id:           transaction.Id,
+			EntryId:      transaction.EntryId,
+			Type:         transaction.Type,
+			Direction:    transaction.Direction,
+			Counterparty: transaction.CounterpartyId,
+			Amount:       transaction.Amount,
+			Currency:     transaction.Amount.Currency(),
+			Balance:      transaction.Balance,
+			CreatedAt:    transaction.CreatedAt,
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,177 @@
+package account_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type transactionsResponse struct {
+	Transactions []struct {
+		Id           int64     `json:"id"`
+		EntryId      int64     `json:"entry_id"`
+		Type         string    `json:"type"`
+		Direction    string    `json:"direction"`
+		Counterparty *int64    `json:"counterparty"`
+		Amount       int       `json:"amount"`
+		Balance      int       `json:"balance"`
+		CreatedAt    time.Time `json:"created_at"`
+	} `json:"transactions"`
+	NextCursor string `json:"next_cursor"`
+}
+
+func TestTransactions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 0)
+		targetAccId := insertAccount(t, db, 0)
+
+		// Prepare history: top-up 300, transfer 100 and 50 to target
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/topup", srcAccId), bytes.NewBuffer([]byte("{\"amount\":300}")))
+		runApplication(t, db, w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		for _, amount := range []int{100, 50} {
+			w = httptest.NewRecorder()
+			r, _ = http.NewRequest("POST", fmt.Sprintf("/account/%d/transfer", srcAccId), bytes.NewBuffer([]byte(fmt.Sprintf("{\"amount\":%d,\"target\":%d}", amount, targetAccId))))
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+
+		t.Run("newest first", func(t *testing.T) {
+			res := listTransactions(t, db, srcAccId, url.Values{})
+			assert.Len(t, res.Transactions, 3)
+			assert.Empty(t, res.NextCursor)
+
+			assert.Equal(t, "transfer", res.Transactions[0].Type)
+			assert.Equal(t, "out", res.Transactions[0].Direction)
+			assert.Equal(t, targetAccId, *res.Transactions[0].Counterparty)
+			assert.Equal(t, 50, res.Transactions[0].Amount)
+			assert.Equal(t, 150, res.Transactions[0].Balance)
+
+			assert.Equal(t, "transfer", res.Transactions[1].Type)
+			assert.Equal(t, 100, res.Transactions[1].Amount)
+			assert.Equal(t, 200, res.Transactions[1].Balance)
+
+			assert.Equal(t, "topup", res.Transactions[2].Type)
+			assert.Equal(t, "in", res.Transactions[2].Direction)
+			assert.Nil(t, res.Transactions[2].Counterparty)
+			assert.Equal(t, 300, res.Transactions[2].Amount)
+			assert.Equal(t, 300, res.Transactions[2].Balance)
+		})
+		t.Run("paginated", func(t *testing.T) {
+			firstPage := listTransactions(t, db, srcAccId, url.Values{"limit": {"2"}})
+			assert.Len(t, firstPage.Transactions, 2)
+			assert.NotEmpty(t, firstPage.NextCursor)
+
+			secondPage := listTransactions(t, db, srcAccId, url.Values{"limit": {"2"}, "cursor": {firstPage.NextCursor}})
+			assert.Len(t, secondPage.Transactions, 1)
+			assert.Empty(t, secondPage.NextCursor)
+			assert.Equal(t, "topup", secondPage.Transactions[0].Type)
+		})
+		t.Run("filtered", func(t *testing.T) {
+			res := listTransactions(t, db, srcAccId, url.Values{"type": {"topup"}})
+			assert.Len(t, res.Transactions, 1)
+
+			res = listTransactions(t, db, srcAccId, url.Values{"direction": {"out"}})
+			assert.Len(t, res.Transactions, 2)
+
+			res = listTransactions(t, db, targetAccId, url.Values{"direction": {"in"}, "type": {"transfer"}})
+			assert.Len(t, res.Transactions, 2)
+			assert.Equal(t, 150, res.Transactions[0].Balance)
+			assert.Equal(t, srcAccId, *res.Transactions[0].Counterparty)
+
+			res = listTransactions(t, db, srcAccId, url.Values{"from": {time.Now().Add(time.Hour).Format(time.RFC3339)}})
+			assert.Len(t, res.Transactions, 0)
+
+			res = listTransactions(t, db, srcAccId, url.Values{
+				"from": {time.Now().Add(-time.Hour).Format(time.RFC3339)},
+				"to":   {time.Now().Add(time.Hour).Format(time.RFC3339)},
+			})
+			assert.Len(t, res.Transactions, 3)
+		})
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("account does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("GET", fmt.Sprintf("/account/%d/transactions", 0), nil)
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+		t.Run("bad query", func(t *testing.T) {
+			type testCase struct {
+				query              url.Values
+				expectedStatusCode int
+			}
+
+			testCases := map[string]testCase{
+				"malformed cursor": {
+					query:              url.Values{"cursor": {"!"}},
+					expectedStatusCode: http.StatusBadRequest,
+				},
+				"malformed limit": {
+					query:              url.Values{"limit": {"ten"}},
+					expectedStatusCode: http.StatusBadRequest,
+				},
+				"malformed date": {
+					query:              url.Values{"from": {"yesterday"}},
+					expectedStatusCode: http.StatusBadRequest,
+				},
+				"limit out of range": {
+					query:              url.Values{"limit": {"1000"}},
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+				"inverted date range": {
+					query:              url.Values{"from": {"2024-02-01T00:00:00Z"}, "to": {"2024-01-01T00:00:00Z"}},
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+				"unknown direction": {
+					query:              url.Values{"direction": {"sideways"}},
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+				"unknown type": {
+					query:              url.Values{"type": {"gift"}},
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+			}
+
+			for testName, test := range testCases {
+				t.Run(testName, func(t *testing.T) {
+					db, onClose := setupDb(t)
+					defer onClose()
+
+					accId := insertAccount(t, db, 0)
+
+					w := httptest.NewRecorder()
+					r, _ := http.NewRequest("GET", fmt.Sprintf("/account/%d/transactions?%s", accId, test.query.Encode()), nil)
+					runApplication(t, db, w, r)
+					assert.Equal(t, test.expectedStatusCode, w.Code)
+				})
+			}
+		})
+	})
+}
+
+func listTransactions(t *testing.T, db *sql.DB, accId int64, query url.Values) *transactionsResponse {
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", fmt.Sprintf("/account/%d/transactions?%s", accId, query.Encode()), nil)
+	runApplication(t, db, w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	res := &transactionsResponse{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(res))
+	return res
+}
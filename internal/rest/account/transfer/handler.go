@@ -1,35 +1,39 @@
 package transfer
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/fx"
+	"github.com/ktsivkov/su-exc/internal/jwt"
+	"github.com/ktsivkov/su-exc/internal/limits"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/negotiate"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
 )
 
-func Handler(requestParser RequestParser, finder account.Finder, transferer account.Transferrer, logger *slog.Logger) http.HandlerFunc {
+func Handler(requestParser RequestParser, finder account.Finder, transferer account.Transferrer, exchanger account.Exchanger, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
 		req, err := requestParser(ctx, r)
 		if err != nil {
 			logger.WarnContext(ctx, "cannot parse request", "error", err)
-			w.WriteHeader(http.StatusBadRequest)
-			if _, err := fmt.Fprintf(w, "request parsing error: %s", err); err != nil {
-				logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
-			}
+			problem.Write(w, r, problem.Malformed(err), logger)
 			return
 		}
 
 		if err := req.Validate(); err != nil {
 			logger.WarnContext(ctx, "invalid request", "error", err)
-			w.WriteHeader(http.StatusUnprocessableEntity)
-			if _, err := fmt.Fprintf(w, "request validation error: %s", err); err != nil {
-				logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
-			}
+			problem.Write(w, r, problem.Invalid(err), logger)
 			return
 		}
 
@@ -37,18 +41,20 @@ func Handler(requestParser RequestParser, finder account.Finder, transferer acco
 		if err != nil {
 			if errors.Is(err, account.ErrDoesNotExist) {
 				logger.WarnContext(ctx, "source account does not exist", "id", req.Source)
-				w.WriteHeader(http.StatusNotFound)
-				if _, err := fmt.Fprintf(w, "source account with id=%d does not exist", req.Source); err != nil {
-					logger.ErrorContext(ctx, "cannot write bytes to http response", "error", err)
-				}
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("source account with id=%d does not exist", req.Source)), logger)
 				return
 			}
 
 			logger.ErrorContext(ctx, "source account existence check failed", "error", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			if _, err := fmt.Fprint(w, "could not process the request"); err != nil {
-				logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
-			}
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		// Users only move money out of the accounts they own, privileged users out of any. The accounts of others are
+		// answered as missing, as they would otherwise tell the ids in use
+		if user := jwt.From(ctx); user != nil && !user.Privileged && !sourceAccount.IsOwnedBy(user.Subject) {
+			logger.WarnContext(ctx, "source account not owned by the caller", "id", req.Source, "subject", user.Subject)
+			problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("source account with id=%d does not exist", req.Source)), logger)
 			return
 		}
 
@@ -56,38 +62,95 @@ func Handler(requestParser RequestParser, finder account.Finder, transferer acco
 		if err != nil {
 			if errors.Is(err, account.ErrDoesNotExist) {
 				logger.WarnContext(ctx, "target account does not exist", "id", req.Data.Target)
-				w.WriteHeader(http.StatusNotFound)
-				if _, err := fmt.Fprintf(w, "target account with id=%d does not exist", req.Data.Target); err != nil {
-					logger.ErrorContext(ctx, "cannot write bytes to http response", "error", err)
-				}
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("target account with id=%d does not exist", req.Data.Target)), logger)
 				return
 			}
 
 			logger.ErrorContext(ctx, "target account existence check failed", "error", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			if _, err := fmt.Fprint(w, "could not process the request"); err != nil {
-				logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
-			}
+			problem.Write(w, r, problem.Internal(), logger)
 			return
 		}
 
-		if err := transferer.Transfer(ctx, sourceAccount, targetAccount, req.Data.Amount); err != nil {
-			if errors.Is(err, account.ErrInsufficientBalance) {
-				w.WriteHeader(http.StatusBadRequest)
-				if _, err := fmt.Fprint(w, err); err != nil {
-					logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		amount, err := req.Data.Amount.In(sourceAccount.Currency)
+		if err != nil {
+			logger.WarnContext(ctx, "invalid request amount", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		var completed *account.Transfer
+		if req.Data.Quote != "" {
+			completed, err = exchanger.Exchange(ctx, sourceAccount, targetAccount, amount, req.Data.Quote)
+		} else {
+			completed, err = transferer.Transfer(ctx, sourceAccount, targetAccount, amount)
+		}
+
+		if err != nil {
+			var violation *limits.Violation
+			if errors.As(err, &violation) {
+				logger.WarnContext(ctx, "transfer limit exceeded", "error", err)
+				// Too many transfers can simply be retried once the period resets, too much money cannot be sent as is
+				status := http.StatusUnprocessableEntity
+				if violation.Rule.Metric == limits.MetricCount {
+					status = http.StatusTooManyRequests
+					w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(time.Until(violation.ResetsAt).Seconds())))))
 				}
+				problem.Write(w, r, problem.FromError(status, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrInsufficientBalance) {
+				problem.Write(w, r, problem.FromError(http.StatusBadRequest, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrAccountFrozen) || errors.Is(err, account.ErrAccountClosed) {
+				logger.WarnContext(ctx, "account is not active", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrCurrencyMismatch) {
+				logger.WarnContext(ctx, "transfer currency mismatch", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			if errors.Is(err, money.ErrOverflow) {
+				logger.WarnContext(ctx, "transfer would overflow the target balance", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusUnprocessableEntity, err), logger)
 				return
 			}
 
-			logger.ErrorContext(ctx, "account top-up failed", "error", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			if _, err := fmt.Fprint(w, "could not process the request"); err != nil {
-				logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+			if isQuoteError(err) {
+				logger.WarnContext(ctx, "exchange quote rejected", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusUnprocessableEntity, err), logger)
+				return
 			}
+
+			logger.ErrorContext(ctx, "account transfer failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		if negotiate.Legacy(r) {
+			w.WriteHeader(http.StatusOK)
 			return
 		}
 
+		w.Header().Set("Content-Type", negotiate.JSON)
 		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(NewResponse(completed)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
+
+func isQuoteError(err error) bool {
+	for _, quoteErr := range []error{fx.ErrQuoteNotFound, fx.ErrQuoteExpired, fx.ErrQuoteUsed, fx.ErrQuoteCurrencyMismatch, fx.ErrConvertedAmountTooSmall, fx.ErrConvertedAmountTooLarge} {
+		if errors.Is(err, quoteErr) {
+			return true
+		}
 	}
+	return false
 }
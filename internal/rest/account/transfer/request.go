@@ -2,10 +2,13 @@ package transfer
 
 import (
 	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
 )
 
-var ErrRequestDataNotSet = errors.New("request data is mandatory")
-var ErrRequestInvalidAmountLt0 = errors.New("amount cannot be less than 1")
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestInvalidAmountLt0 = problem.Field("amount", "amount_not_positive", "amount must be positive")
 
 type Request struct {
 	Source int64
@@ -26,11 +29,14 @@ func (r *Request) Validate() error {
 
 type RequestData struct {
 	Target int64 `json:"target"`
-	Amount int   `json:"amount"`
+	// Amount is in the currency of the source account, either as an integer number of minor units or as a decimal string.
+	Amount money.Amount `json:"amount"`
+	// Quote is the id of a locked exchange rate quote, it is required when the accounts hold different currencies.
+	Quote string `json:"quote"`
 }
 
 func (d *RequestData) Validate() error {
-	if d.Amount < 1 {
+	if !d.Amount.IsPositive() {
 		return ErrRequestInvalidAmountLt0
 	}
 
@@ -0,0 +1,39 @@
+package transfer
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+type Response struct {
+	Id       int64         `json:"id"`
+	Source   int64         `json:"source"`
+	Target   int64         `json:"target"`
+	Amount   money.Amount  `json:"amount"`
+	Currency currency.Code `json:"currency"`
+	// TargetAmount is what the target has been credited, it differs from Amount only for exchanges.
+	TargetAmount   money.Amount           `json:"target_amount"`
+	TargetCurrency currency.Code          `json:"target_currency"`
+	Status         account.TransferStatus `json:"status"`
+	CreatedAt      time.Time              `json:"created_at"`
+	// SourceBalance is the balance of the source right after the transfer.
+	SourceBalance money.Amount `json:"source_balance"`
+}
+
+func NewResponse(completed *account.Transfer) *Response {
+	return &Response{
+		Id:             completed.Id,
+		Source:         completed.SourceId,
+		Target:         completed.TargetId,
+		Amount:         completed.Amount,
+		Currency:       completed.Amount.Currency(),
+		TargetAmount:   completed.TargetAmount,
+		TargetCurrency: completed.TargetAmount.Currency(),
+		Status:         completed.Status(),
+		CreatedAt:      completed.CreatedAt,
+		SourceBalance:  completed.SourceBalance,
+	}
+}
@@ -0,0 +1,145 @@
+package account_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferConcurrency(t *testing.T) {
+	t.Run("single source is never overdrawn", func(t *testing.T) {
+		srcAccBalanceInitial := 100
+		transferAmount := 1
+		parallelTransfers := 300
+
+		db, onClose := setupDb(t)
+		defer onClose()
+		db.SetMaxOpenConns(20)
+
+		srcAccId := insertAccount(t, db, srcAccBalanceInitial)
+		targetAccIds := []int64{insertAccount(t, db, 0), insertAccount(t, db, 0), insertAccount(t, db, 0)}
+
+		app := application(t, db, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+		stopWatching := watchBalance(t, db, srcAccId)
+
+		var succeeded, rejected atomic.Int64
+		var wg sync.WaitGroup
+		for i := 0; i < parallelTransfers; i++ {
+			wg.Add(1)
+			go func(targetAccId int64) {
+				defer wg.Done()
+				switch code := sendTransfer(app, srcAccId, targetAccId, transferAmount); code {
+				case http.StatusOK:
+					succeeded.Add(1)
+				case http.StatusBadRequest:
+					rejected.Add(1)
+				default:
+					t.Errorf("unexpected status code %d", code)
+				}
+			}(targetAccIds[i%len(targetAccIds)])
+		}
+		wg.Wait()
+		lowestBalance := stopWatching()
+
+		assert.Equal(t, int64(srcAccBalanceInitial/transferAmount), succeeded.Load())
+		assert.Equal(t, int64(parallelTransfers-srcAccBalanceInitial/transferAmount), rejected.Load())
+		assert.GreaterOrEqual(t, lowestBalance, 0)
+
+		assert.Equal(t, 0, accountBalance(t, db, srcAccId))
+		targetsTotal := 0
+		for _, targetAccId := range targetAccIds {
+			targetsTotal += accountBalance(t, db, targetAccId)
+		}
+		assert.Equal(t, srcAccBalanceInitial, targetsTotal)
+	})
+	t.Run("opposite directions do not deadlock", func(t *testing.T) {
+		accBalanceInitial := 50
+		transferAmount := 1
+		parallelTransfers := 200
+
+		db, onClose := setupDb(t)
+		defer onClose()
+		db.SetMaxOpenConns(20)
+
+		firstAccId := insertAccount(t, db, accBalanceInitial)
+		secondAccId := insertAccount(t, db, accBalanceInitial)
+
+		app := application(t, db, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+		var wg sync.WaitGroup
+		for i := 0; i < parallelTransfers; i++ {
+			source, target := firstAccId, secondAccId
+			if i%2 == 1 {
+				source, target = secondAccId, firstAccId
+			}
+
+			wg.Add(1)
+			go func(source int64, target int64) {
+				defer wg.Done()
+				if code := sendTransfer(app, source, target, transferAmount); code != http.StatusOK && code != http.StatusBadRequest {
+					t.Errorf("unexpected status code %d", code)
+				}
+			}(source, target)
+		}
+		wg.Wait()
+
+		firstBalance := accountBalance(t, db, firstAccId)
+		secondBalance := accountBalance(t, db, secondAccId)
+		assert.GreaterOrEqual(t, firstBalance, 0)
+		assert.GreaterOrEqual(t, secondBalance, 0)
+		assert.Equal(t, 2*accBalanceInitial, firstBalance+secondBalance)
+	})
+}
+
+func sendTransfer(app http.Handler, source int64, target int64, amount int) int {
+	reqBodyJsonBytes, _ := json.Marshal(map[string]any{
+		"target": target,
+		"amount": amount,
+	})
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/transfer", source), bytes.NewBuffer(reqBodyJsonBytes))
+	r.Header.Set("Content-Type", "application/json")
+	app.ServeHTTP(w, r)
+
+	return w.Code
+}
+
+// watchBalance polls the balance of the given account until the returned function is called,
+// which stops the polling and reports the lowest balance observed in the meantime.
+func watchBalance(t *testing.T, db *sql.DB, accId int64) func() int {
+	done := make(chan struct{})
+	lowest := make(chan int)
+
+	go func(lowestBalance int) {
+		for {
+			select {
+			case <-done:
+				lowest <- lowestBalance
+				return
+			default:
+			}
+
+			var balance int
+			if err := db.QueryRow("SELECT balance FROM accounts WHERE id=$1", accId).Scan(&balance); err != nil {
+				t.Errorf("cannot read balance: %s", err)
+			}
+			lowestBalance = min(lowestBalance, balance)
+		}
+	}(accountBalance(t, db, accId))
+
+	return func() int {
+		close(done)
+		return <-lowest
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -51,9 +52,92 @@ func TestTransfer(t *testing.T) {
 
 		assert.Equal(t, transferAmount, targetAccBalance)
 		assert.Equal(t, srcAccBalanceInitial-transferAmount, srcAccBalance)
+
+		res := transferResponse(t, w)
+		assert.NotZero(t, res.Id)
+		assert.Equal(t, srcAccId, res.Source)
+		assert.Equal(t, targetAccId, res.Target)
+		assert.Equal(t, int64(transferAmount), res.Amount)
+		assert.Equal(t, "EUR", res.Currency)
+		assert.Equal(t, int64(transferAmount), res.TargetAmount)
+		assert.Equal(t, "EUR", res.TargetCurrency)
+		assert.Equal(t, "completed", res.Status)
+		assert.False(t, res.CreatedAt.IsZero())
+		assert.Equal(t, int64(srcAccBalance), res.SourceBalance)
+
+		var entryId int64
+		assert.NoError(t, db.QueryRow("SELECT id FROM journal_entries").Scan(&entryId))
+		assert.Equal(t, entryId, res.Id)
+	})
+
+	t.Run("success as plain text", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 200)
+		targetAccId := insertAccount(t, db, 0)
+
+		reqBodyJsonBytes, _ := json.Marshal(map[string]any{
+			"target": targetAccId,
+			"amount": 100,
+		})
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/transfer", srcAccId), bytes.NewBuffer(reqBodyJsonBytes))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept", "text/plain, application/json;q=0.5")
+
+		runApplication(t, db, w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, 100, accountBalance(t, db, targetAccId))
+	})
+
+	t.Run("success in major units", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		srcAccId := insertAccount(t, db, 200)
+		targetAccId := insertAccount(t, db, 0)
+
+		reqBodyJsonBytes, _ := json.Marshal(map[string]any{
+			"target": targetAccId,
+			"amount": "1.5",
+		})
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/transfer", srcAccId), bytes.NewBuffer(reqBodyJsonBytes))
+		r.Header.Set("Content-Type", "application/json")
+
+		runApplication(t, db, w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 50, accountBalance(t, db, srcAccId))
+		assert.Equal(t, 150, accountBalance(t, db, targetAccId))
 	})
 
 	t.Run("fail", func(t *testing.T) {
+		t.Run("currency mismatch", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			srcAccId := insertCurrencyAccount(t, db, 200, "EUR")
+			targetAccId := insertCurrencyAccount(t, db, 0, "USD")
+
+			reqBody := map[string]any{
+				"target": targetAccId,
+				"amount": 100,
+			}
+			reqBodyJsonBytes, _ := json.Marshal(reqBody)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/transfer", srcAccId), bytes.NewBuffer(reqBodyJsonBytes))
+			r.Header.Set("Content-Type", "application/json")
+
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusConflict, w.Code)
+			assert.Equal(t, 200, accountBalance(t, db, srcAccId))
+			assert.Equal(t, 0, accountBalance(t, db, targetAccId))
+		})
 		t.Run("insufficient balance", func(t *testing.T) {
 			srcAccBalanceInitial := 200
 			transferAmount := 300
@@ -166,6 +250,10 @@ func TestTransfer(t *testing.T) {
 					body:               bytes.NewBuffer([]byte(fmt.Sprintf("{\"amount\":0, \"target\": %d}", targetAccId))),
 					expectedStatusCode: http.StatusUnprocessableEntity,
 				},
+				"too many decimal places": {
+					body:               bytes.NewBuffer([]byte(fmt.Sprintf("{\"amount\":\"0.001\", \"target\": %d}", targetAccId))),
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
 			}
 			for testName, test := range testCases {
 				t.Run(testName, func(t *testing.T) {
@@ -180,3 +268,24 @@ func TestTransfer(t *testing.T) {
 		})
 	})
 }
+
+type transferView struct {
+	Id             int64     `json:"id"`
+	Source         int64     `json:"source"`
+	Target         int64     `json:"target"`
+	Amount         int64     `json:"amount"`
+	Currency       string    `json:"currency"`
+	TargetAmount   int64     `json:"target_amount"`
+	TargetCurrency string    `json:"target_currency"`
+	Status         string    `json:"status"`
+	CreatedAt      time.Time `json:"created_at"`
+	SourceBalance  int64     `json:"source_balance"`
+}
+
+func transferResponse(t *testing.T, w *httptest.ResponseRecorder) *transferView {
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	res := &transferView{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(res))
+	return res
+}
@@ -1,19 +1,31 @@
 package account_test
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/json"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	_ "github.com/lib/pq"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/apikey"
+	"github.com/ktsivkov/su-exc/internal/audit"
+	"github.com/ktsivkov/su-exc/internal/fx"
+	"github.com/ktsivkov/su-exc/internal/idempotency"
+	"github.com/ktsivkov/su-exc/internal/jwt"
+	"github.com/ktsivkov/su-exc/internal/limits"
 	"github.com/ktsivkov/su-exc/internal/rest"
+	"github.com/ktsivkov/su-exc/internal/rest/middleware"
 )
 
 func runApplication(t *testing.T, db *sql.DB, w *httptest.ResponseRecorder, r *http.Request) {
@@ -25,20 +37,111 @@ func runApplication(t *testing.T, db *sql.DB, w *httptest.ResponseRecorder, r *h
 	}(t, fileName)
 
 	logger := slog.New(slog.NewJSONHandler(file, nil))
-	accountRepo, err := account.NewRepository(db)
+	application(t, db, logger).ServeHTTP(w, r)
+}
+
+// application serves the API as an admin, requests sent without an API key are authenticated by a key granted every scope.
+func application(t *testing.T, db *sql.DB, logger *slog.Logger) http.Handler {
+	adminKey := adminApiKey(t, db)
+	router := apiRouter(t, db, logger)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" && r.Header.Get(middleware.ApiKeyHeader) == "" {
+			r.Header.Set("Authorization", "Bearer "+adminKey)
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+var adminKeysMu sync.Mutex
+var adminKeys = make(map[*sql.DB]string)
+
+// adminApiKey issues the key application authenticates requests with once per database, so that the requests of a
+// test are all made by the same actor.
+func adminApiKey(t *testing.T, db *sql.DB) string {
+	adminKeysMu.Lock()
+	defer adminKeysMu.Unlock()
+
+	if key, ok := adminKeys[db]; ok {
+		return key
+	}
+	_, key := issueApiKey(t, db, nil, apikey.ScopeAdmin)
+	adminKeys[db] = key
+	return key
+}
+
+func apiRouter(t *testing.T, db *sql.DB, logger *slog.Logger) http.Handler {
+	accountRepo, err := account.NewRepository(db, time.Hour)
+	assert.NoError(t, err)
+	idempotencyRepo, err := idempotency.NewRepository(db, time.Hour, time.Minute)
+	assert.NoError(t, err)
+	rateProvider, err := fx.NewConfigRateProvider(map[string]string{"EUR/USD": "1.25", "EUR/JPY": "160"})
+	assert.NoError(t, err)
+	fxRepo, err := fx.NewRepository(db, rateProvider, time.Minute)
+	assert.NoError(t, err)
+	limitsRepo, err := limits.NewRepository(db)
+	assert.NoError(t, err)
+	auditRepo, err := audit.NewRepository(db)
+	assert.NoError(t, err)
+	verifier, err := jwt.NewVerifier(tokenSecret, nil)
 	assert.NoError(t, err)
-	rest.ApiRouter(accountRepo, logger).ServeHTTP(w, r)
+	return rest.ApiRouter(accountRepo, idempotencyRepo, fxRepo, limitsRepo, webhookRepository(t, db), auditRepo, apiKeyRepository(t, db), customerRepository(t, db), verifier, []string{"admin"}, logger)
 }
 
 func setupDb(t *testing.T) (*sql.DB, func()) {
 	db, err := sql.Open("postgres", os.Getenv("POSTGRES_URI_TEST"))
 	assert.NoError(t, err)
 
-	// Truncate accounts table
-	_, err = db.Exec("TRUNCATE TABLE accounts RESTART IDENTITY")
+	// Truncate tables
+	_, err = db.Exec("TRUNCATE TABLE accounts, journal_entries, postings, transactions, idempotency_keys, fx_quotes, holds, account_tiers, transfer_limits, scheduled_transfers, scheduled_transfer_runs, balance_snapshots, outbox, webhook_subscriptions, webhook_deliveries, audit_log, api_keys, customers RESTART IDENTITY")
 	assert.NoError(t, err)
 
 	return db, func() {
 		assert.NoError(t, db.Close())
 	}
 }
+
+func insertAccount(t *testing.T, db *sql.DB, balance int) int64 {
+	row := db.QueryRow("INSERT INTO accounts (balance) VALUES ($1) RETURNING id", balance)
+	var id int64
+	assert.NoError(t, row.Scan(&id))
+	return id
+}
+
+func insertCurrencyAccount(t *testing.T, db *sql.DB, balance int, currency string) int64 {
+	row := db.QueryRow("INSERT INTO accounts (balance, currency) VALUES ($1, $2) RETURNING id", balance, currency)
+	var id int64
+	assert.NoError(t, row.Scan(&id))
+	return id
+}
+
+func accountBalance(t *testing.T, db *sql.DB, id int64) int {
+	row := db.QueryRow("SELECT balance FROM accounts WHERE id=$1", id)
+	var balance int
+	assert.NoError(t, row.Scan(&balance))
+	return balance
+}
+
+// postJson sends a POST request with the given body encoded as JSON, or with no body at all when it is nil.
+func postJson(t *testing.T, db *sql.DB, path string, body map[string]any) *httptest.ResponseRecorder {
+	return sendJson(t, db, "POST", path, body)
+}
+
+func putJson(t *testing.T, db *sql.DB, path string, body map[string]any) *httptest.ResponseRecorder {
+	return sendJson(t, db, "PUT", path, body)
+}
+
+func sendJson(t *testing.T, db *sql.DB, method string, path string, body map[string]any) *httptest.ResponseRecorder {
+	var reqBody io.Reader = http.NoBody
+	if body != nil {
+		reqBodyJsonBytes, _ := json.Marshal(body)
+		reqBody = bytes.NewBuffer(reqBodyJsonBytes)
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(method, path, reqBody)
+	r.Header.Set("Content-Type", "application/json")
+	runApplication(t, db, w, r)
+
+	return w
+}
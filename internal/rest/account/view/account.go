@@ -0,0 +1,33 @@
+package view
+
+import (
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+type Account struct {
+	Id       int64          `json:"id"`
+	Currency currency.Code  `json:"currency"`
+	Status   account.Status `json:"status"`
+	Balance  money.Amount   `json:"balance"`
+	// Available is the balance minus the funds reserved by active holds.
+	Available money.Amount `json:"available_balance"`
+	// OverdraftLimit is how far below zero the balance may go.
+	OverdraftLimit money.Amount `json:"overdraft_limit"`
+	Owner          string       `json:"owner,omitempty"`
+	CustomerId     *int64       `json:"customer_id,omitempty"`
+}
+
+func NewAccount(record *account.Record) *Account {
+	return &Account{
+		Id:             record.Id,
+		Currency:       record.Currency,
+		Status:         record.Status,
+		Balance:        record.Balance,
+		Available:      record.Available,
+		OverdraftLimit: record.OverdraftLimit,
+		Owner:          record.Owner,
+		CustomerId:     record.CustomerId,
+	}
+}
@@ -0,0 +1,242 @@
+package account_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/outbox"
+	"github.com/ktsivkov/su-exc/internal/webhook"
+)
+
+func TestRegisterWebhook(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		w := postJson(t, db, "/webhooks", map[string]any{"url": "https://partner.example/callbacks", "event_types": []string{"TransferCompleted", "AccountToppedUp"}})
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var res map[string]any
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, float64(1), res["id"])
+		assert.Equal(t, "https://partner.example/callbacks", res["url"])
+		assert.Equal(t, []any{"AccountToppedUp", "TransferCompleted"}, res["event_types"])
+		assert.True(t, strings.HasPrefix(res["secret"].(string), "whsec_"))
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("no body", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			assert.Equal(t, http.StatusBadRequest, postJson(t, db, "/webhooks", nil).Code)
+		})
+		t.Run("invalid url", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			assert.Equal(t, http.StatusUnprocessableEntity, postJson(t, db, "/webhooks", map[string]any{"url": "partner.example/callbacks", "event_types": []string{"AccountCreated"}}).Code)
+		})
+		t.Run("no event types", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			assert.Equal(t, http.StatusUnprocessableEntity, postJson(t, db, "/webhooks", map[string]any{"url": "https://partner.example/callbacks", "event_types": []string{}}).Code)
+		})
+		t.Run("unknown event type", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			assert.Equal(t, http.StatusUnprocessableEntity, postJson(t, db, "/webhooks", map[string]any{"url": "https://partner.example/callbacks", "event_types": []string{"MoneyPrinted"}}).Code)
+		})
+	})
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	t.Run("signed delivery of subscribed events", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		receiver := newWebhookReceiver(http.StatusNoContent)
+		defer receiver.Close()
+
+		secret := registerWebhook(t, db, receiver.URL, "AccountToppedUp")
+		accId := insertAccount(t, db, 0)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 100}).Code)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/admin/accounts/%d/freeze", accId), nil).Code)
+
+		relayEvents(t, db)
+		assert.Equal(t, 1, dispatchWebhooks(t, db))
+
+		requests := receiver.Requests()
+		assert.Len(t, requests, 1)
+		assert.Equal(t, "AccountToppedUp", requests[0].Header.Get(webhook.HeaderEventType))
+		timestamp, err := strconv.ParseInt(requests[0].Header.Get(webhook.HeaderTimestamp), 10, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, webhook.Sign(secret, time.Unix(timestamp, 0), requests[0].Body), requests[0].Header.Get(webhook.HeaderSignature))
+		var event map[string]any
+		assert.NoError(t, json.Unmarshal(requests[0].Body, &event))
+		assert.Equal(t, "AccountToppedUp", event["type"])
+		assert.Equal(t, float64(100), event["payload"].(map[string]any)["amount"])
+
+		deliveries := webhookDeliveries(t, db, "/webhooks/1/deliveries")
+		assert.Len(t, deliveries, 1)
+		assert.Equal(t, "succeeded", deliveries[0]["status"])
+		assert.Equal(t, float64(1), deliveries[0]["attempts"])
+		assert.Equal(t, float64(http.StatusNoContent), deliveries[0]["last_status_code"])
+		assert.NotNil(t, deliveries[0]["delivered_at"])
+	})
+	t.Run("events published again are delivered once", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		receiver := newWebhookReceiver(http.StatusOK)
+		defer receiver.Close()
+
+		registerWebhook(t, db, receiver.URL, "AccountToppedUp")
+		accId := insertAccount(t, db, 0)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 100}).Code)
+
+		relayEvents(t, db)
+		_, err := db.Exec("UPDATE outbox SET published_at = NULL")
+		assert.NoError(t, err)
+		relayEvents(t, db)
+
+		assert.Equal(t, 1, dispatchWebhooks(t, db))
+		assert.Len(t, receiver.Requests(), 1)
+	})
+	t.Run("failed deliveries are retried until dead", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		receiver := newWebhookReceiver(http.StatusInternalServerError)
+		defer receiver.Close()
+
+		registerWebhook(t, db, receiver.URL, "AccountToppedUp")
+		accId := insertAccount(t, db, 0)
+		assert.Equal(t, http.StatusOK, postJson(t, db, fmt.Sprintf("/account/%d/topup", accId), map[string]any{"amount": 100}).Code)
+		relayEvents(t, db)
+
+		assert.Equal(t, 0, dispatchWebhooks(t, db))
+		deliveries := webhookDeliveries(t, db, "/webhooks/1/deliveries")
+		assert.Equal(t, "pending", deliveries[0]["status"])
+		assert.Equal(t, float64(1), deliveries[0]["attempts"])
+		assert.Equal(t, float64(http.StatusInternalServerError), deliveries[0]["last_status_code"])
+		assert.NotNil(t, deliveries[0]["next_attempt_at"])
+
+		// The backoff of the test repository is short enough for the retries to be due right away
+		for i := 0; i < webhookMaxAttempts; i++ {
+			time.Sleep(20 * time.Millisecond)
+			dispatchWebhooks(t, db)
+		}
+
+		assert.Len(t, receiver.Requests(), webhookMaxAttempts)
+		deliveries = webhookDeliveries(t, db, "/webhooks/1/deliveries?status=dead")
+		assert.Len(t, deliveries, 1)
+		assert.Equal(t, float64(webhookMaxAttempts), deliveries[0]["attempts"])
+		assert.Nil(t, deliveries[0]["next_attempt_at"])
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("webhook does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			assert.Equal(t, http.StatusNotFound, sendJson(t, db, "GET", "/webhooks/1/deliveries", nil).Code)
+		})
+		t.Run("invalid status", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			registerWebhook(t, db, "https://partner.example/callbacks", "AccountCreated")
+
+			assert.Equal(t, http.StatusUnprocessableEntity, sendJson(t, db, "GET", "/webhooks/1/deliveries?status=lost", nil).Code)
+		})
+	})
+}
+
+const webhookMaxAttempts = 3
+
+func webhookRepository(t *testing.T, db *sql.DB) *webhook.Repository {
+	repo, err := webhook.NewRepository(db, webhookMaxAttempts, time.Millisecond)
+	assert.NoError(t, err)
+	return repo
+}
+
+// registerWebhook subscribes url to the given event types and returns the secret of the subscription.
+func registerWebhook(t *testing.T, db *sql.DB, url string, eventTypes ...string) string {
+	w := postJson(t, db, "/webhooks", map[string]any{"url": url, "event_types": eventTypes})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var res struct {
+		Secret string `json:"secret"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return res.Secret
+}
+
+func relayEvents(t *testing.T, db *sql.DB) {
+	relay, err := outbox.NewRelay(db, 100, time.Minute, webhookRepository(t, db))
+	assert.NoError(t, err)
+	_, err = relay.Publish(context.Background())
+	assert.NoError(t, err)
+}
+
+func dispatchWebhooks(t *testing.T, db *sql.DB) int {
+	dispatcher, err := webhook.NewDispatcher(webhookRepository(t, db), time.Second)
+	assert.NoError(t, err)
+	delivered, err := dispatcher.Dispatch(context.Background(), 10)
+	assert.NoError(t, err)
+	return delivered
+}
+
+func webhookDeliveries(t *testing.T, db *sql.DB, path string) []map[string]any {
+	w := sendJson(t, db, "GET", path, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var res struct {
+		Deliveries []map[string]any `json:"deliveries"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return res.Deliveries
+}
+
+type receivedRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+// webhookReceiver records the requests it receives and answers all of them with the same status code.
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []receivedRequest
+}
+
+func newWebhookReceiver(statusCode int) *webhookReceiver {
+	receiver := &webhookReceiver{}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		receiver.requests = append(receiver.requests, receivedRequest{Header: r.Header.Clone(), Body: body})
+		receiver.mu.Unlock()
+		w.WriteHeader(statusCode)
+	}))
+	return receiver
+}
+
+func (r *webhookReceiver) Requests() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
@@ -0,0 +1,81 @@
+package withdraw
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, finder account.Finder, withdrawer account.Withdrawer, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		sourceAccount, err := finder.FindById(ctx, req.Source)
+		if err != nil {
+			if errors.Is(err, account.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "account id not found", "id", req.Source)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("source account with id=%d does not exist", req.Source)), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "account existence check failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		code := req.Data.Currency
+		if code == "" {
+			code = sourceAccount.Currency
+		}
+
+		amount, err := req.Data.Amount.In(code)
+		if err != nil {
+			logger.WarnContext(ctx, "invalid request amount", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		if err := withdrawer.Withdraw(ctx, sourceAccount, amount); err != nil {
+			if errors.Is(err, account.ErrInsufficientBalance) {
+				problem.Write(w, r, problem.FromError(http.StatusBadRequest, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrAccountFrozen) || errors.Is(err, account.ErrAccountClosed) {
+				logger.WarnContext(ctx, "account is not active", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrCurrencyMismatch) {
+				logger.WarnContext(ctx, "withdrawal currency mismatch", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "account withdrawal failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
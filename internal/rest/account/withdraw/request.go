@@ -0,0 +1,48 @@
+package withdraw
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestInvalidAmountLt0 = problem.Field("amount", "amount_not_positive", "amount must be positive")
+
+type Request struct {
+	Source int64
+	Data   *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	// Amount is either an integer number of minor units or a decimal string of major units, e.g. 1234 or "12.34".
+	Amount money.Amount `json:"amount"`
+	// Currency is optional, the amount is in the currency of the account when omitted.
+	Currency currency.Code `json:"currency"`
+}
+
+func (d *RequestData) Validate() error {
+	if !d.Amount.IsPositive() {
+		return ErrRequestInvalidAmountLt0
+	}
+
+	if d.Currency != "" && !d.Currency.IsValid() {
+		return errors.Wrapf(currency.ErrUnsupported, "code=%q", d.Currency)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package account_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/ledger"
+)
+
+func TestWithdraw(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 250)
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/withdraw", accId), bytes.NewBuffer([]byte("{\"amount\":100}")))
+		r.Header.Set("Content-Type", "application/json")
+
+		runApplication(t, db, w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 150, accountBalance(t, db, accId))
+		assert.Equal(t, -100, postingsTotal(t, db, accId))
+		assert.Equal(t, 100, postingsTotal(t, db, ledger.PayoutAccountId))
+
+		res := listTransactions(t, db, accId, nil)
+		assert.Len(t, res.Transactions, 1)
+		assert.Equal(t, "withdrawal", res.Transactions[0].Type)
+		assert.Equal(t, "out", res.Transactions[0].Direction)
+		assert.Equal(t, 150, res.Transactions[0].Balance)
+	})
+	t.Run("success with the whole balance", func(t *testing.T) {
+		db, onClose := setupDb(t)
+		defer onClose()
+
+		accId := insertAccount(t, db, 250)
+
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/withdraw", accId), bytes.NewBuffer([]byte("{\"amount\":\"2.50\"}")))
+		r.Header.Set("Content-Type", "application/json")
+
+		runApplication(t, db, w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 0, accountBalance(t, db, accId))
+	})
+	t.Run("fail", func(t *testing.T) {
+		t.Run("insufficient balance", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/withdraw", accId), bytes.NewBuffer([]byte("{\"amount\":251}")))
+			r.Header.Set("Content-Type", "application/json")
+
+			runApplication(t, db, w, r)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, 250, accountBalance(t, db, accId))
+			assert.Equal(t, 0, postingsTotal(t, db, ledger.PayoutAccountId))
+		})
+		t.Run("funds reserved by a hold", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertAccount(t, db, 250)
+			authorizeHold(t, db, accId, insertAccount(t, db, 0), 200)
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/withdraw", accId), bytes.NewBuffer([]byte("{\"amount\":51}")))
+			r.Header.Set("Content-Type", "application/json")
+
+			runApplication(t, db, w, r)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, 250, accountBalance(t, db, accId))
+		})
+		t.Run("currency mismatch", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			accId := insertCurrencyAccount(t, db, 250, "USD")
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/withdraw", accId), bytes.NewBuffer([]byte("{\"amount\":100,\"currency\":\"EUR\"}")))
+			r.Header.Set("Content-Type", "application/json")
+
+			runApplication(t, db, w, r)
+
+			assert.Equal(t, http.StatusConflict, w.Code)
+			assert.Equal(t, 250, accountBalance(t, db, accId))
+		})
+		t.Run("account does not exist", func(t *testing.T) {
+			db, onClose := setupDb(t)
+			defer onClose()
+
+			w := httptest.NewRecorder()
+			r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/withdraw", 0), bytes.NewBuffer([]byte("{\"amount\":100}")))
+			r.Header.Set("Content-Type", "application/json")
+
+			runApplication(t, db, w, r)
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+		t.Run("bad request body", func(t *testing.T) {
+			type testCase struct {
+				body               io.Reader
+				expectedStatusCode int
+			}
+
+			testCases := map[string]testCase{
+				"no request body": {
+					body:               http.NoBody,
+					expectedStatusCode: http.StatusBadRequest,
+				},
+				"empty request body": {
+					body:               bytes.NewBuffer([]byte("null")),
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+				"invalid json request body": {
+					body:               bytes.NewBuffer([]byte("{\"}")),
+					expectedStatusCode: http.StatusBadRequest,
+				},
+				"invalid amount input": {
+					body:               bytes.NewBuffer([]byte("{\"amount\":0}")),
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+				"too many decimal places": {
+					body:               bytes.NewBuffer([]byte("{\"amount\":\"0.001\"}")),
+					expectedStatusCode: http.StatusUnprocessableEntity,
+				},
+			}
+
+			for testName, test := range testCases {
+				t.Run(testName, func(t *testing.T) {
+					db, onClose := setupDb(t)
+					defer onClose()
+
+					accId := insertAccount(t, db, 250)
+
+					w := httptest.NewRecorder()
+					r, _ := http.NewRequest("POST", fmt.Sprintf("/account/%d/withdraw", accId), test.body)
+					r.Header.Set("Content-Type", "application/json")
+
+					runApplication(t, db, w, r)
+					assert.Equal(t, test.expectedStatusCode, w.Code)
+					assert.Equal(t, 250, accountBalance(t, db, accId))
+				})
+			}
+		})
+	})
+}
@@ -0,0 +1,82 @@
+package close
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/rest/account/view"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, finder account.Finder, closer account.Closer, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		ids := []int64{req.Id}
+		if req.Data.SweepTo != nil {
+			ids = append(ids, *req.Data.SweepTo)
+		}
+		accounts := make(map[int64]*account.Record, len(ids))
+		for _, id := range ids {
+			record, err := finder.FindById(ctx, id)
+			if err != nil {
+				if errors.Is(err, account.ErrDoesNotExist) {
+					logger.WarnContext(ctx, "account id not found", "id", id)
+					problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeAccountNotFound, fmt.Sprintf("account with id=%d does not exist", id)), logger)
+					return
+				}
+
+				logger.ErrorContext(ctx, "account lookup failed", "error", err)
+				problem.Write(w, r, problem.Internal(), logger)
+				return
+			}
+			accounts[id] = record
+		}
+
+		var sweepTo *account.Record
+		if req.Data.SweepTo != nil {
+			sweepTo = accounts[*req.Data.SweepTo]
+		}
+
+		closed, err := closer.Close(ctx, accounts[req.Id], sweepTo)
+		if err != nil {
+			if errors.Is(err, account.ErrAccountClosed) ||
+				errors.Is(err, account.ErrAccountFrozen) ||
+				errors.Is(err, account.ErrActiveHolds) ||
+				errors.Is(err, account.ErrBalanceNotZero) ||
+				errors.Is(err, account.ErrCurrencyMismatch) {
+				logger.WarnContext(ctx, "account cannot be closed", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "account close failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(view.NewAccount(closed)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
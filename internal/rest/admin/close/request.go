@@ -0,0 +1,27 @@
+package close
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestInvalidSweepTo = problem.Field("sweep_to", "invalid_sweep_to", "sweep_to must be a different account")
+
+type Request struct {
+	Id   int64
+	Data *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data.SweepTo != nil && *r.Data.SweepTo == r.Id {
+		return errors.Wrap(ErrRequestInvalidSweepTo, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	// SweepTo is optional, it names the account that receives the remaining balance before closing.
+	SweepTo *int64 `json:"sweep_to"`
+}
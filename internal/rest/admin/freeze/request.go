@@ -0,0 +1,5 @@
+package freeze
+
+type Request struct {
+	Id int64
+}
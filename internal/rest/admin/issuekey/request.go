@@ -0,0 +1,72 @@
+package issuekey
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/apikey"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestNameNotSet = problem.Field("name", "name_not_set", "name is mandatory")
+var ErrRequestScopesNotSet = problem.Field("scopes", "scopes_not_set", "scopes must list at least one scope")
+var ErrRequestUnknownScope = problem.Field("scopes", "unknown_scope", fmt.Sprintf("scopes must be any of %s", scopeNames()))
+var ErrRequestAccountNotPositive = problem.Field("accounts", "account_not_positive", "accounts must be positive account ids")
+
+type Request struct {
+	Data *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	Name   string         `json:"name"`
+	Scopes []apikey.Scope `json:"scopes"`
+	// Accounts restricts the key to the given accounts, a key issued without any may act on every account.
+	Accounts []int64 `json:"accounts"`
+}
+
+func (d *RequestData) Validate() error {
+	if strings.TrimSpace(d.Name) == "" {
+		return ErrRequestNameNotSet
+	}
+
+	if len(d.Scopes) == 0 {
+		return ErrRequestScopesNotSet
+	}
+
+	for _, scope := range d.Scopes {
+		if !scope.IsValid() {
+			return errors.Wrapf(ErrRequestUnknownScope, "scope=%q", scope)
+		}
+	}
+
+	for _, id := range d.Accounts {
+		if id <= 0 {
+			return errors.Wrapf(ErrRequestAccountNotPositive, "account=%d", id)
+		}
+	}
+
+	return nil
+}
+
+func scopeNames() string {
+	names := make([]string, 0, len(apikey.Scopes))
+	for _, scope := range apikey.Scopes {
+		names = append(names, string(scope))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,33 @@
+package issuekey
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestBodyNotSet = problem.Field("", "body_not_set", "request body is mandatory")
+
+type RequestParser func(ctx context.Context, r *http.Request) (*Request, error)
+
+func GetRequestParser() RequestParser {
+	return func(ctx context.Context, r *http.Request) (*Request, error) {
+		if r.Body == http.NoBody {
+			return nil, ErrRequestBodyNotSet
+		}
+
+		req := &Request{
+			Data: &RequestData{},
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(req.Data); err != nil {
+			return nil, errors.Wrap(err, "request body not a valid json")
+		}
+
+		return req, nil
+	}
+}
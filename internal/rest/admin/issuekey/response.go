@@ -0,0 +1,19 @@
+package issuekey
+
+import (
+	"github.com/ktsivkov/su-exc/internal/apikey"
+	"github.com/ktsivkov/su-exc/internal/rest/admin/view"
+)
+
+type Response struct {
+	*view.ApiKey
+	// Key is only returned once, only its hash is kept.
+	Key string `json:"key"`
+}
+
+func NewResponse(key *apikey.Key, secret string) *Response {
+	return &Response{
+		ApiKey: view.NewApiKey(key),
+		Key:    secret,
+	}
+}
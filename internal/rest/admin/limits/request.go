@@ -0,0 +1,65 @@
+package limits
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/limits"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestLimitsNotSet = problem.Field("limits", "limits_not_set", "limits are mandatory, send an empty list to remove them")
+
+type Request struct {
+	Id   int64
+	Data *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	Limits []Limit `json:"limits"`
+}
+
+type Limit struct {
+	Metric limits.Metric `json:"metric"`
+	Period limits.Period `json:"period"`
+	// Max is a number of transfers, or minor units of the account currency for amount limits.
+	Max int64 `json:"max"`
+}
+
+func (d *RequestData) Validate() error {
+	if d.Limits == nil {
+		return ErrRequestLimitsNotSet
+	}
+
+	for _, rule := range d.Rules() {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *RequestData) Rules() []limits.Rule {
+	rules := make([]limits.Rule, 0, len(d.Limits))
+	for _, limit := range d.Limits {
+		rules = append(rules, limits.Rule{
+			Metric: limit.Metric,
+			Period: limit.Period,
+			Max:    limit.Max,
+		})
+	}
+	return rules
+}
@@ -0,0 +1,41 @@
+package overdraft
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestInvalidLimitLt0 = problem.Field("limit", "limit_negative", "limit must not be negative")
+
+type Request struct {
+	Id   int64
+	Data *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	// Limit is in the currency of the account, either an integer number of minor units or a decimal string of major units.
+	Limit money.Amount `json:"limit"`
+}
+
+func (d *RequestData) Validate() error {
+	if d.Limit.IsNegative() {
+		return ErrRequestInvalidLimitLt0
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package revokekey
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/apikey"
+	"github.com/ktsivkov/su-exc/internal/rest/admin/view"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, revoker apikey.Revoker, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		revoked, err := revoker.Revoke(ctx, req.Id)
+		if err != nil {
+			if errors.Is(err, apikey.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "api key id not found", "id", req.Id)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeApiKeyNotFound, fmt.Sprintf("api key with id=%d does not exist", req.Id)), logger)
+				return
+			}
+
+			if errors.Is(err, apikey.ErrAlreadyRevoked) {
+				logger.WarnContext(ctx, "api key already revoked", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "api key revocation failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(view.NewApiKey(revoked)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+package revokekey
+
+type Request struct {
+	Id int64
+}
@@ -0,0 +1,39 @@
+package tier
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestTierNotSet = problem.Field("tier", "tier_not_set", "tier is mandatory")
+
+type Request struct {
+	Id   int64
+	Data *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	Tier string `json:"tier"`
+}
+
+func (d *RequestData) Validate() error {
+	if d.Tier == "" {
+		return ErrRequestTierNotSet
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package tierlimits
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/limits"
+	"github.com/ktsivkov/su-exc/internal/rest/admin/view"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, configurer limits.Configurer, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		rules := req.Data.Rules()
+		if err := configurer.SetTierRules(ctx, req.Tier, rules); err != nil {
+			if errors.Is(err, limits.ErrInvalidRule) || errors.Is(err, limits.ErrInvalidTier) {
+				logger.WarnContext(ctx, "invalid limits", "error", err)
+				problem.Write(w, r, problem.Invalid(err), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "setting tier limits failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(&view.Policy{Tier: req.Tier, Limits: view.NewLimits(rules)}); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
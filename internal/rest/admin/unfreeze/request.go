@@ -0,0 +1,5 @@
+package unfreeze
+
+type Request struct {
+	Id int64
+}
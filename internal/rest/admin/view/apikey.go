@@ -0,0 +1,35 @@
+package view
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/apikey"
+)
+
+type ApiKey struct {
+	Id     int64          `json:"id"`
+	Name   string         `json:"name"`
+	Prefix string         `json:"prefix"`
+	Scopes []apikey.Scope `json:"scopes"`
+	// Accounts restricts the key to the given accounts, it is empty for keys which may act on every account.
+	Accounts  []int64    `json:"accounts"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func NewApiKey(key *apikey.Key) *ApiKey {
+	accounts := key.AccountIds
+	if accounts == nil {
+		accounts = []int64{}
+	}
+
+	return &ApiKey{
+		Id:        key.Id,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		Accounts:  accounts,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
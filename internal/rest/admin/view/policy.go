@@ -0,0 +1,36 @@
+package view
+
+import (
+	"github.com/ktsivkov/su-exc/internal/limits"
+)
+
+type Policy struct {
+	Tier   string  `json:"tier,omitempty"`
+	Limits []Limit `json:"limits"`
+}
+
+type Limit struct {
+	Metric limits.Metric `json:"metric"`
+	Period limits.Period `json:"period"`
+	// Max is a number of transfers, or minor units of the account currency for amount limits.
+	Max int64 `json:"max"`
+}
+
+func NewPolicy(policy *limits.Policy) *Policy {
+	return &Policy{
+		Tier:   policy.Tier,
+		Limits: NewLimits(policy.Rules),
+	}
+}
+
+func NewLimits(rules []limits.Rule) []Limit {
+	res := make([]Limit, 0, len(rules))
+	for _, rule := range rules {
+		res = append(res, Limit{
+			Metric: rule.Metric,
+			Period: rule.Period,
+			Max:    rule.Max,
+		})
+	}
+	return res
+}
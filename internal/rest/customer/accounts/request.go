@@ -0,0 +1,41 @@
+package accounts
+
+import (
+	"fmt"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+const DefaultLimit = 20
+const MaxLimit = 100
+
+var ErrRequestInvalidLimit = problem.Field("limit", "invalid_limit", fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+
+type Request struct {
+	Id     int64
+	Cursor int64
+	Limit  int
+}
+
+func (r *Request) Validate() error {
+	if r.Limit < 1 || r.Limit > MaxLimit {
+		return ErrRequestInvalidLimit
+	}
+
+	return nil
+}
+
+func (r *Request) Query() *account.ListQuery {
+	query := &account.ListQuery{
+		SortBy:     account.SortById,
+		CustomerId: &r.Id,
+		Limit:      r.Limit,
+	}
+
+	if r.Cursor != 0 {
+		query.After = &account.Position{Id: r.Cursor}
+	}
+
+	return query
+}
@@ -0,0 +1,61 @@
+package accounts
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+type RequestParser func(ctx context.Context, r *http.Request) (*Request, error)
+
+func GetRequestParser() RequestParser {
+	return func(ctx context.Context, r *http.Request) (*Request, error) {
+		variables := mux.Vars(r)
+		id, err := strconv.ParseInt(variables["id"], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot parse customer id")
+		}
+
+		query := r.URL.Query()
+		req := &Request{
+			Id:    id,
+			Limit: DefaultLimit,
+		}
+
+		if cursor := query.Get("cursor"); cursor != "" {
+			if req.Cursor, err = DecodeCursor(cursor); err != nil {
+				return nil, errors.Wrap(err, "cannot parse cursor")
+			}
+		}
+
+		if limit := query.Get("limit"); limit != "" {
+			if req.Limit, err = strconv.Atoi(limit); err != nil {
+				return nil, errors.Wrap(err, "cannot parse limit")
+			}
+		}
+
+		return req, nil
+	}
+}
+
+func EncodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func DecodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.Wrap(err, "cursor is not valid base64")
+	}
+
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("cursor is malformed")
+	}
+
+	return id, nil
+}
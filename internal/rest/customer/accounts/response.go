@@ -0,0 +1,55 @@
+package accounts
+
+import (
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/customer"
+	"github.com/ktsivkov/su-exc/internal/money"
+	accountview "github.com/ktsivkov/su-exc/internal/rest/account/view"
+	"github.com/ktsivkov/su-exc/internal/rest/customer/view"
+)
+
+type Response struct {
+	Customer *view.Customer         `json:"customer"`
+	Accounts []*accountview.Account `json:"accounts"`
+	// Balances are the balances of all the accounts, not only of the page, summed up per currency in the order of the
+	// currency codes.
+	Balances   []*Balance `json:"balances"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+type Balance struct {
+	Currency  currency.Code `json:"currency"`
+	Accounts  int           `json:"accounts"`
+	Balance   money.Amount  `json:"balance"`
+	Available money.Amount  `json:"available_balance"`
+}
+
+func NewResponse(c *customer.Customer, records []*account.Record, balances []*account.CustomerBalance, limit int) *Response {
+	res := &Response{
+		Customer: view.NewCustomer(c),
+		Accounts: make([]*accountview.Account, 0, len(records)),
+		Balances: make([]*Balance, 0, len(balances)),
+	}
+
+	// One more record than requested is fetched to detect whether there is a next page
+	if len(records) > limit {
+		records = records[:limit]
+		res.NextCursor = EncodeCursor(records[limit-1].Id)
+	}
+
+	for _, record := range records {
+		res.Accounts = append(res.Accounts, accountview.NewAccount(record))
+	}
+
+	for _, balance := range balances {
+		res.Balances = append(res.Balances, &Balance{
+			Currency:  balance.Currency,
+			Accounts:  balance.Accounts,
+			Balance:   balance.Balance,
+			Available: balance.Available,
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,45 @@
+package remove
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/customer"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, deleter customer.Deleter, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := deleter.Delete(ctx, req.Id); err != nil {
+			if errors.Is(err, customer.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "customer id not found", "id", req.Id)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeCustomerNotFound, fmt.Sprintf("customer with id=%d does not exist", req.Id)), logger)
+				return
+			}
+
+			if errors.Is(err, customer.ErrHasOpenAccounts) {
+				logger.WarnContext(ctx, "customer has open accounts", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "customer deletion failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,5 @@
+package remove
+
+type Request struct {
+	Id int64
+}
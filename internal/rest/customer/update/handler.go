@@ -0,0 +1,69 @@
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/customer"
+	"github.com/ktsivkov/su-exc/internal/rest/customer/view"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, updater customer.Updater, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		updated, err := updater.Update(ctx, req.Id, req.Data.Details())
+		if err != nil {
+			if errors.Is(err, customer.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "customer id not found", "id", req.Id)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeCustomerNotFound, fmt.Sprintf("customer with id=%d does not exist", req.Id)), logger)
+				return
+			}
+
+			if errors.Is(err, customer.ErrExternalRefTaken) {
+				logger.WarnContext(ctx, "customer external reference taken", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			if isInvalid(err) {
+				logger.WarnContext(ctx, "invalid customer details", "error", err)
+				problem.Write(w, r, problem.Invalid(err), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "customer update failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(view.NewCustomer(updated)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
+
+func isInvalid(err error) bool {
+	return errors.Is(err, customer.ErrInvalidName) || errors.Is(err, customer.ErrInvalidEmail) ||
+		errors.Is(err, customer.ErrInvalidExternalRef) || errors.Is(err, customer.ErrUnknownKycTier)
+}
@@ -0,0 +1,73 @@
+package update
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/customer"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestNameNotSet = problem.Field("name", "name_not_set", "name is mandatory")
+var ErrRequestEmailNotSet = problem.Field("email", "email_not_set", "email is mandatory")
+var ErrRequestUnknownKycTier = problem.Field("kyc_tier", "unknown_kyc_tier", fmt.Sprintf("kyc_tier must be any of %s", kycTiers()))
+
+type Request struct {
+	Id   int64
+	Data *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	Name        string           `json:"name"`
+	Email       string           `json:"email"`
+	ExternalRef string           `json:"external_ref"`
+	KycTier     customer.KycTier `json:"kyc_tier"`
+}
+
+func (d *RequestData) Validate() error {
+	if strings.TrimSpace(d.Name) == "" {
+		return ErrRequestNameNotSet
+	}
+
+	if strings.TrimSpace(d.Email) == "" {
+		return ErrRequestEmailNotSet
+	}
+
+	if d.KycTier != "" && !d.KycTier.IsValid() {
+		return errors.Wrapf(ErrRequestUnknownKycTier, "kyc tier=%q", d.KycTier)
+	}
+
+	return nil
+}
+
+func (d *RequestData) Details() *customer.Details {
+	return &customer.Details{
+		Name:        d.Name,
+		Email:       d.Email,
+		ExternalRef: d.ExternalRef,
+		KycTier:     d.KycTier,
+	}
+}
+
+func kycTiers() string {
+	names := make([]string, 0, len(customer.KycTiers))
+	for _, tier := range customer.KycTiers {
+		names = append(names, string(tier))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,29 @@
+package view
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/customer"
+)
+
+type Customer struct {
+	Id          int64            `json:"id"`
+	Name        string           `json:"name"`
+	Email       string           `json:"email"`
+	ExternalRef string           `json:"external_ref,omitempty"`
+	KycTier     customer.KycTier `json:"kyc_tier"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+func NewCustomer(c *customer.Customer) *Customer {
+	return &Customer{
+		Id:          c.Id,
+		Name:        c.Name,
+		Email:       c.Email,
+		ExternalRef: c.ExternalRef,
+		KycTier:     c.KycTier,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
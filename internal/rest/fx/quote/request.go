@@ -0,0 +1,48 @@
+package quote
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestSameCurrency = problem.Field("target", "same_currency", "source and target currency must differ")
+
+type Request struct {
+	Data *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	Source currency.Code `json:"source_currency"`
+	Target currency.Code `json:"target_currency"`
+}
+
+func (d *RequestData) Validate() error {
+	if _, err := currency.Parse(string(d.Source)); err != nil {
+		return errors.Wrap(err, "invalid source currency")
+	}
+
+	if _, err := currency.Parse(string(d.Target)); err != nil {
+		return errors.Wrap(err, "invalid target currency")
+	}
+
+	if d.Source == d.Target {
+		return ErrRequestSameCurrency
+	}
+
+	return nil
+}
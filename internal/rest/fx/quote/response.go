@@ -0,0 +1,26 @@
+package quote
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/fx"
+)
+
+type Response struct {
+	Id        string        `json:"id"`
+	Source    currency.Code `json:"source_currency"`
+	Target    currency.Code `json:"target_currency"`
+	Rate      string        `json:"rate"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+func NewResponse(quote *fx.Quote) *Response {
+	return &Response{
+		Id:        quote.Id,
+		Source:    quote.Source,
+		Target:    quote.Target,
+		Rate:      quote.Rate,
+		ExpiresAt: quote.ExpiresAt,
+	}
+}
@@ -0,0 +1,109 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/limits"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/hold/view"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func Handler(requestParser RequestParser, holdFinder account.HoldFinder, capturer account.Capturer, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		hold, err := holdFinder.FindHold(ctx, req.Id)
+		if err != nil {
+			if errors.Is(err, account.ErrHoldDoesNotExist) {
+				logger.WarnContext(ctx, "hold id not found", "id", req.Id)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeHoldNotFound, fmt.Sprintf("hold with id=%d does not exist", req.Id)), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "hold lookup failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		amount := hold.Amount
+		if req.Data.Amount != nil {
+			if amount, err = req.Data.Amount.In(hold.Amount.Currency()); err != nil {
+				logger.WarnContext(ctx, "invalid request amount", "error", err)
+				problem.Write(w, r, problem.Invalid(err), logger)
+				return
+			}
+		}
+
+		captured, err := capturer.Capture(ctx, hold, amount)
+		if err != nil {
+			var violation *limits.Violation
+			if errors.As(err, &violation) {
+				logger.WarnContext(ctx, "transfer limit exceeded", "error", err)
+				// Too many transfers can simply be retried once the period resets, too much money cannot be sent as is
+				status := http.StatusUnprocessableEntity
+				if violation.Rule.Metric == limits.MetricCount {
+					status = http.StatusTooManyRequests
+					w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(time.Until(violation.ResetsAt).Seconds())))))
+				}
+				problem.Write(w, r, problem.FromError(status, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrHoldNotActive) {
+				logger.WarnContext(ctx, "hold cannot be captured", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrHoldExceeded) || errors.Is(err, money.ErrOverflow) {
+				logger.WarnContext(ctx, "invalid capture amount", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusUnprocessableEntity, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrAccountFrozen) || errors.Is(err, account.ErrAccountClosed) {
+				logger.WarnContext(ctx, "account is not active", "error", err)
+				problem.Write(w, r, problem.FromError(http.StatusConflict, err), logger)
+				return
+			}
+
+			if errors.Is(err, account.ErrInsufficientBalance) {
+				problem.Write(w, r, problem.FromError(http.StatusBadRequest, err), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "hold capture failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(view.NewHold(captured)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package capture
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestInvalidAmountLt0 = problem.Field("amount", "amount_not_positive", "amount must be positive")
+
+type Request struct {
+	Id   int64
+	Data *RequestData
+}
+
+func (r *Request) Validate() error {
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	// Amount is optional, the whole held amount is captured when omitted.
+	Amount *money.Amount `json:"amount"`
+}
+
+func (d *RequestData) Validate() error {
+	if d.Amount != nil && !d.Amount.IsPositive() {
+		return ErrRequestInvalidAmountLt0
+	}
+
+	return nil
+}
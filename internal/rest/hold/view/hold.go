@@ -0,0 +1,37 @@
+package view
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+type Hold struct {
+	Id         int64              `json:"id"`
+	Source     int64              `json:"source"`
+	Target     int64              `json:"target"`
+	Amount     money.Amount       `json:"amount"`
+	Currency   currency.Code      `json:"currency"`
+	Status     account.HoldStatus `json:"status"`
+	Captured   *money.Amount      `json:"captured_amount,omitempty"`
+	TransferId *int64             `json:"transfer_id,omitempty"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+func NewHold(hold *account.Hold) *Hold {
+	return &Hold{
+		Id:         hold.Id,
+		Source:     hold.SourceId,
+		Target:     hold.TargetId,
+		Amount:     hold.Amount,
+		Currency:   hold.Amount.Currency(),
+		Status:     hold.Status,
+		Captured:   hold.Captured,
+		TransferId: hold.TransferId,
+		ExpiresAt:  hold.ExpiresAt,
+		CreatedAt:  hold.CreatedAt,
+	}
+}
@@ -0,0 +1,5 @@
+package void
+
+type Request struct {
+	Id int64
+}
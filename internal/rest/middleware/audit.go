@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ktsivkov/su-exc/internal/audit"
+	"github.com/ktsivkov/su-exc/internal/requestid"
+)
+
+// Audit records every state-changing request in the audit log, whatever its outcome. The entry of a request which
+// changes balances is appended within the transaction making the changes, the entries of the others once they have
+// been handled. It relies on RequestId running first.
+func Audit(recorder audit.Recorder, logger *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestId := requestid.From(r.Context())
+			entry := audit.Entry{
+				Actor:     audit.ActorFrom(r.Context()),
+				RequestId: requestId,
+				Method:    r.Method,
+				Endpoint:  r.URL.Path,
+				Path:      r.URL.Path,
+			}
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					entry.Endpoint = template
+				}
+			}
+
+			ctx, trail := audit.WithTrail(r.Context(), recorder, entry)
+			status := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(status, r.WithContext(ctx))
+
+			if trail.Recorded() {
+				if status.StatusCode() != trail.StatusCode() {
+					logger.WarnContext(ctx, "request answered with another status than the audited one", "request_id", requestId, "status_code", status.StatusCode(), "audited_status_code", trail.StatusCode())
+				}
+				return
+			}
+
+			// The request is detached from the client's context, the audit has to be written even if the client went away
+			if err := recorder.Append(context.WithoutCancel(r.Context()), trail.Uncommitted(status.StatusCode())); err != nil {
+				logger.ErrorContext(ctx, "cannot append to the audit log", "request_id", requestId, "error", err)
+			}
+		})
+	}
+}
+
+// AuditStatus tells Audit the status code a route answers with once its changes are committed, to be used by the
+// routes answering with another one than http.StatusOK.
+func AuditStatus(statusCode int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			audit.SetStatus(r.Context(), statusCode)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// statusRecorder remembers the status code of a response without buffering its body.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) StatusCode() int {
+	if r.statusCode == 0 {
+		return http.StatusOK
+	}
+	return r.statusCode
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/apikey"
+	"github.com/ktsivkov/su-exc/internal/audit"
+	"github.com/ktsivkov/su-exc/internal/jwt"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+// ApiKeyHeader carries the API key of clients which do not send it as a bearer token.
+const ApiKeyHeader = "X-Api-Key"
+
+type authErrorKey struct{}
+
+// Authenticate identifies the client by the API key sent as a bearer token or in the X-Api-Key header, or by the
+// token sent as a bearer token if tokens are verified at all, and makes it the actor of the request. Users holding any
+// of privilegedRoles are privileged. Requests are let through whatever the outcome, it is up to Require and its
+// siblings to reject them, so that the rejected requests are audited as well.
+func Authenticate(authenticator apikey.Authenticator, verifier jwt.TokenVerifier, privilegedRoles []string, logger *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			credential, isToken := requestCredential(r)
+			switch {
+			case credential == "":
+				next.ServeHTTP(w, r)
+			case isToken && verifier != nil:
+				claims, err := verifier.Verify(credential)
+				if err != nil {
+					next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, authErrorKey{}, err)))
+					return
+				}
+
+				user := &jwt.User{Subject: claims.Subject, Privileged: claims.HasAnyRole(privilegedRoles)}
+				next.ServeHTTP(w, r.WithContext(audit.WithActor(jwt.With(ctx, user), user.Actor())))
+			default:
+				key, err := authenticator.Authenticate(ctx, credential)
+				if err != nil {
+					next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, authErrorKey{}, err)))
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(audit.WithActor(apikey.With(ctx, key), key.Actor())))
+			}
+		})
+	}
+}
+
+// accountCheck tells how a route checks the accounts a key is restricted to.
+type accountCheck int
+
+const (
+	// anyKey routes do not act on accounts, restricted keys are let through.
+	anyKey accountCheck = iota
+	// routeAccount routes act on the account their id variable names.
+	routeAccount
+	// unrestrictedKey routes act on accounts they do not name, restricted keys are rejected.
+	unrestrictedKey
+	// ownedAccount routes are routeAccount routes letting users through, the handler checks they own the account.
+	ownedAccount
+)
+
+// Require lets through the requests authenticated by a key granted scope, for routes which do not act on an account.
+func Require(scope apikey.Scope, logger *slog.Logger) mux.MiddlewareFunc {
+	return authorize(scope, anyKey, logger)
+}
+
+// RequireAccount lets through the requests authenticated by a key granted scope which may act on the account the
+// id variable of the route names.
+func RequireAccount(scope apikey.Scope, logger *slog.Logger) mux.MiddlewareFunc {
+	return authorize(scope, routeAccount, logger)
+}
+
+// RequireAnyAccount lets through the requests authenticated by a key granted scope which may act on every account,
+// for routes acting on accounts they do not name.
+func RequireAnyAccount(scope apikey.Scope, logger *slog.Logger) mux.MiddlewareFunc {
+	return authorize(scope, unrestrictedKey, logger)
+}
+
+// RequireOwnedAccount works as RequireAccount for API keys, but lets through the users the route handler checks to
+// own the account it acts on.
+func RequireOwnedAccount(scope apikey.Scope, logger *slog.Logger) mux.MiddlewareFunc {
+	return authorize(scope, ownedAccount, logger)
+}
+
+func authorize(scope apikey.Scope, check accountCheck, logger *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			if user := jwt.From(ctx); user != nil {
+				authorizeUser(w, r, next, user, scope, check, logger)
+				return
+			}
+
+			key := apikey.From(ctx)
+			if key == nil {
+				unauthenticated(w, r, logger)
+				return
+			}
+
+			if !key.Allows(scope) {
+				logger.WarnContext(ctx, "api key lacks scope", "api_key_id", key.Id, "scope", scope)
+				problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeScopeMissing, fmt.Sprintf("api key lacks the %s scope", scope)), logger)
+				return
+			}
+
+			switch check {
+			case anyKey:
+				next.ServeHTTP(w, r)
+				return
+			case unrestrictedKey:
+				if key.IsBound() {
+					logger.WarnContext(ctx, "api key restricted to accounts", "api_key_id", key.Id)
+					problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeAccountForbidden, "api key is restricted to some accounts and cannot be used here"), logger)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+			if err != nil || !key.CoversAccount(id) {
+				logger.WarnContext(ctx, "api key may not act on account", "api_key_id", key.Id, "account", mux.Vars(r)["id"])
+				problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodeAccountForbidden, fmt.Sprintf("api key may not act on account with id=%s", mux.Vars(r)["id"])), logger)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authorizeUser lets privileged users through any route, the others only through the routes they cannot act on the
+// accounts of others with.
+func authorizeUser(w http.ResponseWriter, r *http.Request, next http.Handler, user *jwt.User, scope apikey.Scope, check accountCheck, logger *slog.Logger) {
+	if user.Privileged || (scope != apikey.ScopeAdmin && (check == anyKey || check == ownedAccount)) {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	logger.WarnContext(r.Context(), "user lacks a privileged role", "subject", user.Subject, "scope", scope)
+	problem.Write(w, r, problem.New(http.StatusForbidden, problem.CodePrivilegedRoleRequired, "a privileged role is required"), logger)
+}
+
+// unauthenticated rejects a request sent without a valid key or token, telling why if it sent one.
+func unauthenticated(w http.ResponseWriter, r *http.Request, logger *slog.Logger) {
+	ctx := r.Context()
+
+	err, _ := ctx.Value(authErrorKey{}).(error)
+	if err == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthenticated, "an api key or a token is required, send it as a bearer token"), logger)
+		return
+	}
+
+	if errors.Is(err, apikey.ErrInvalid) || errors.Is(err, apikey.ErrRevoked) || isTokenError(err) {
+		logger.WarnContext(ctx, "credentials rejected", "error", err)
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		problem.Write(w, r, problem.FromError(http.StatusUnauthorized, err), logger)
+		return
+	}
+
+	logger.ErrorContext(ctx, "authentication failed", "error", err)
+	problem.Write(w, r, problem.Internal(), logger)
+}
+
+func isTokenError(err error) bool {
+	for _, tokenErr := range []error{jwt.ErrMalformed, jwt.ErrUnsupportedAlgorithm, jwt.ErrUnknownKey, jwt.ErrSignature, jwt.ErrExpired, jwt.ErrNotYetValid, jwt.ErrSubjectNotSet} {
+		if errors.Is(err, tokenErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestCredential returns the API key or the token of the request, telling which one it is. Bearer tokens are
+// told from API keys by their three dot separated parts.
+func requestCredential(r *http.Request) (string, bool) {
+	if scheme, credential, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		credential = strings.TrimSpace(credential)
+		return credential, strings.Count(credential, ".") == 2
+	}
+	return r.Header.Get(ApiKeyHeader), false
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/audit"
+	"github.com/ktsivkov/su-exc/internal/idempotency"
+	"github.com/ktsivkov/su-exc/internal/requestid"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+const IdempotencyReplayedHeader = "Idempotency-Replayed"
+const maxIdempotencyKeyLength = 255
+
+// Idempotency makes the wrapped handler safe to retry: requests carrying an Idempotency-Key header are
+// processed once and their response is replayed for every repeat of the same request.
+func Idempotency(store idempotency.Store, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				logger.WarnContext(ctx, "idempotency key too long", "length", len(key))
+				problem.Write(w, r, problem.Malformed(errors.Errorf("%s header cannot be longer than %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength)), logger)
+				return
+			}
+
+			fingerprint, err := fingerprintRequest(r)
+			if err != nil {
+				logger.WarnContext(ctx, "cannot read request body", "error", err)
+				problem.Write(w, r, problem.Malformed(err), logger)
+				return
+			}
+
+			// Keys are only unique to the actor choosing them, one actor cannot replay the response of another
+			actor := audit.ActorFrom(ctx)
+			// The owner tells this request apart from its retries, only it may store the response or release the key
+			owner := requestid.New()
+			stored, err := store.Begin(ctx, actor, key, owner, fingerprint)
+			if err != nil {
+				switch {
+				case errors.Is(err, idempotency.ErrFingerprintMismatch):
+					logger.WarnContext(ctx, "idempotency key reused with a different request", "key", key)
+					problem.Write(w, r, problem.FromError(http.StatusUnprocessableEntity, idempotency.ErrFingerprintMismatch), logger)
+				case errors.Is(err, idempotency.ErrInProgress):
+					logger.WarnContext(ctx, "idempotency key is in progress", "key", key)
+					problem.Write(w, r, problem.FromError(http.StatusConflict, idempotency.ErrInProgress), logger)
+				case errors.Is(err, idempotency.ErrOutcomeUnknown):
+					logger.WarnContext(ctx, "idempotency key outcome is unknown", "key", key)
+					problem.Write(w, r, problem.FromError(http.StatusConflict, idempotency.ErrOutcomeUnknown), logger)
+				default:
+					logger.ErrorContext(ctx, "idempotency key check failed", "error", err)
+					problem.Write(w, r, problem.Internal(), logger)
+				}
+				return
+			}
+
+			if stored != nil {
+				logger.InfoContext(ctx, "replaying stored response", "key", key)
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				w.Header().Set(IdempotencyReplayedHeader, "true")
+				w.WriteHeader(stored.StatusCode)
+				if _, err := w.Write(stored.Body); err != nil {
+					logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+				}
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+
+			// The request is detached from the client's context, the outcome has to be stored even if the client went away
+			sCtx := context.WithoutCancel(ctx)
+			if recorder.StatusCode() >= http.StatusInternalServerError {
+				if err := store.Release(sCtx, actor, key, owner); err != nil {
+					logger.ErrorContext(ctx, "cannot release idempotency key", "key", key, "error", err)
+				}
+				return
+			}
+
+			if err := store.Complete(sCtx, actor, key, owner, &idempotency.Response{
+				StatusCode:  recorder.StatusCode(),
+				ContentType: w.Header().Get("Content-Type"),
+				Body:        recorder.body.Bytes(),
+			}); err != nil {
+				logger.ErrorContext(ctx, "cannot store idempotent response", "key", key, "error", err)
+			}
+		})
+	}
+}
+
+func fingerprintRequest(r *http.Request) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return "", errors.Wrap(err, "cannot read request body")
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash.Write(body)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) StatusCode() int {
+	if r.statusCode == 0 {
+		return http.StatusOK
+	}
+	return r.statusCode
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/ktsivkov/su-exc/internal/requestid"
+)
+
+// RequestId identifies every request by the X-Request-Id header, or by a generated id if the client sent none, and
+// echoes the id back.
+func RequestId() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestid.Header)
+			if id == "" || len(id) > requestid.MaxLength {
+				id = requestid.New()
+			}
+
+			w.Header().Set(requestid.Header, id)
+			next.ServeHTTP(w, r.WithContext(requestid.With(r.Context(), id)))
+		})
+	}
+}
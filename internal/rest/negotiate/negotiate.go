@@ -0,0 +1,74 @@
+// Package negotiate picks the representation of a response from the Accept header of the request.
+package negotiate
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	JSON = "application/json"
+	Text = "text/plain"
+)
+
+// Legacy reports whether the client prefers the plain text responses the API returned before it answered with JSON,
+// that is when it accepts text/plain with a higher quality than application/json. Everyone else, including clients
+// not sending an Accept header at all, gets JSON.
+func Legacy(r *http.Request) bool {
+	text, json := newPreference(), newPreference()
+	for _, value := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil {
+				continue
+			}
+
+			q := 1.0
+			if raw, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(raw, 64); err != nil {
+					continue
+				}
+			}
+
+			text.offer(mediaType, Text, q)
+			json.offer(mediaType, JSON, q)
+		}
+	}
+
+	return text.quality() > json.quality()
+}
+
+// preference is the quality a client gives to a media type, the most specific media range matching it wins.
+type preference struct {
+	q           float64
+	specificity int
+}
+
+func newPreference() *preference {
+	return &preference{specificity: -1}
+}
+
+func (p *preference) offer(mediaRange string, mediaType string, q float64) {
+	specificity := -1
+	switch {
+	case mediaRange == mediaType:
+		specificity = 2
+	case mediaRange == mediaType[:strings.Index(mediaType, "/")]+"/*":
+		specificity = 1
+	case mediaRange == "*/*":
+		specificity = 0
+	}
+
+	if specificity > p.specificity || (specificity == p.specificity && q > p.q) {
+		p.q, p.specificity = q, specificity
+	}
+}
+
+func (p *preference) quality() float64 {
+	if p.specificity < 0 {
+		return 0
+	}
+	return p.q
+}
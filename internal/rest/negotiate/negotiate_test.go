@@ -0,0 +1,46 @@
+package negotiate_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/rest/negotiate"
+)
+
+func TestLegacy(t *testing.T) {
+	type testCase struct {
+		accept   []string
+		expected bool
+	}
+
+	testCases := map[string]testCase{
+		"no accept header":             {accept: nil, expected: false},
+		"json":                         {accept: []string{"application/json"}, expected: false},
+		"any":                          {accept: []string{"*/*"}, expected: false},
+		"text":                         {accept: []string{"text/plain"}, expected: true},
+		"text with parameters":         {accept: []string{"text/plain; charset=utf-8"}, expected: true},
+		"any text":                     {accept: []string{"text/*"}, expected: true},
+		"text before any":              {accept: []string{"text/plain, */*;q=0.1"}, expected: true},
+		"text preferred by quality":    {accept: []string{"application/json;q=0.5, text/plain"}, expected: true},
+		"json preferred by quality":    {accept: []string{"text/plain;q=0.5, application/json"}, expected: false},
+		"equal quality":                {accept: []string{"text/plain, application/json"}, expected: false},
+		"specific range overrides any": {accept: []string{"*/*, application/json;q=0.1, text/plain;q=0.2"}, expected: true},
+		"text refused":                 {accept: []string{"text/plain;q=0"}, expected: false},
+		"repeated headers":             {accept: []string{"application/json;q=0.1", "text/plain"}, expected: true},
+		"malformed range is ignored":   {accept: []string{"text/plain;q=x, application/json"}, expected: false},
+		"unrelated types are ignored":  {accept: []string{"application/xml"}, expected: false},
+	}
+
+	for testName, test := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/accounts", nil)
+			for _, accept := range test.accept {
+				r.Header.Add("Accept", accept)
+			}
+
+			assert.Equal(t, test.expected, negotiate.Legacy(r))
+		})
+	}
+}
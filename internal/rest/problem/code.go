@@ -0,0 +1,172 @@
+package problem
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/apikey"
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/customer"
+	"github.com/ktsivkov/su-exc/internal/fx"
+	"github.com/ktsivkov/su-exc/internal/idempotency"
+	"github.com/ktsivkov/su-exc/internal/jwt"
+	"github.com/ktsivkov/su-exc/internal/limits"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/webhook"
+)
+
+// Code identifies a problem for clients, codes are part of the API and must never change once released.
+type Code string
+
+const (
+	CodeInternal         Code = "internal_error"
+	CodeRouteNotFound    Code = "route_not_found"
+	CodeMethodNotAllowed Code = "method_not_allowed"
+
+	CodeUnauthenticated        Code = "unauthenticated"
+	CodeApiKeyInvalid          Code = "api_key_invalid"
+	CodeApiKeyRevoked          Code = "api_key_revoked"
+	CodeScopeMissing           Code = "scope_missing"
+	CodeAccountForbidden       Code = "account_forbidden"
+	CodeApiKeyNotFound         Code = "api_key_not_found"
+	CodeApiKeyAlreadyRevoked   Code = "api_key_already_revoked"
+	CodeApiKeyScopesNotSet     Code = "api_key_scopes_not_set"
+	CodeUnknownApiKeyScope     Code = "unknown_api_key_scope"
+	CodeTokenInvalid           Code = "token_invalid"
+	CodeTokenExpired           Code = "token_expired"
+	CodeTokenNotYetValid       Code = "token_not_yet_valid"
+	CodePrivilegedRoleRequired Code = "privileged_role_required"
+
+	CodeAccountNotFound           Code = "account_not_found"
+	CodeInsufficientBalance       Code = "insufficient_balance"
+	CodeCurrencyMismatch          Code = "currency_mismatch"
+	CodeAccountFrozen             Code = "account_frozen"
+	CodeAccountClosed             Code = "account_closed"
+	CodeStatusTransition          Code = "status_transition_not_allowed"
+	CodeBalanceNotZero            Code = "balance_not_zero"
+	CodeActiveHolds               Code = "active_holds"
+	CodeInvalidOverdraftLimit     Code = "invalid_overdraft_limit"
+	CodeEmptyBatch                Code = "empty_batch"
+	CodeHoldNotFound              Code = "hold_not_found"
+	CodeHoldNotActive             Code = "hold_not_active"
+	CodeHoldExceeded              Code = "hold_exceeded"
+	CodeTransferNotFound          Code = "transfer_not_found"
+	CodeTransferAlreadyReversed   Code = "transfer_already_reversed"
+	CodeReversalExceedsTransfer   Code = "reversal_exceeds_transfer"
+	CodeScheduledTransferNotFound Code = "scheduled_transfer_not_found"
+	CodeLimitExceeded             Code = "limit_exceeded"
+	CodeInvalidLimitRule          Code = "invalid_limit_rule"
+	CodeInvalidTier               Code = "invalid_tier"
+	CodeAmountOutOfRange          Code = "amount_out_of_range"
+	CodeAmountCurrencyNotSet      Code = "amount_currency_not_set"
+	CodeAmountTooManyDecimals     Code = "amount_too_many_decimals"
+	CodeAmountMalformed           Code = "amount_malformed"
+	CodeUnsupportedCurrency       Code = "unsupported_currency"
+	CodeRateNotAvailable          Code = "rate_not_available"
+	CodeSameCurrency              Code = "same_currency"
+	CodeQuoteNotFound             Code = "quote_not_found"
+	CodeQuoteExpired              Code = "quote_expired"
+	CodeQuoteUsed                 Code = "quote_used"
+	CodeQuoteCurrencyMismatch     Code = "quote_currency_mismatch"
+	CodeConvertedAmountTooSmall   Code = "converted_amount_too_small"
+	CodeConvertedAmountTooLarge   Code = "converted_amount_too_large"
+	CodeWebhookNotFound           Code = "webhook_not_found"
+	CodeInvalidWebhookUrl         Code = "invalid_webhook_url"
+	CodeWebhookEventTypesNotSet   Code = "webhook_event_types_not_set"
+	CodeIdempotencyKeyReused      Code = "idempotency_key_reused"
+	CodeIdempotencyInProgress     Code = "idempotency_request_in_progress"
+	CodeIdempotencyOutcomeUnknown Code = "idempotency_outcome_unknown"
+	CodeCustomerNotFound          Code = "customer_not_found"
+	CodeInvalidCustomerName       Code = "invalid_customer_name"
+	CodeInvalidCustomerEmail      Code = "invalid_customer_email"
+	CodeInvalidExternalRef        Code = "invalid_external_ref"
+	CodeUnknownKycTier            Code = "unknown_kyc_tier"
+	CodeExternalRefTaken          Code = "external_ref_taken"
+	CodeCustomerHasOpenAccounts   Code = "customer_has_open_accounts"
+)
+
+// domainCodes maps the domain errors to their codes, the first error err wraps decides its code.
+var domainCodes = []struct {
+	err  error
+	code Code
+}{
+	{account.ErrDoesNotExist, CodeAccountNotFound},
+	{account.ErrInsufficientBalance, CodeInsufficientBalance},
+	{account.ErrCurrencyMismatch, CodeCurrencyMismatch},
+	{account.ErrAccountFrozen, CodeAccountFrozen},
+	{account.ErrAccountClosed, CodeAccountClosed},
+	{account.ErrStatusTransition, CodeStatusTransition},
+	{account.ErrBalanceNotZero, CodeBalanceNotZero},
+	{account.ErrActiveHolds, CodeActiveHolds},
+	{account.ErrInvalidOverdraftLimit, CodeInvalidOverdraftLimit},
+	{account.ErrEmptyBatch, CodeEmptyBatch},
+	{account.ErrHoldDoesNotExist, CodeHoldNotFound},
+	{account.ErrHoldNotActive, CodeHoldNotActive},
+	{account.ErrHoldExceeded, CodeHoldExceeded},
+	{account.ErrTransferDoesNotExist, CodeTransferNotFound},
+	{account.ErrTransferAlreadyReversed, CodeTransferAlreadyReversed},
+	{account.ErrReversalExceedsTransfer, CodeReversalExceedsTransfer},
+	{account.ErrScheduledTransferDoesNotExist, CodeScheduledTransferNotFound},
+	{limits.ErrLimitExceeded, CodeLimitExceeded},
+	{limits.ErrInvalidRule, CodeInvalidLimitRule},
+	{limits.ErrInvalidTier, CodeInvalidTier},
+	{money.ErrOverflow, CodeAmountOutOfRange},
+	{money.ErrCurrencyMismatch, CodeCurrencyMismatch},
+	{money.ErrCurrencyNotSet, CodeAmountCurrencyNotSet},
+	{money.ErrTooManyDecimals, CodeAmountTooManyDecimals},
+	{money.ErrMalformed, CodeAmountMalformed},
+	{currency.ErrUnsupported, CodeUnsupportedCurrency},
+	{fx.ErrRateNotAvailable, CodeRateNotAvailable},
+	{fx.ErrSameCurrency, CodeSameCurrency},
+	{fx.ErrQuoteNotFound, CodeQuoteNotFound},
+	{fx.ErrQuoteExpired, CodeQuoteExpired},
+	{fx.ErrQuoteUsed, CodeQuoteUsed},
+	{fx.ErrQuoteCurrencyMismatch, CodeQuoteCurrencyMismatch},
+	{fx.ErrConvertedAmountTooSmall, CodeConvertedAmountTooSmall},
+	{fx.ErrConvertedAmountTooLarge, CodeConvertedAmountTooLarge},
+	{webhook.ErrDoesNotExist, CodeWebhookNotFound},
+	{webhook.ErrInvalidUrl, CodeInvalidWebhookUrl},
+	{webhook.ErrNoEventTypes, CodeWebhookEventTypesNotSet},
+	{idempotency.ErrFingerprintMismatch, CodeIdempotencyKeyReused},
+	{idempotency.ErrInProgress, CodeIdempotencyInProgress},
+	{idempotency.ErrOutcomeUnknown, CodeIdempotencyOutcomeUnknown},
+	{customer.ErrDoesNotExist, CodeCustomerNotFound},
+	{customer.ErrInvalidName, CodeInvalidCustomerName},
+	{customer.ErrInvalidEmail, CodeInvalidCustomerEmail},
+	{customer.ErrInvalidExternalRef, CodeInvalidExternalRef},
+	{customer.ErrUnknownKycTier, CodeUnknownKycTier},
+	{customer.ErrExternalRefTaken, CodeExternalRefTaken},
+	{customer.ErrHasOpenAccounts, CodeCustomerHasOpenAccounts},
+	{apikey.ErrInvalid, CodeApiKeyInvalid},
+	{apikey.ErrRevoked, CodeApiKeyRevoked},
+	{apikey.ErrDoesNotExist, CodeApiKeyNotFound},
+	{apikey.ErrAlreadyRevoked, CodeApiKeyAlreadyRevoked},
+	{apikey.ErrNoScopes, CodeApiKeyScopesNotSet},
+	{apikey.ErrUnknownScope, CodeUnknownApiKeyScope},
+	{jwt.ErrExpired, CodeTokenExpired},
+	{jwt.ErrNotYetValid, CodeTokenNotYetValid},
+	{jwt.ErrMalformed, CodeTokenInvalid},
+	{jwt.ErrUnsupportedAlgorithm, CodeTokenInvalid},
+	{jwt.ErrUnknownKey, CodeTokenInvalid},
+	{jwt.ErrSignature, CodeTokenInvalid},
+	{jwt.ErrSubjectNotSet, CodeTokenInvalid},
+}
+
+// codeOf returns the code of the field or domain error err wraps, or a code derived from status for any other error.
+func codeOf(err error, status int) Code {
+	var field *FieldError
+	if errors.As(err, &field) {
+		return field.Code
+	}
+
+	for _, domain := range domainCodes {
+		if errors.Is(err, domain.err) {
+			return domain.code
+		}
+	}
+
+	return Code(strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_"))
+}
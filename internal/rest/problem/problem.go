@@ -0,0 +1,128 @@
+package problem
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/requestid"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is an error response as described by RFC 7807, extended with a machine-readable code, the id of the request
+// and the validation errors of the request fields.
+type Problem struct {
+	// Type is always about:blank, the code tells the problems apart.
+	Type      string        `json:"type"`
+	Title     string        `json:"title"`
+	Status    int           `json:"status"`
+	Detail    string        `json:"detail,omitempty"`
+	Instance  string        `json:"instance,omitempty"`
+	Code      Code          `json:"code"`
+	RequestId string        `json:"request_id,omitempty"`
+	Errors    []*FieldError `json:"errors,omitempty"`
+	// AvailableBalance and OverdraftLimit tell insufficient balance problems apart, in minor units of Currency.
+	AvailableBalance *money.Amount `json:"available_balance,omitempty"`
+	OverdraftLimit   *money.Amount `json:"overdraft_limit,omitempty"`
+	Currency         currency.Code `json:"currency,omitempty"`
+}
+
+func New(status int, code Code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// FromError describes err, the code is the one of the domain error err wraps.
+func FromError(status int, err error) *Problem {
+	p := New(status, codeOf(err, status), err.Error())
+
+	var insufficient *account.InsufficientBalance
+	if errors.As(err, &insufficient) {
+		p.AvailableBalance, p.OverdraftLimit = &insufficient.Available, &insufficient.OverdraftLimit
+		p.Currency = insufficient.Available.Currency()
+	}
+
+	return p
+}
+
+// Malformed describes a request which could not be parsed.
+func Malformed(err error) *Problem {
+	return New(http.StatusBadRequest, codeOf(err, http.StatusBadRequest), "request parsing error: "+err.Error())
+}
+
+// Invalid describes a request which was parsed but is not valid, along with the field at fault if err tells it.
+func Invalid(err error) *Problem {
+	p := New(http.StatusUnprocessableEntity, codeOf(err, http.StatusUnprocessableEntity), "request validation error: "+err.Error())
+
+	var field *FieldError
+	if errors.As(err, &field) {
+		p.Errors = []*FieldError{field}
+	}
+
+	return p
+}
+
+// Internal describes a failure of the server, the details of which are logged rather than exposed.
+func Internal() *Problem {
+	return New(http.StatusInternalServerError, CodeInternal, "could not process the request")
+}
+
+// Write sends p as the response to r.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem, logger *slog.Logger) {
+	p.Instance = r.URL.Path
+	p.RequestId = requestid.From(r.Context())
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logger.ErrorContext(r.Context(), "cannot write bytes to client", "error", err)
+	}
+}
+
+// FieldError is a validation error of a request field, Field is empty when the error concerns the request as a whole.
+type FieldError struct {
+	Field  string `json:"field,omitempty"`
+	Code   Code   `json:"code"`
+	Detail string `json:"detail"`
+	parent *FieldError
+}
+
+func Field(field string, code Code, detail string) *FieldError {
+	return &FieldError{
+		Field:  field,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+func (e *FieldError) Error() string {
+	return e.Detail
+}
+
+// At returns the same error for the field at path, e.g. for an item of a list. It still matches e with errors.Is.
+func (e *FieldError) At(path string) *FieldError {
+	return &FieldError{
+		Field:  path,
+		Code:   e.Code,
+		Detail: e.Detail,
+		parent: e,
+	}
+}
+
+func (e *FieldError) Unwrap() error {
+	if e.parent == nil {
+		return nil
+	}
+	return e.parent
+}
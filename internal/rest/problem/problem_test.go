@@ -0,0 +1,78 @@
+package problem_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/limits"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+func TestFromError(t *testing.T) {
+	type testCase struct {
+		status   int
+		err      error
+		expected problem.Code
+	}
+
+	testCases := map[string]testCase{
+		"domain error":             {status: http.StatusNotFound, err: account.ErrDoesNotExist, expected: problem.CodeAccountNotFound},
+		"wrapped domain error":     {status: http.StatusBadRequest, err: errors.Wrap(account.ErrInsufficientBalance, "account id=1"), expected: problem.CodeInsufficientBalance},
+		"unwrapping error":         {status: http.StatusTooManyRequests, err: &limits.Violation{}, expected: problem.CodeLimitExceeded},
+		"field error":              {status: http.StatusUnprocessableEntity, err: problem.Field("amount", "amount_not_positive", "amount must be positive"), expected: "amount_not_positive"},
+		"unknown error":            {status: http.StatusConflict, err: errors.New("something else"), expected: "conflict"},
+		"unknown error of 2 words": {status: http.StatusUnprocessableEntity, err: errors.New("something else"), expected: "unprocessable_entity"},
+	}
+
+	for testName, test := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			p := problem.FromError(test.status, test.err)
+			assert.Equal(t, test.expected, p.Code)
+			assert.Equal(t, test.status, p.Status)
+			assert.Equal(t, http.StatusText(test.status), p.Title)
+			assert.Equal(t, test.err.Error(), p.Detail)
+		})
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	errAmount := problem.Field("amount", "amount_not_positive", "amount must be positive")
+
+	type testCase struct {
+		err            error
+		expectedCode   problem.Code
+		expectedErrors []*problem.FieldError
+	}
+
+	testCases := map[string]testCase{
+		"field error":         {err: errAmount, expectedCode: "amount_not_positive", expectedErrors: []*problem.FieldError{errAmount}},
+		"wrapped field error": {err: errors.Wrap(errAmount, "invalid request data"), expectedCode: "amount_not_positive", expectedErrors: []*problem.FieldError{errAmount}},
+		"domain error":        {err: account.ErrCurrencyMismatch, expectedCode: problem.CodeCurrencyMismatch},
+		"unknown error":       {err: errors.New("something else"), expectedCode: "unprocessable_entity"},
+	}
+
+	for testName, test := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			p := problem.Invalid(test.err)
+			assert.Equal(t, http.StatusUnprocessableEntity, p.Status)
+			assert.Equal(t, test.expectedCode, p.Code)
+			assert.Equal(t, test.expectedErrors, p.Errors)
+		})
+	}
+}
+
+func TestFieldErrorAt(t *testing.T) {
+	errAmount := problem.Field("amount", "amount_not_positive", "amount must be positive")
+
+	err := errAmount.At("transfers[2].amount")
+
+	assert.Equal(t, "transfers[2].amount", err.Field)
+	assert.Equal(t, errAmount.Code, err.Code)
+	assert.Equal(t, errAmount.Error(), err.Error())
+	assert.ErrorIs(t, err, errAmount)
+	assert.Equal(t, "amount", errAmount.Field)
+}
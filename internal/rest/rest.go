@@ -2,28 +2,128 @@ package rest
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 
 	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/apikey"
+	"github.com/ktsivkov/su-exc/internal/audit"
+	"github.com/ktsivkov/su-exc/internal/customer"
+	"github.com/ktsivkov/su-exc/internal/fx"
+	"github.com/ktsivkov/su-exc/internal/idempotency"
+	"github.com/ktsivkov/su-exc/internal/jwt"
 	"github.com/ktsivkov/su-exc/internal/lifecycle"
+	"github.com/ktsivkov/su-exc/internal/limits"
+	"github.com/ktsivkov/su-exc/internal/outbox"
+	"github.com/ktsivkov/su-exc/internal/rest/account/authorize"
+	"github.com/ktsivkov/su-exc/internal/rest/account/balance"
+	"github.com/ktsivkov/su-exc/internal/rest/account/batch"
 	"github.com/ktsivkov/su-exc/internal/rest/account/create"
+	"github.com/ktsivkov/su-exc/internal/rest/account/find"
+	"github.com/ktsivkov/su-exc/internal/rest/account/list"
+	"github.com/ktsivkov/su-exc/internal/rest/account/schedule"
 	"github.com/ktsivkov/su-exc/internal/rest/account/topup"
+	"github.com/ktsivkov/su-exc/internal/rest/account/transactions"
 	"github.com/ktsivkov/su-exc/internal/rest/account/transfer"
+	"github.com/ktsivkov/su-exc/internal/rest/account/withdraw"
+	closeaccount "github.com/ktsivkov/su-exc/internal/rest/admin/close"
+	"github.com/ktsivkov/su-exc/internal/rest/admin/freeze"
+	"github.com/ktsivkov/su-exc/internal/rest/admin/issuekey"
+	accountlimits "github.com/ktsivkov/su-exc/internal/rest/admin/limits"
+	"github.com/ktsivkov/su-exc/internal/rest/admin/overdraft"
+	"github.com/ktsivkov/su-exc/internal/rest/admin/revokekey"
+	"github.com/ktsivkov/su-exc/internal/rest/admin/tier"
+	"github.com/ktsivkov/su-exc/internal/rest/admin/tierlimits"
+	"github.com/ktsivkov/su-exc/internal/rest/admin/unfreeze"
+	customeraccounts "github.com/ktsivkov/su-exc/internal/rest/customer/accounts"
+	customercreate "github.com/ktsivkov/su-exc/internal/rest/customer/create"
+	customerfind "github.com/ktsivkov/su-exc/internal/rest/customer/find"
+	customerremove "github.com/ktsivkov/su-exc/internal/rest/customer/remove"
+	customerupdate "github.com/ktsivkov/su-exc/internal/rest/customer/update"
+	"github.com/ktsivkov/su-exc/internal/rest/fx/quote"
+	"github.com/ktsivkov/su-exc/internal/rest/hold/capture"
+	"github.com/ktsivkov/su-exc/internal/rest/hold/void"
+	"github.com/ktsivkov/su-exc/internal/rest/middleware"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+	scheduledfind "github.com/ktsivkov/su-exc/internal/rest/scheduled/find"
+	"github.com/ktsivkov/su-exc/internal/rest/transfer/reverse"
+	"github.com/ktsivkov/su-exc/internal/rest/webhook/deliveries"
+	"github.com/ktsivkov/su-exc/internal/rest/webhook/register"
+	"github.com/ktsivkov/su-exc/internal/webhook"
 )
 
-func Boot(ctx context.Context, dbUri string, port int, shutdownGracePeriod time.Duration) error {
+type Config struct {
+	DbUri                string
+	Port                 int
+	ShutdownGracePeriod  time.Duration
+	IdempotencyRetention time.Duration
+	// IdempotencyLease is how long a request holds its idempotency key, retries sent afterwards without a response
+	// being stored are told the outcome of the request is unknown instead of that it is in progress.
+	IdempotencyLease time.Duration
+	// FxRates maps currency pairs such as "EUR/USD" to their exchange rate.
+	FxRates    map[string]string
+	FxQuoteTtl time.Duration
+	// HoldTtl is how long a hold reserves funds before it expires unless captured or voided.
+	HoldTtl time.Duration
+	// SchedulerInterval is how often due scheduled transfers are polled for.
+	SchedulerInterval time.Duration
+	// SchedulerLeaseTtl is how long a scheduled transfer stays claimed by an instance before another one may run it.
+	SchedulerLeaseTtl time.Duration
+	// OutboxRelayInterval is how often pending outbox events are published to the sinks.
+	OutboxRelayInterval time.Duration
+	// OutboxStdout publishes the outbox events as lines of JSON to the standard output.
+	OutboxStdout bool
+	// OutboxFile is the path of a file the outbox events are appended to as lines of JSON, if set.
+	OutboxFile string
+	// OutboxWebhookUrl is the url the outbox events are posted to, if set.
+	OutboxWebhookUrl     string
+	OutboxWebhookTimeout time.Duration
+	// WebhookDispatchInterval is how often due webhook deliveries are polled for.
+	WebhookDispatchInterval time.Duration
+	// WebhookTimeout is how long a webhook receiver has to respond to a delivery.
+	WebhookTimeout time.Duration
+	// WebhookMaxAttempts is the number of attempts after which a failing webhook delivery is given up as dead.
+	WebhookMaxAttempts int
+	// WebhookRetryBackoff is the delay before the first retry of a webhook delivery, it doubles with every retry.
+	WebhookRetryBackoff time.Duration
+	// BalanceSnapshotInterval is how far apart the balance snapshots backing the balances at a point in time are taken.
+	BalanceSnapshotInterval time.Duration
+	// JwtSecret verifies HS256 bearer tokens, if set.
+	JwtSecret string
+	// JwtJwksFile is the path of a local JWKS file whose keys verify RS256 and ES256 bearer tokens, if set.
+	JwtJwksFile string
+	// JwtPrivilegedRoles are the token roles not restricted to the accounts their subject owns.
+	JwtPrivilegedRoles []string
+}
+
+// scheduledTransfersBatch is the number of due scheduled transfers claimed at once.
+const scheduledTransfersBatch = 50
+
+// outboxBatch is the number of pending outbox events published at once.
+const outboxBatch = 100
+
+// webhookDeliveriesBatch is the number of due webhook deliveries claimed at once.
+const webhookDeliveriesBatch = 20
+
+// balanceSnapshotDelay is how long after its time a balance snapshot is taken, transactions which started before that
+// time must have committed by then for the snapshot to include them.
+const balanceSnapshotDelay = time.Minute
+
+func Boot(ctx context.Context, conf *Config) error {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 
 	// Dependencies
-	db, err := sql.Open("postgres", dbUri)
+	db, err := sql.Open("postgres", conf.DbUri)
 	if err != nil {
 		logger.Error("cannot create database connection", "error", err)
 		return errors.Wrap(err, "cannot create database connection")
@@ -34,15 +134,100 @@ func Boot(ctx context.Context, dbUri string, port int, shutdownGracePeriod time.
 		}
 	}()
 
-	accountRepo, err := account.NewRepository(db)
+	accountRepo, err := account.NewRepository(db, conf.HoldTtl)
 	if err != nil {
 		logger.Error("cannot initialize account repository", "error", err)
 		return errors.Wrap(err, "cannot initialize account repository")
 	}
 
-	router := ApiRouter(accountRepo, logger)
+	idempotencyRepo, err := idempotency.NewRepository(db, conf.IdempotencyRetention, conf.IdempotencyLease)
+	if err != nil {
+		logger.Error("cannot initialize idempotency repository", "error", err)
+		return errors.Wrap(err, "cannot initialize idempotency repository")
+	}
+
+	rateProvider, err := fx.NewConfigRateProvider(conf.FxRates)
+	if err != nil {
+		logger.Error("cannot initialize exchange rate provider", "error", err)
+		return errors.Wrap(err, "cannot initialize exchange rate provider")
+	}
+
+	fxRepo, err := fx.NewRepository(db, rateProvider, conf.FxQuoteTtl)
+	if err != nil {
+		logger.Error("cannot initialize fx repository", "error", err)
+		return errors.Wrap(err, "cannot initialize fx repository")
+	}
+
+	limitsRepo, err := limits.NewRepository(db)
+	if err != nil {
+		logger.Error("cannot initialize limits repository", "error", err)
+		return errors.Wrap(err, "cannot initialize limits repository")
+	}
+
+	owner, err := schedulerOwner()
+	if err != nil {
+		logger.Error("cannot identify scheduler", "error", err)
+		return err
+	}
+
+	auditRepo, err := audit.NewRepository(db)
+	if err != nil {
+		logger.Error("cannot initialize audit repository", "error", err)
+		return errors.Wrap(err, "cannot initialize audit repository")
+	}
+
+	webhookRepo, err := webhook.NewRepository(db, conf.WebhookMaxAttempts, conf.WebhookRetryBackoff)
+	if err != nil {
+		logger.Error("cannot initialize webhook repository", "error", err)
+		return errors.Wrap(err, "cannot initialize webhook repository")
+	}
+
+	dispatcher, err := webhook.NewDispatcher(webhookRepo, conf.WebhookTimeout)
+	if err != nil {
+		logger.Error("cannot initialize webhook dispatcher", "error", err)
+		return errors.Wrap(err, "cannot initialize webhook dispatcher")
+	}
+
+	sinks, closeSinks, err := outboxSinks(conf)
+	if err != nil {
+		logger.Error("cannot initialize outbox sinks", "error", err)
+		return err
+	}
+	defer func() {
+		if err := closeSinks(); err != nil {
+			logger.Error("cannot close outbox sinks", "error", err)
+		}
+	}()
+
+	// Webhook deliveries are queued by the relay like by any other sink
+	// The lease outlasts the time it takes to post every event of a batch, so that no event is published twice at once
+	relay, err := outbox.NewRelay(db, outboxBatch, time.Duration(outboxBatch+1)*conf.OutboxWebhookTimeout, append(sinks, webhookRepo)...)
+	if err != nil {
+		logger.Error("cannot initialize outbox relay", "error", err)
+		return errors.Wrap(err, "cannot initialize outbox relay")
+	}
+
+	apiKeyRepo, err := apikey.NewRepository(db)
+	if err != nil {
+		logger.Error("cannot initialize api key repository", "error", err)
+		return errors.Wrap(err, "cannot initialize api key repository")
+	}
 
-	addr := fmt.Sprintf(":%d", port)
+	customerRepo, err := customer.NewRepository(db)
+	if err != nil {
+		logger.Error("cannot initialize customer repository", "error", err)
+		return errors.Wrap(err, "cannot initialize customer repository")
+	}
+
+	verifier, err := tokenVerifier(conf)
+	if err != nil {
+		logger.Error("cannot initialize token verifier", "error", err)
+		return errors.Wrap(err, "cannot initialize token verifier")
+	}
+
+	router := ApiRouter(accountRepo, idempotencyRepo, fxRepo, limitsRepo, webhookRepo, auditRepo, apiKeyRepo, customerRepo, verifier, conf.JwtPrivilegedRoles, logger)
+
+	addr := fmt.Sprintf(":%d", conf.Port)
 	srv := &http.Server{
 		Handler: router,
 		Addr:    addr,
@@ -53,7 +238,7 @@ func Boot(ctx context.Context, dbUri string, port int, shutdownGracePeriod time.
 
 	// Graceful shutdown
 	gCtx, stop := lifecycle.GetGracefulContext(ctx, func(ctx context.Context, cause error) {
-		tCtx, shutdown := context.WithTimeout(ctx, shutdownGracePeriod)
+		tCtx, shutdown := context.WithTimeout(ctx, conf.ShutdownGracePeriod)
 		defer shutdown()
 
 		logger.Info("HTTP server graceful shutdown begins...", "cause", cause)
@@ -78,16 +263,317 @@ func Boot(ctx context.Context, dbUri string, port int, shutdownGracePeriod time.
 		}
 	}(stop)
 
+	// Background jobs
+	go purgeIdempotencyKeys(gCtx, idempotencyRepo, conf.IdempotencyRetention, logger)
+	go expireHolds(gCtx, accountRepo, conf.HoldTtl, logger)
+	go snapshotBalances(gCtx, accountRepo, conf.BalanceSnapshotInterval, logger)
+
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		runScheduledTransfers(gCtx, accountRepo, owner, conf.SchedulerInterval, conf.SchedulerLeaseTtl, logger)
+	}()
+
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		relayOutbox(gCtx, relay, conf.OutboxRelayInterval, logger)
+	}()
+	go func() {
+		defer workers.Done()
+		dispatchWebhooks(gCtx, dispatcher, conf.WebhookDispatchInterval, logger)
+	}()
+
 	defer stop()
 	<-gCtx.Done()
 
+	// The database connection and the sinks must outlive the runs in progress, if any
+	workers.Wait()
+
 	return nil
 }
 
-func ApiRouter(accountRepo *account.Repository, logger *slog.Logger) *mux.Router {
+func ApiRouter(accountRepo *account.Repository, idempotencyStore idempotency.Store, fxRepo *fx.Repository, limitsRepo *limits.Repository, webhookRepo *webhook.Repository, auditRecorder audit.Recorder, apiKeyRepo *apikey.Repository, customerRepo *customer.Repository, verifier jwt.TokenVerifier, privilegedRoles []string, logger *slog.Logger) *mux.Router {
+	idempotent := middleware.Idempotency(idempotencyStore, logger)
+
+	// Keys restricted to some accounts may only use the routes naming one of them, or acting on no account at all
+	createAccounts := middleware.Require(apikey.ScopeAccountsCreate, logger)
+	// Customers span accounts, so only unrestricted keys may manage them
+	manageCustomers := middleware.RequireAnyAccount(apikey.ScopeAccountsCreate, logger)
+	readOwnedAccount := middleware.RequireOwnedAccount(apikey.ScopeAccountsRead, logger)
+	readAnyAccount := middleware.RequireAnyAccount(apikey.ScopeAccountsRead, logger)
+	writeTransfers := middleware.Require(apikey.ScopeTransfersWrite, logger)
+	writeAccount := middleware.RequireAccount(apikey.ScopeTransfersWrite, logger)
+	writeAnyAccount := middleware.RequireAnyAccount(apikey.ScopeTransfersWrite, logger)
+	writeOwnedAccount := middleware.RequireOwnedAccount(apikey.ScopeTransfersWrite, logger)
+	admin := middleware.RequireAnyAccount(apikey.ScopeAdmin, logger)
+	// The audit records the status a route answers with on success within the transaction making its changes
+	created := middleware.AuditStatus(http.StatusCreated)
+	noContent := middleware.AuditStatus(http.StatusNoContent)
+
 	router := mux.NewRouter()
-	router.HandleFunc("/accounts", create.Handler(accountRepo, logger)).Methods(http.MethodPost)
-	router.HandleFunc("/account/{id:[0-9]+}/topup", topup.Handler(topup.GetRequestParser(), accountRepo, accountRepo, logger)).Methods(http.MethodPost)
-	router.HandleFunc("/account/{id:[0-9]+}/transfer", transfer.Handler(transfer.GetRequestParser(), accountRepo, accountRepo, logger)).Methods(http.MethodPost)
+	// The key is authenticated ahead of the audit to make it the actor, the routes reject the requests it does not allow
+	router.Use(middleware.RequestId(), middleware.Authenticate(apiKeyRepo, verifier, privilegedRoles, logger), middleware.Audit(auditRecorder, logger))
+	// Requests no route matches skip the middlewares of the router
+	router.NotFoundHandler = middleware.RequestId()(routeProblem(problem.CodeRouteNotFound, http.StatusNotFound, logger))
+	router.MethodNotAllowedHandler = middleware.RequestId()(routeProblem(problem.CodeMethodNotAllowed, http.StatusMethodNotAllowed, logger))
+	router.Handle("/accounts", created(createAccounts(create.Handler(create.GetRequestParser(), accountRepo, logger)))).Methods(http.MethodPost)
+	router.Handle("/accounts", readAnyAccount(list.Handler(list.GetRequestParser(), accountRepo, logger))).Methods(http.MethodGet)
+	router.Handle("/customers", created(manageCustomers(customercreate.Handler(customercreate.GetRequestParser(), customerRepo, logger)))).Methods(http.MethodPost)
+	router.Handle("/customers/{id:[0-9]+}", readAnyAccount(customerfind.Handler(customerfind.GetRequestParser(), customerRepo, logger))).Methods(http.MethodGet)
+	router.Handle("/customers/{id:[0-9]+}", manageCustomers(customerupdate.Handler(customerupdate.GetRequestParser(), customerRepo, logger))).Methods(http.MethodPut)
+	router.Handle("/customers/{id:[0-9]+}", noContent(manageCustomers(customerremove.Handler(customerremove.GetRequestParser(), customerRepo, logger)))).Methods(http.MethodDelete)
+	router.Handle("/customers/{id:[0-9]+}/accounts", readAnyAccount(customeraccounts.Handler(customeraccounts.GetRequestParser(), customerRepo, accountRepo, accountRepo, logger))).Methods(http.MethodGet)
+	router.Handle("/account/{id:[0-9]+}", readOwnedAccount(find.Handler(find.GetRequestParser(), accountRepo, logger))).Methods(http.MethodGet)
+	// Top-ups mint funds, so only unrestricted admin keys may make them
+	router.Handle("/account/{id:[0-9]+}/topup", admin(idempotent(topup.Handler(topup.GetRequestParser(), accountRepo, accountRepo, logger)))).Methods(http.MethodPost)
+	router.Handle("/account/{id:[0-9]+}/withdraw", writeAccount(idempotent(withdraw.Handler(withdraw.GetRequestParser(), accountRepo, accountRepo, logger)))).Methods(http.MethodPost)
+	router.Handle("/account/{id:[0-9]+}/transfer", writeOwnedAccount(idempotent(transfer.Handler(transfer.GetRequestParser(), accountRepo, accountRepo, accountRepo, logger)))).Methods(http.MethodPost)
+	router.Handle("/account/{id:[0-9]+}/transfers:batch", writeAccount(idempotent(batch.Handler(batch.GetRequestParser(), accountRepo, accountRepo, logger)))).Methods(http.MethodPost)
+	router.Handle("/transfers/{id:[0-9]+}/reverse", created(writeAnyAccount(idempotent(reverse.Handler(reverse.GetRequestParser(), accountRepo, accountRepo, logger))))).Methods(http.MethodPost)
+	router.Handle("/fx/quotes", created(writeTransfers(quote.Handler(quote.GetRequestParser(), fxRepo, logger)))).Methods(http.MethodPost)
+	router.Handle("/account/{id:[0-9]+}/holds", created(writeAccount(idempotent(authorize.Handler(authorize.GetRequestParser(), accountRepo, accountRepo, logger))))).Methods(http.MethodPost)
+	router.Handle("/holds/{id:[0-9]+}/capture", writeAnyAccount(idempotent(capture.Handler(capture.GetRequestParser(), accountRepo, accountRepo, logger)))).Methods(http.MethodPost)
+	router.Handle("/holds/{id:[0-9]+}/void", writeAnyAccount(idempotent(void.Handler(void.GetRequestParser(), accountRepo, accountRepo, logger)))).Methods(http.MethodPost)
+	router.Handle("/account/{id:[0-9]+}/scheduled-transfers", created(writeAccount(idempotent(schedule.Handler(schedule.GetRequestParser(), accountRepo, accountRepo, logger))))).Methods(http.MethodPost)
+	router.Handle("/scheduled-transfers/{id:[0-9]+}", readAnyAccount(scheduledfind.Handler(scheduledfind.GetRequestParser(), accountRepo, logger))).Methods(http.MethodGet)
+	router.Handle("/account/{id:[0-9]+}/balance", readOwnedAccount(balance.Handler(balance.GetRequestParser(), accountRepo, accountRepo, logger))).Methods(http.MethodGet)
+	router.Handle("/account/{id:[0-9]+}/transactions", readOwnedAccount(transactions.Handler(transactions.GetRequestParser(), accountRepo, accountRepo, logger))).Methods(http.MethodGet)
+	router.Handle("/admin/accounts/{id:[0-9]+}/freeze", admin(freeze.Handler(freeze.GetRequestParser(), accountRepo, accountRepo, logger))).Methods(http.MethodPost)
+	router.Handle("/admin/accounts/{id:[0-9]+}/unfreeze", admin(unfreeze.Handler(unfreeze.GetRequestParser(), accountRepo, accountRepo, logger))).Methods(http.MethodPost)
+	router.Handle("/admin/accounts/{id:[0-9]+}/close", admin(closeaccount.Handler(closeaccount.GetRequestParser(), accountRepo, accountRepo, logger))).Methods(http.MethodPost)
+	router.Handle("/admin/accounts/{id:[0-9]+}/overdraft", admin(overdraft.Handler(overdraft.GetRequestParser(), accountRepo, accountRepo, logger))).Methods(http.MethodPut)
+	router.Handle("/admin/accounts/{id:[0-9]+}/limits", admin(accountlimits.Handler(accountlimits.GetRequestParser(), accountRepo, limitsRepo, limitsRepo, logger))).Methods(http.MethodPut)
+	router.Handle("/admin/accounts/{id:[0-9]+}/tier", admin(tier.Handler(tier.GetRequestParser(), accountRepo, limitsRepo, limitsRepo, logger))).Methods(http.MethodPut)
+	router.Handle("/admin/tiers/{tier}/limits", admin(tierlimits.Handler(tierlimits.GetRequestParser(), limitsRepo, logger))).Methods(http.MethodPut)
+	router.Handle("/admin/api-keys", created(admin(issuekey.Handler(issuekey.GetRequestParser(), apiKeyRepo, logger)))).Methods(http.MethodPost)
+	router.Handle("/admin/api-keys/{id:[0-9]+}/revoke", admin(revokekey.Handler(revokekey.GetRequestParser(), apiKeyRepo, logger))).Methods(http.MethodPost)
+	router.Handle("/webhooks", created(admin(idempotent(register.Handler(register.GetRequestParser(), webhookRepo, logger))))).Methods(http.MethodPost)
+	router.Handle("/webhooks/{id:[0-9]+}/deliveries", admin(deliveries.Handler(deliveries.GetRequestParser(), webhookRepo, webhookRepo, logger))).Methods(http.MethodGet)
 	return router
 }
+
+func routeProblem(code problem.Code, status int, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, problem.New(status, code, fmt.Sprintf("%s %s is not supported", r.Method, r.URL.Path)), logger)
+	})
+}
+
+func purgeIdempotencyKeys(ctx context.Context, idempotencyRepo *idempotency.Repository, retention time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(min(retention, time.Hour))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := idempotencyRepo.Purge(ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "cannot purge expired idempotency keys", "error", err)
+				continue
+			}
+			logger.InfoContext(ctx, "expired idempotency keys purged", "count", purged)
+		}
+	}
+}
+
+func expireHolds(ctx context.Context, accountRepo *account.Repository, holdTtl time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(min(holdTtl, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := accountRepo.ExpireHolds(ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "cannot expire holds", "error", err)
+				continue
+			}
+			if expired > 0 {
+				logger.InfoContext(ctx, "holds expired", "count", expired)
+			}
+		}
+	}
+}
+
+// snapshotBalances takes a balance snapshot at the end of every interval, aligned to the zero time so that daily
+// snapshots are taken as of midnight UTC.
+func snapshotBalances(ctx context.Context, accountRepo *account.Repository, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(min(interval, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Taking the same snapshot again does nothing, it is only taken once per interval despite the shorter ticks
+			asOf := time.Now().Add(-balanceSnapshotDelay).Truncate(interval)
+			snapshots, err := accountRepo.SnapshotBalances(ctx, asOf)
+			if err != nil {
+				logger.ErrorContext(ctx, "cannot snapshot balances", "as_of", asOf, "error", err)
+				continue
+			}
+			if snapshots > 0 {
+				logger.InfoContext(ctx, "balances snapshotted", "as_of", asOf, "count", snapshots)
+			}
+		}
+	}
+}
+
+// runScheduledTransfers executes the due scheduled transfers until ctx is done. A run interrupted by the shutdown is
+// rolled back, the transfer is picked up again by any instance once its lease expires.
+func runScheduledTransfers(ctx context.Context, runner account.ScheduledTransferRunner, owner string, interval time.Duration, leaseTtl time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			claimed, err := runner.ClaimScheduledTransfers(ctx, owner, scheduledTransfersBatch, leaseTtl)
+			if err != nil {
+				logger.ErrorContext(ctx, "cannot claim scheduled transfers", "error", err)
+				continue
+			}
+
+			for _, scheduled := range claimed {
+				if ctx.Err() != nil {
+					return
+				}
+
+				run, err := runner.RunScheduledTransfer(ctx, scheduled, owner)
+				if err != nil {
+					if errors.Is(err, account.ErrLeaseLost) {
+						logger.WarnContext(ctx, "scheduled transfer lease lost", "id", scheduled.Id, "error", err)
+						continue
+					}
+					logger.ErrorContext(ctx, "cannot run scheduled transfer", "id", scheduled.Id, "error", err)
+					continue
+				}
+				logger.InfoContext(ctx, "scheduled transfer ran", "id", scheduled.Id, "outcome", run.Outcome)
+			}
+		}
+	}
+}
+
+// relayOutbox publishes the pending outbox events until ctx is done. Events a relay interrupted by the shutdown did
+// not get to publish are published again once their lease is over.
+func relayOutbox(ctx context.Context, relay *outbox.Relay, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			published, err := relay.Publish(ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "cannot publish outbox events", "published", published, "error", err)
+				continue
+			}
+			if published > 0 {
+				logger.InfoContext(ctx, "outbox events published", "count", published)
+			}
+		}
+	}
+}
+
+// dispatchWebhooks sends the due webhook deliveries until ctx is done. A delivery interrupted by the shutdown is sent
+// again once its lease is over.
+func dispatchWebhooks(ctx context.Context, dispatcher *webhook.Dispatcher, interval time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			delivered, err := dispatcher.Dispatch(ctx, webhookDeliveriesBatch)
+			if err != nil {
+				logger.ErrorContext(ctx, "cannot dispatch webhook deliveries", "delivered", delivered, "error", err)
+				continue
+			}
+			if delivered > 0 {
+				logger.InfoContext(ctx, "webhook deliveries sent", "count", delivered)
+			}
+		}
+	}
+}
+
+// tokenVerifier returns the verifier of the bearer tokens, or nil if tokens are not accepted at all.
+func tokenVerifier(conf *Config) (jwt.TokenVerifier, error) {
+	if conf.JwtSecret == "" && conf.JwtJwksFile == "" {
+		return nil, nil
+	}
+
+	var keySet *jwt.KeySet
+	if conf.JwtJwksFile != "" {
+		var err error
+		if keySet, err = jwt.LoadKeySet(conf.JwtJwksFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return jwt.NewVerifier([]byte(conf.JwtSecret), keySet)
+}
+
+// outboxSinks creates the sinks enabled by conf, the returned function closes the resources they hold.
+func outboxSinks(conf *Config) ([]outbox.Sink, func() error, error) {
+	var sinks []outbox.Sink
+	closeSinks := func() error { return nil }
+
+	if conf.OutboxStdout {
+		sink, err := outbox.NewWriterSink(os.Stdout)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "cannot create stdout sink")
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if conf.OutboxFile != "" {
+		file, err := os.OpenFile(conf.OutboxFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "cannot open outbox file %q", conf.OutboxFile)
+		}
+		sink, err := outbox.NewWriterSink(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, nil, errors.Wrap(err, "cannot create file sink")
+		}
+		sinks, closeSinks = append(sinks, sink), file.Close
+	}
+
+	if conf.OutboxWebhookUrl != "" {
+		sink, err := outbox.NewHttpSink(conf.OutboxWebhookUrl, conf.OutboxWebhookTimeout)
+		if err != nil {
+			_ = closeSinks()
+			return nil, nil, errors.Wrap(err, "cannot create webhook sink")
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return sinks, closeSinks, nil
+}
+
+// schedulerOwner identifies this instance in the leases it takes on scheduled transfers.
+func schedulerOwner() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", errors.Wrap(err, "cannot read hostname")
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", errors.Wrap(err, "cannot generate scheduler id")
+	}
+
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(suffix)), nil
+}
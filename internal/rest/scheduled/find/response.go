@@ -0,0 +1,19 @@
+package find
+
+import (
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/rest/scheduled/view"
+)
+
+type Response struct {
+	*view.ScheduledTransfer
+	// Runs is the history of the scheduled transfer, most recent run first.
+	Runs []*view.Run `json:"runs"`
+}
+
+func NewResponse(scheduled *account.ScheduledTransfer, runs []*account.ScheduledRun) *Response {
+	return &Response{
+		ScheduledTransfer: view.NewScheduledTransfer(scheduled),
+		Runs:              view.NewRuns(runs),
+	}
+}
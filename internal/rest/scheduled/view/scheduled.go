@@ -0,0 +1,61 @@
+package view
+
+import (
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+type ScheduledTransfer struct {
+	Id         int64                  `json:"id"`
+	Source     int64                  `json:"source"`
+	Target     int64                  `json:"target"`
+	Amount     money.Amount           `json:"amount"`
+	Currency   currency.Code          `json:"currency"`
+	Recurrence *account.Recurrence    `json:"recurrence,omitempty"`
+	StartAt    time.Time              `json:"start_at"`
+	NextRunAt  *time.Time             `json:"next_run_at,omitempty"`
+	Status     account.ScheduleStatus `json:"status"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+func NewScheduledTransfer(scheduled *account.ScheduledTransfer) *ScheduledTransfer {
+	return &ScheduledTransfer{
+		Id:         scheduled.Id,
+		Source:     scheduled.SourceId,
+		Target:     scheduled.TargetId,
+		Amount:     scheduled.Amount,
+		Currency:   scheduled.Amount.Currency(),
+		Recurrence: scheduled.Recurrence,
+		StartAt:    scheduled.StartAt,
+		NextRunAt:  scheduled.NextRunAt,
+		Status:     scheduled.Status,
+		CreatedAt:  scheduled.CreatedAt,
+	}
+}
+
+type Run struct {
+	Id           int64              `json:"id"`
+	ScheduledFor time.Time          `json:"scheduled_for"`
+	Outcome      account.RunOutcome `json:"outcome"`
+	Error        *string            `json:"error,omitempty"`
+	TransferId   *int64             `json:"transfer_id,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+func NewRuns(runs []*account.ScheduledRun) []*Run {
+	res := make([]*Run, 0, len(runs))
+	for _, run := range runs {
+		res = append(res, &Run{
+			Id:           run.Id,
+			ScheduledFor: run.ScheduledFor,
+			Outcome:      run.Outcome,
+			Error:        run.Error,
+			TransferId:   run.TransferId,
+			CreatedAt:    run.CreatedAt,
+		})
+	}
+	return res
+}
@@ -0,0 +1,38 @@
+package reverse
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/money"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestInvalidAmountLt0 = problem.Field("amount", "amount_not_positive", "amount must be positive")
+
+type Request struct {
+	Id   int64
+	Data *RequestData
+}
+
+func (r *Request) Validate() error {
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	// Amount is optional, whatever is left to reverse of the transfer is reversed when omitted.
+	Amount *money.Amount `json:"amount"`
+	// AllowNegativeBalance lets the reversal proceed even if the recipient of the transfer no longer has the funds.
+	AllowNegativeBalance bool `json:"allow_negative_balance"`
+}
+
+func (d *RequestData) Validate() error {
+	if d.Amount != nil && !d.Amount.IsPositive() {
+		return ErrRequestInvalidAmountLt0
+	}
+
+	return nil
+}
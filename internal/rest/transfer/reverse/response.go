@@ -0,0 +1,27 @@
+package reverse
+
+import (
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/currency"
+	"github.com/ktsivkov/su-exc/internal/money"
+)
+
+type Response struct {
+	Id         int64         `json:"id"`
+	TransferId int64         `json:"transfer_id"`
+	Source     int64         `json:"source"`
+	Target     int64         `json:"target"`
+	Amount     money.Amount  `json:"amount"`
+	Currency   currency.Code `json:"currency"`
+}
+
+func NewResponse(reversal *account.Reversal) *Response {
+	return &Response{
+		Id:         reversal.Id,
+		TransferId: reversal.TransferId,
+		Source:     reversal.SourceId,
+		Target:     reversal.TargetId,
+		Amount:     reversal.Amount,
+		Currency:   reversal.Amount.Currency(),
+	}
+}
@@ -0,0 +1,58 @@
+package deliveries
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+	"github.com/ktsivkov/su-exc/internal/webhook"
+)
+
+func Handler(requestParser RequestParser, finder webhook.Finder, deliveryFinder webhook.DeliveryFinder, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		req, err := requestParser(ctx, r)
+		if err != nil {
+			logger.WarnContext(ctx, "cannot parse request", "error", err)
+			problem.Write(w, r, problem.Malformed(err), logger)
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			logger.WarnContext(ctx, "invalid request", "error", err)
+			problem.Write(w, r, problem.Invalid(err), logger)
+			return
+		}
+
+		subscription, err := finder.FindById(ctx, req.Webhook)
+		if err != nil {
+			if errors.Is(err, webhook.ErrDoesNotExist) {
+				logger.WarnContext(ctx, "webhook id not found", "id", req.Webhook)
+				problem.Write(w, r, problem.New(http.StatusNotFound, problem.CodeWebhookNotFound, fmt.Sprintf("webhook with id=%d does not exist", req.Webhook)), logger)
+				return
+			}
+
+			logger.ErrorContext(ctx, "webhook lookup failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		deliveries, err := deliveryFinder.Deliveries(ctx, req.Query())
+		if err != nil {
+			logger.ErrorContext(ctx, "webhook deliveries lookup failed", "error", err)
+			problem.Write(w, r, problem.Internal(), logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(NewResponse(subscription, deliveries)); err != nil {
+			logger.ErrorContext(ctx, "cannot write bytes to client", "error", err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package deliveries
+
+import (
+	"fmt"
+
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+	"github.com/ktsivkov/su-exc/internal/webhook"
+)
+
+const DefaultLimit = 20
+const MaxLimit = 100
+
+var ErrRequestInvalidLimit = problem.Field("limit", "invalid_limit", fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+var ErrRequestInvalidStatus = problem.Field("status", "invalid_status", fmt.Sprintf("status must be one of %q, %q, %q", webhook.DeliveryStatusPending, webhook.DeliveryStatusSucceeded, webhook.DeliveryStatusDead))
+
+type Request struct {
+	Webhook int64
+	Limit   int
+	Status  webhook.DeliveryStatus
+}
+
+func (r *Request) Validate() error {
+	if r.Limit < 1 || r.Limit > MaxLimit {
+		return ErrRequestInvalidLimit
+	}
+
+	switch r.Status {
+	case "", webhook.DeliveryStatusPending, webhook.DeliveryStatusSucceeded, webhook.DeliveryStatusDead:
+	default:
+		return ErrRequestInvalidStatus
+	}
+
+	return nil
+}
+
+func (r *Request) Query() *webhook.DeliveryQuery {
+	return &webhook.DeliveryQuery{
+		SubscriptionId: r.Webhook,
+		Status:         r.Status,
+		Limit:          r.Limit,
+	}
+}
@@ -0,0 +1,39 @@
+package deliveries
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/webhook"
+)
+
+type RequestParser func(ctx context.Context, r *http.Request) (*Request, error)
+
+func GetRequestParser() RequestParser {
+	return func(ctx context.Context, r *http.Request) (*Request, error) {
+		variables := mux.Vars(r)
+		id, err := strconv.ParseInt(variables["id"], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot parse webhook id")
+		}
+
+		query := r.URL.Query()
+		req := &Request{
+			Webhook: id,
+			Limit:   DefaultLimit,
+			Status:  webhook.DeliveryStatus(query.Get("status")),
+		}
+
+		if limit := query.Get("limit"); limit != "" {
+			if req.Limit, err = strconv.Atoi(limit); err != nil {
+				return nil, errors.Wrap(err, "cannot parse limit")
+			}
+		}
+
+		return req, nil
+	}
+}
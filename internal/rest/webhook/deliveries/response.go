@@ -0,0 +1,19 @@
+package deliveries
+
+import (
+	"github.com/ktsivkov/su-exc/internal/rest/webhook/view"
+	"github.com/ktsivkov/su-exc/internal/webhook"
+)
+
+type Response struct {
+	*view.Subscription
+	// Deliveries are the most recent deliveries to the webhook, most recent first.
+	Deliveries []*view.Delivery `json:"deliveries"`
+}
+
+func NewResponse(subscription *webhook.Subscription, deliveries []*webhook.Delivery) *Response {
+	return &Response{
+		Subscription: view.NewSubscription(subscription),
+		Deliveries:   view.NewDeliveries(deliveries),
+	}
+}
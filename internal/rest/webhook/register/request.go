@@ -0,0 +1,56 @@
+package register
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/account"
+	"github.com/ktsivkov/su-exc/internal/rest/problem"
+)
+
+var ErrRequestDataNotSet = problem.Field("", "data_not_set", "request data is mandatory")
+var ErrRequestUrlNotSet = problem.Field("url", "url_not_set", "url is mandatory")
+var ErrRequestEventTypesNotSet = problem.Field("event_types", "event_types_not_set", "event_types must list at least one event type")
+var ErrRequestUnknownEventType = problem.Field("event_types", "unknown_event_type", fmt.Sprintf("event types must be any of %s", strings.Join(account.EventTypes, ", ")))
+
+type Request struct {
+	Data *RequestData
+}
+
+func (r *Request) Validate() error {
+	if r.Data == nil {
+		return ErrRequestDataNotSet
+	}
+
+	if err := r.Data.Validate(); err != nil {
+		return errors.Wrap(err, "invalid request data")
+	}
+
+	return nil
+}
+
+type RequestData struct {
+	Url        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+func (d *RequestData) Validate() error {
+	if d.Url == "" {
+		return ErrRequestUrlNotSet
+	}
+
+	if len(d.EventTypes) == 0 {
+		return ErrRequestEventTypesNotSet
+	}
+
+	for _, eventType := range d.EventTypes {
+		if !slices.Contains(account.EventTypes, eventType) {
+			return errors.Wrapf(ErrRequestUnknownEventType, "event type=%q", eventType)
+		}
+	}
+
+	return nil
+}
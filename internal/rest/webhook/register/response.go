@@ -0,0 +1,19 @@
+package register
+
+import (
+	"github.com/ktsivkov/su-exc/internal/rest/webhook/view"
+	"github.com/ktsivkov/su-exc/internal/webhook"
+)
+
+type Response struct {
+	*view.Subscription
+	// Secret is only returned once, receivers need it to verify the signatures of the deliveries.
+	Secret string `json:"secret"`
+}
+
+func NewResponse(subscription *webhook.Subscription) *Response {
+	return &Response{
+		Subscription: view.NewSubscription(subscription),
+		Secret:       subscription.Secret,
+	}
+}
@@ -0,0 +1,59 @@
+package view
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ktsivkov/su-exc/internal/webhook"
+)
+
+type Subscription struct {
+	Id         int64     `json:"id"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func NewSubscription(subscription *webhook.Subscription) *Subscription {
+	return &Subscription{
+		Id:         subscription.Id,
+		Url:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+type Delivery struct {
+	Id             int64                  `json:"id"`
+	EventId        int64                  `json:"event_id"`
+	EventType      string                 `json:"event_type"`
+	Status         webhook.DeliveryStatus `json:"status"`
+	Attempts       int                    `json:"attempts"`
+	LastStatusCode *int                   `json:"last_status_code,omitempty"`
+	LastError      *string                `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time             `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time             `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time              `json:"created_at"`
+	// Body is the delivered event, exactly as the receiver gets it.
+	Body json.RawMessage `json:"body"`
+}
+
+func NewDeliveries(deliveries []*webhook.Delivery) []*Delivery {
+	res := make([]*Delivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		res = append(res, &Delivery{
+			Id:             delivery.Id,
+			EventId:        delivery.EventId,
+			EventType:      delivery.EventType,
+			Status:         delivery.Status,
+			Attempts:       delivery.Attempts,
+			LastStatusCode: delivery.LastStatusCode,
+			LastError:      delivery.LastError,
+			NextAttemptAt:  delivery.NextAttemptAt,
+			DeliveredAt:    delivery.DeliveredAt,
+			CreatedAt:      delivery.CreatedAt,
+			Body:           delivery.Body,
+		})
+	}
+	return res
+}
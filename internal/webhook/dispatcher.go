@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrDeliveryRejected = errors.New("webhook receiver rejected the delivery")
+
+func NewDispatcher(repo *Repository, timeout time.Duration) (*Dispatcher, error) {
+	if repo == nil {
+		return nil, errors.New("repository cannot be nil")
+	}
+	if timeout <= 0 {
+		return nil, errors.New("timeout must be positive")
+	}
+	return &Dispatcher{
+		repo:    repo,
+		timeout: timeout,
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Dispatcher sends the queued deliveries to their subscriptions.
+type Dispatcher struct {
+	repo    *Repository
+	timeout time.Duration
+	client  *http.Client
+}
+
+// Dispatch sends up to limit due deliveries and returns how many of them succeeded. The deliveries are sent one after
+// the other, a failed one is recorded for a retry and does not stop the others.
+func (d *Dispatcher) Dispatch(ctx context.Context, limit int) (int, error) {
+	// The lease outlasts the time it takes to send every delivery claimed, so that none of them is sent twice at once
+	deliveries, err := d.repo.claim(ctx, limit, time.Duration(limit+1)*d.timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	var succeeded int
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return succeeded, nil
+		}
+
+		statusCode, failure := d.send(ctx, delivery)
+		if err := d.repo.recordAttempt(ctx, delivery.Delivery, statusCode, failure); err != nil {
+			return succeeded, err
+		}
+		if failure == nil {
+			succeeded++
+		}
+	}
+
+	return succeeded, nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *claimed) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create request")
+	}
+
+	timestamp := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDeliveryId, strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.secret, timestamp, delivery.Body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not reach webhook receiver")
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &res.StatusCode, errors.Wrapf(ErrDeliveryRejected, "status code=%d", res.StatusCode)
+	}
+
+	return &res.StatusCode, nil
+}
@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/ktsivkov/su-exc/internal/outbox"
+)
+
+// maxRetryDelay caps the exponential backoff between two attempts of a delivery.
+const maxRetryDelay = time.Hour
+
+var ErrDoesNotExist = errors.New("webhook not found")
+var ErrInvalidUrl = errors.New("webhook url must be an absolute http or https url")
+var ErrNoEventTypes = errors.New("webhook must subscribe to at least one event type")
+
+const subscriptionColumns = "id, url, event_types, secret, created_at"
+
+const deliveryColumns = "id, subscription_id, event_id, event_type, body, status, attempts, last_status_code, last_error, next_attempt_at, delivered_at, created_at"
+
+func NewRepository(db *sql.DB, maxAttempts int, retryBackoff time.Duration) (*Repository, error) {
+	if db == nil {
+		return nil, errors.New("db cannot be nil")
+	}
+	if maxAttempts <= 0 {
+		return nil, errors.New("max attempts must be positive")
+	}
+	if retryBackoff <= 0 {
+		return nil, errors.New("retry backoff must be positive")
+	}
+	return &Repository{
+		db:           db,
+		maxAttempts:  maxAttempts,
+		retryBackoff: retryBackoff,
+	}, nil
+}
+
+type Repository struct {
+	db           *sql.DB
+	maxAttempts  int
+	retryBackoff time.Duration
+}
+
+func (r *Repository) Register(ctx context.Context, rawUrl string, eventTypes []string) (*Subscription, error) {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, errors.Wrapf(ErrInvalidUrl, "url=%q", rawUrl)
+	}
+	if len(eventTypes) == 0 {
+		return nil, ErrNoEventTypes
+	}
+
+	eventTypes = slices.Clone(eventTypes)
+	slices.Sort(eventTypes)
+	eventTypes = slices.Compact(eventTypes)
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.Wrap(err, "could not generate webhook secret")
+	}
+
+	subscription, err := scanSubscription(r.db.QueryRowContext(ctx, "INSERT INTO webhook_subscriptions (url, event_types, secret) VALUES ($1, $2, $3) RETURNING "+subscriptionColumns,
+		rawUrl, pq.Array(eventTypes), "whsec_"+hex.EncodeToString(secret)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not register webhook for url=%q", rawUrl)
+	}
+
+	return subscription, nil
+}
+
+func (r *Repository) FindById(ctx context.Context, id int64) (*Subscription, error) {
+	subscription, err := scanSubscription(r.db.QueryRowContext(ctx, "SELECT "+subscriptionColumns+" FROM webhook_subscriptions WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.Wrapf(ErrDoesNotExist, "webhook id=%d", id)
+		}
+		return nil, errors.Wrap(err, "could not scan database query result into struct")
+	}
+
+	return subscription, nil
+}
+
+func (r *Repository) Deliveries(ctx context.Context, query *DeliveryQuery) ([]*Delivery, error) {
+	stmt := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE subscription_id = $1"
+	args := []any{query.SubscriptionId}
+	if query.Status != "" {
+		args = append(args, query.Status)
+		stmt += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	args = append(args, query.Limit)
+	stmt += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "database query failed")
+	}
+	defer rows.Close()
+
+	deliveries := make([]*Delivery, 0, query.Limit)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not scan database query result into struct")
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over database query result")
+	}
+
+	return deliveries, nil
+}
+
+// Publish makes the repository an outbox.Sink, it queues a delivery of the event for every subscription to its type.
+// An event published again is not queued twice for the same subscription.
+func (r *Repository) Publish(ctx context.Context, event *outbox.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrapf(err, "could not encode event id=%d", event.Id)
+	}
+
+	_, err = r.db.ExecContext(ctx, `INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, body, next_attempt_at)
+SELECT id, $1, $2, $3, now() FROM webhook_subscriptions WHERE $2 = ANY(event_types)
+ON CONFLICT (subscription_id, event_id) DO NOTHING`, event.Id, event.Type, body)
+	if err != nil {
+		return errors.Wrapf(err, "could not queue deliveries of event id=%d", event.Id)
+	}
+
+	return nil
+}
+
+// claimed is a delivery along with what it takes to send it.
+type claimed struct {
+	*Delivery
+	url    string
+	secret string
+}
+
+// claim takes up to limit pending deliveries that are due, they are not handed out again until lease is over,
+// so that another dispatcher retries them should this one not report back in time.
+func (r *Repository) claim(ctx context.Context, limit int, lease time.Duration) ([]*claimed, error) {
+	rows, err := r.db.QueryContext(ctx, `UPDATE webhook_deliveries d SET next_attempt_at = now() + $2 * INTERVAL '1 millisecond'
+FROM webhook_subscriptions s
+WHERE s.id = d.subscription_id AND d.id IN (
+    SELECT id FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= now() ORDER BY next_attempt_at, id LIMIT $1 FOR UPDATE SKIP LOCKED
+)
+RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.body, d.status, d.attempts, d.last_status_code, d.last_error, d.next_attempt_at, d.delivered_at, d.created_at, s.url, s.secret`,
+		limit, lease.Milliseconds())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not claim webhook deliveries")
+	}
+	defer rows.Close()
+
+	var deliveries []*claimed
+	for rows.Next() {
+		delivery := &claimed{Delivery: &Delivery{}}
+		if err := rows.Scan(&delivery.Id, &delivery.SubscriptionId, &delivery.EventId, &delivery.EventType, &delivery.Body, &delivery.Status, &delivery.Attempts,
+			&delivery.LastStatusCode, &delivery.LastError, &delivery.NextAttemptAt, &delivery.DeliveredAt, &delivery.CreatedAt, &delivery.url, &delivery.secret); err != nil {
+			return nil, errors.Wrap(err, "could not scan database query result into struct")
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "could not iterate over database query result")
+	}
+
+	return deliveries, nil
+}
+
+// recordAttempt stores the outcome of an attempt, a failed delivery is either scheduled for a retry or, once it has
+// run out of attempts, left dead. The status code is nil when no response was received.
+func (r *Repository) recordAttempt(ctx context.Context, delivery *Delivery, statusCode *int, failure error) error {
+	attempts := delivery.Attempts + 1
+	status := DeliveryStatusSucceeded
+	var reason *string
+	var retryIn *int64
+	if failure != nil {
+		message := failure.Error()
+		reason = &message
+		status = DeliveryStatusDead
+		if attempts < r.maxAttempts {
+			delay := r.retryDelay(attempts).Milliseconds()
+			status, retryIn = DeliveryStatusPending, &delay
+		}
+	}
+
+	_, err := r.db.ExecContext(ctx, `UPDATE webhook_deliveries SET
+    status = $1,
+    attempts = $2,
+    last_status_code = $3,
+    last_error = $4,
+    next_attempt_at = now() + $5 * INTERVAL '1 millisecond',
+    delivered_at = CASE WHEN $1 = 'succeeded' THEN now() END
+WHERE id = $6`, status, attempts, statusCode, reason, retryIn, delivery.Id)
+	if err != nil {
+		return errors.Wrapf(err, "could not record attempt of webhook delivery id=%d", delivery.Id)
+	}
+
+	return nil
+}
+
+// retryDelay is the backoff before the attempt following the given number of failed attempts.
+func (r *Repository) retryDelay(failed int) time.Duration {
+	delay := r.retryBackoff
+	for i := 1; i < failed && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+func scanSubscription(row interface{ Scan(dest ...any) error }) (*Subscription, error) {
+	subscription := &Subscription{}
+	if err := row.Scan(&subscription.Id, &subscription.Url, pq.Array(&subscription.EventTypes), &subscription.Secret, &subscription.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// scanDelivery reads a row selected with deliveryColumns.
+func scanDelivery(row interface{ Scan(dest ...any) error }) (*Delivery, error) {
+	delivery := &Delivery{}
+	if err := row.Scan(&delivery.Id, &delivery.SubscriptionId, &delivery.EventId, &delivery.EventType, &delivery.Body, &delivery.Status, &delivery.Attempts,
+		&delivery.LastStatusCode, &delivery.LastError, &delivery.NextAttemptAt, &delivery.DeliveredAt, &delivery.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Headers sent along with every delivery, the signature covers the timestamp header and the body.
+const (
+	HeaderDeliveryId = "X-Webhook-Delivery"
+	HeaderEventType  = "X-Webhook-Event"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderSignature  = "X-Webhook-Signature"
+)
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+	// DeliveryStatusDead deliveries failed too many times and are no longer retried.
+	DeliveryStatusDead DeliveryStatus = "dead"
+)
+
+type DeliveryStatus string
+
+// Subscription asks for the events of the given types to be posted to Url.
+type Subscription struct {
+	Id         int64
+	Url        string
+	EventTypes []string
+	// Secret signs the deliveries, it is only ever shown to the subscriber when the subscription is registered.
+	Secret    string
+	CreatedAt time.Time
+}
+
+// Delivery is an event on its way to a subscription, it is retried with an exponential backoff until it succeeds
+// or runs out of attempts.
+type Delivery struct {
+	Id             int64
+	SubscriptionId int64
+	EventId        int64
+	EventType      string
+	Body           json.RawMessage
+	Status         DeliveryStatus
+	Attempts       int
+	// LastStatusCode is the response status of the last attempt, it is not set if no response was received.
+	LastStatusCode *int
+	LastError      *string
+	// NextAttemptAt is only set on pending deliveries.
+	NextAttemptAt *time.Time
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time
+}
+
+type DeliveryQuery struct {
+	SubscriptionId int64
+	// Status only returns the deliveries in the given status when set.
+	Status DeliveryStatus
+	Limit  int
+}
+
+type Registrar interface {
+	Register(ctx context.Context, url string, eventTypes []string) (*Subscription, error)
+}
+
+type Finder interface {
+	FindById(ctx context.Context, id int64) (*Subscription, error)
+}
+
+type DeliveryFinder interface {
+	// Deliveries returns the deliveries of a subscription, most recent first.
+	Deliveries(ctx context.Context, query *DeliveryQuery) ([]*Delivery, error)
+}
+
+// Sign computes the value of the HeaderSignature header, receivers verify a delivery by computing it themselves
+// from the HeaderTimestamp header and the raw body and comparing both in constant time.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
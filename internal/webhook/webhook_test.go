@@ -0,0 +1,32 @@
+package webhook_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ktsivkov/su-exc/internal/webhook"
+)
+
+func TestSign(t *testing.T) {
+	type testCase struct {
+		secret    string
+		timestamp time.Time
+		body      string
+		expected  string
+	}
+
+	timestamp := time.Unix(1700000000, 0)
+	testCases := map[string]testCase{
+		"signature":                  {secret: "whsec_test", timestamp: timestamp, body: `{"id":1}`, expected: "sha256=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8"},
+		"secret changes signature":   {secret: "whsec_other", timestamp: timestamp, body: `{"id":1}`, expected: "sha256=9c21515791baf591e45d7a07dd043081d85de11318bf326a8d6b19143d16c3e7"},
+		"sub-second part is ignored": {secret: "whsec_test", timestamp: timestamp.Add(500 * time.Millisecond), body: `{"id":1}`, expected: "sha256=2f441ba4b3b2d50d28a9ab9d9fd8880376ecd1eb5d0435401553f5d8d0a5dcf8"},
+	}
+
+	for testName, test := range testCases {
+		t.Run(testName, func(t *testing.T) {
+			assert.Equal(t, test.expected, webhook.Sign(test.secret, test.timestamp, []byte(test.body)))
+		})
+	}
+}